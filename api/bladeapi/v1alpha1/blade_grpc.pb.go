@@ -25,6 +25,8 @@ const (
 	BladeAgentService_SetFanSpeed_FullMethodName            = "/api.bladeapi.v1alpha1.BladeAgentService/SetFanSpeed"
 	BladeAgentService_SetStealthMode_FullMethodName         = "/api.bladeapi.v1alpha1.BladeAgentService/SetStealthMode"
 	BladeAgentService_GetStatus_FullMethodName              = "/api.bladeapi.v1alpha1.BladeAgentService/GetStatus"
+	BladeAgentService_ListEvents_FullMethodName             = "/api.bladeapi.v1alpha1.BladeAgentService/ListEvents"
+	BladeAgentService_GetTelemetryHistory_FullMethodName    = "/api.bladeapi.v1alpha1.BladeAgentService/GetTelemetryHistory"
 )
 
 // BladeAgentServiceClient is the client API for BladeAgentService service.
@@ -38,6 +40,10 @@ type BladeAgentServiceClient interface {
 	SetFanSpeed(ctx context.Context, in *SetFanSpeedRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	SetStealthMode(ctx context.Context, in *StealthModeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	GetStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	// ListEvents returns the journal of events handled by the agent
+	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	// GetTelemetryHistory returns the recent telemetry of the blade, either raw or downsampled
+	GetTelemetryHistory(ctx context.Context, in *GetTelemetryHistoryRequest, opts ...grpc.CallOption) (*GetTelemetryHistoryResponse, error)
 }
 
 type bladeAgentServiceClient struct {
@@ -93,6 +99,24 @@ func (c *bladeAgentServiceClient) GetStatus(ctx context.Context, in *emptypb.Emp
 	return out, nil
 }
 
+func (c *bladeAgentServiceClient) ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error) {
+	out := new(ListEventsResponse)
+	err := c.cc.Invoke(ctx, BladeAgentService_ListEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bladeAgentServiceClient) GetTelemetryHistory(ctx context.Context, in *GetTelemetryHistoryRequest, opts ...grpc.CallOption) (*GetTelemetryHistoryResponse, error) {
+	out := new(GetTelemetryHistoryResponse)
+	err := c.cc.Invoke(ctx, BladeAgentService_GetTelemetryHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BladeAgentServiceServer is the server API for BladeAgentService service.
 // All implementations must embed UnimplementedBladeAgentServiceServer
 // for forward compatibility
@@ -104,6 +128,10 @@ type BladeAgentServiceServer interface {
 	SetFanSpeed(context.Context, *SetFanSpeedRequest) (*emptypb.Empty, error)
 	SetStealthMode(context.Context, *StealthModeRequest) (*emptypb.Empty, error)
 	GetStatus(context.Context, *emptypb.Empty) (*StatusResponse, error)
+	// ListEvents returns the journal of events handled by the agent
+	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	// GetTelemetryHistory returns the recent telemetry of the blade, either raw or downsampled
+	GetTelemetryHistory(context.Context, *GetTelemetryHistoryRequest) (*GetTelemetryHistoryResponse, error)
 	mustEmbedUnimplementedBladeAgentServiceServer()
 }
 
@@ -126,6 +154,12 @@ func (UnimplementedBladeAgentServiceServer) SetStealthMode(context.Context, *Ste
 func (UnimplementedBladeAgentServiceServer) GetStatus(context.Context, *emptypb.Empty) (*StatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
 }
+func (UnimplementedBladeAgentServiceServer) ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEvents not implemented")
+}
+func (UnimplementedBladeAgentServiceServer) GetTelemetryHistory(context.Context, *GetTelemetryHistoryRequest) (*GetTelemetryHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTelemetryHistory not implemented")
+}
 func (UnimplementedBladeAgentServiceServer) mustEmbedUnimplementedBladeAgentServiceServer() {}
 
 // UnsafeBladeAgentServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -229,6 +263,42 @@ func _BladeAgentService_GetStatus_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BladeAgentService_ListEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BladeAgentServiceServer).ListEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BladeAgentService_ListEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BladeAgentServiceServer).ListEvents(ctx, req.(*ListEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BladeAgentService_GetTelemetryHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTelemetryHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BladeAgentServiceServer).GetTelemetryHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BladeAgentService_GetTelemetryHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BladeAgentServiceServer).GetTelemetryHistory(ctx, req.(*GetTelemetryHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BladeAgentService_ServiceDesc is the grpc.ServiceDesc for BladeAgentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -256,6 +326,14 @@ var BladeAgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStatus",
 			Handler:    _BladeAgentService_GetStatus_Handler,
 		},
+		{
+			MethodName: "ListEvents",
+			Handler:    _BladeAgentService_ListEvents_Handler,
+		},
+		{
+			MethodName: "GetTelemetryHistory",
+			Handler:    _BladeAgentService_GetTelemetryHistory_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/bladeapi/v1alpha1/blade.proto",
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	bladeapiv1alpha1 "github.com/uptime-induestries/compute-blade-agent/api/bladeapi/v1alpha1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	cmdEvents.Flags().Duration("since", 0, "only show events newer than the given duration (e.g. 1h)")
+	cmdEvents.Flags().Duration("until", 0, "only show events older than the given duration (e.g. 10m)")
+	cmdEvents.Flags().StringSlice("type", nil, "only show events of the given type(s), e.g. identify,critical")
+	cmdEvents.Flags().Uint32("limit", 0, "only show the newest n events")
+	rootCmd.AddCommand(cmdEvents)
+}
+
+var cmdEvents = &cobra.Command{
+	Use:     "events",
+	Example: "bladectl events --since 24h --type critical,critical_reset",
+	Short:   "List events handled by the compute-blade agent",
+	RunE:    runEvents,
+}
+
+func runEvents(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	client := clientFromContext(ctx)
+
+	since, err := cmd.Flags().GetDuration("since")
+	if err != nil {
+		return err
+	}
+	until, err := cmd.Flags().GetDuration("until")
+	if err != nil {
+		return err
+	}
+	types, err := cmd.Flags().GetStringSlice("type")
+	if err != nil {
+		return err
+	}
+	limit, err := cmd.Flags().GetUint32("limit")
+	if err != nil {
+		return err
+	}
+
+	req := &bladeapiv1alpha1.ListEventsRequest{
+		Types: types,
+		Limit: limit,
+	}
+	now := time.Now()
+	if since > 0 {
+		req.Since = timestamppb.New(now.Add(-since))
+	}
+	if until > 0 {
+		req.Until = timestamppb.New(now.Add(-until))
+	}
+
+	resp, err := client.ListEvents(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tSOURCE\tSTATE\tTEMPERATURE\tFAN RPM")
+	for _, event := range resp.GetEvents() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s -> %s\t%s\t%s\n",
+			event.GetTimestamp().AsTime().Local().Format(time.RFC3339),
+			event.GetEvent(),
+			event.GetSource(),
+			event.GetPreviousState(),
+			event.GetNewState(),
+			formatOptional(event.Temperature, "%.1f°C"),
+			formatOptional(event.FanRpm, "%.0f"),
+		)
+	}
+	return w.Flush()
+}
+
+// formatOptional formats an optional value, printing "-" if it is not set
+func formatOptional(val *float64, format string) string {
+	if val == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *val)
+}
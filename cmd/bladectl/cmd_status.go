@@ -0,0 +1,55 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func init() {
+	rootCmd.AddCommand(cmdStatus)
+}
+
+var cmdStatus = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current status of the compute blade",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+		client := clientFromContext(ctx)
+
+		status, err := client.GetStatus(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Temperature:\t%d°C\n", status.GetTemperature())
+		fmt.Fprintf(w, "Fan speed:\t%d rpm\n", status.GetFanRpm())
+		fmt.Fprintf(w, "Fan unit:\t%s\n", status.GetFanUnit())
+		if info := status.GetSmartFanUnit(); info != nil {
+			fmt.Fprintf(w, "Fan unit firmware:\t%s (protocol v%d, capabilities: %s)\n",
+				cmp.Or(info.GetFirmwareVersion(), "unknown"),
+				info.GetProtocolVersion(),
+				cmp.Or(strings.Join(info.GetCapabilities(), ", "), "none"),
+			)
+			if info.GetSide() != "" {
+				fmt.Fprintf(w, "Fan unit blades:\t%s side (alive: %s)\n",
+					info.GetSide(),
+					cmp.Or(strings.Join(info.GetAliveBlades(), ", "), "none"),
+				)
+			}
+		}
+		fmt.Fprintf(w, "Fan unit link:\t%s\n", status.GetFanUnitLink())
+		fmt.Fprintf(w, "Power status:\t%s\n", status.GetPowerStatus())
+		fmt.Fprintf(w, "Low power mode:\t%t\n", status.GetLowPowerActive())
+		fmt.Fprintf(w, "Stealth mode:\t%t\n", status.GetStealthMode())
+		fmt.Fprintf(w, "Identify active:\t%t\n", status.GetIdentifyActive())
+		fmt.Fprintf(w, "Critical active:\t%t\n", status.GetCriticalActive())
+		return w.Flush()
+	},
+}
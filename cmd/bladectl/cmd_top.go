@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	bladeapiv1alpha1 "github.com/uptime-induestries/compute-blade-agent/api/bladeapi/v1alpha1"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+func init() {
+	cmdTop.Flags().Duration("window", 10*time.Minute, "time range shown in the sparklines")
+	cmdTop.Flags().Duration("refresh", 5*time.Second, "refresh interval")
+	cmdTop.Flags().Int("width", 60, "number of data points per sparkline")
+	cmdTop.Flags().Bool("once", false, "render once and exit")
+	rootCmd.AddCommand(cmdTop)
+}
+
+var cmdTop = &cobra.Command{
+	Use:     "top",
+	Example: "bladectl top --window 1h",
+	Short:   "Live view of the blade telemetry history",
+	Long:    "Live view of the blade telemetry history. Runs until interrupted, --timeout applies to each refresh.",
+	RunE:    runTop,
+}
+
+func runTop(cmd *cobra.Command, _ []string) error {
+	client := clientFromContext(cmd.Context())
+
+	window, err := cmd.Flags().GetDuration("window")
+	if err != nil {
+		return err
+	}
+	refresh, err := cmd.Flags().GetDuration("refresh")
+	if err != nil {
+		return err
+	}
+	width, err := cmd.Flags().GetInt("width")
+	if err != nil {
+		return err
+	}
+	once, err := cmd.Flags().GetBool("once")
+	if err != nil {
+		return err
+	}
+	if width <= 0 || window <= 0 || refresh <= 0 {
+		return fmt.Errorf("window, refresh and width must be positive")
+	}
+
+	// top is long-running, so it must not be bound to the global timeout
+	ctx, stop := signal.NotifyContext(context.WithoutCancel(cmd.Context()), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, timeout)
+		resp, err := client.GetTelemetryHistory(reqCtx, &bladeapiv1alpha1.GetTelemetryHistoryRequest{
+			Window:     durationpb.New(window),
+			Resolution: durationpb.New(window / time.Duration(width)),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if !once {
+			// Clear screen and move cursor to the top left corner
+			fmt.Print("\033[H\033[2J")
+		}
+		if err := renderTop(resp.GetBuckets(), window, width); err != nil {
+			return err
+		}
+		if once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderTop prints one sparkline row per metric
+func renderTop(buckets []*bladeapiv1alpha1.TelemetryBucket, window time.Duration, width int) error {
+	fmt.Printf("compute-blade telemetry, last %s (%s)\n\n", window, time.Now().Format(time.TimeOnly))
+
+	metrics := []struct {
+		name   string
+		unit   string
+		getter func(*bladeapiv1alpha1.TelemetryBucket) *bladeapiv1alpha1.TelemetryAggregate
+	}{
+		{"SoC temp", "°C", (*bladeapiv1alpha1.TelemetryBucket).GetSocTemperature},
+		{"Airflow temp", "°C", (*bladeapiv1alpha1.TelemetryBucket).GetAirflowTemperature},
+		{"Fan target", "%", (*bladeapiv1alpha1.TelemetryBucket).GetFanTargetPercent},
+		{"Fan speed", "rpm", (*bladeapiv1alpha1.TelemetryBucket).GetFanRpm},
+	}
+
+	// Buckets without samples are omitted by the agent, so place them by their start time
+	now := time.Now()
+	resolution := window / time.Duration(width)
+	slots := make([]int, len(buckets))
+	for i, bucket := range buckets {
+		slots[i] = width - 1 - int(now.Sub(bucket.GetStart().AsTime())/resolution)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tHISTORY\tNOW\tMIN\tAVG\tMAX")
+	for _, metric := range metrics {
+		values := make([]*bladeapiv1alpha1.TelemetryAggregate, width)
+		for i, bucket := range buckets {
+			if slots[i] >= 0 && slots[i] < width {
+				values[slots[i]] = metric.getter(bucket)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", metric.name, sparkline(values), summarize(values, metric.unit))
+	}
+	return w.Flush()
+}
+
+// sparkline renders the averages of the given aggregates, gaps are rendered as spaces
+func sparkline(values []*bladeapiv1alpha1.TelemetryAggregate) string {
+	var lo, hi float64
+	found := false
+	for _, val := range values {
+		if val == nil {
+			continue
+		}
+		if !found || val.GetAvg() < lo {
+			lo = val.GetAvg()
+		}
+		if !found || val.GetAvg() > hi {
+			hi = val.GetAvg()
+		}
+		found = true
+	}
+
+	var sb strings.Builder
+	for _, val := range values {
+		switch {
+		case val == nil:
+			sb.WriteRune(' ')
+		case hi == lo:
+			sb.WriteRune(sparkBlocks[len(sparkBlocks)/2])
+		default:
+			idx := int((val.GetAvg() - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+			sb.WriteRune(sparkBlocks[idx])
+		}
+	}
+	return sb.String()
+}
+
+// summarize returns the tab separated now/min/avg/max columns
+func summarize(values []*bladeapiv1alpha1.TelemetryAggregate, unit string) string {
+	var (
+		last          *bladeapiv1alpha1.TelemetryAggregate
+		lo, hi, total float64
+		count         int
+	)
+	for _, val := range values {
+		if val == nil {
+			continue
+		}
+		if count == 0 || val.GetMin() < lo {
+			lo = val.GetMin()
+		}
+		if count == 0 || val.GetMax() > hi {
+			hi = val.GetMax()
+		}
+		total += val.GetAvg()
+		count++
+		last = val
+	}
+	if count == 0 {
+		return "-\t-\t-\t-"
+	}
+	return fmt.Sprintf("%.1f%s\t%.1f%s\t%.1f%s\t%.1f%s", last.GetAvg(), unit, lo, unit, total/float64(count), unit, hi, unit)
+}
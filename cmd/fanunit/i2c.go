@@ -0,0 +1,87 @@
+//go:build tinygo
+
+package main
+
+import (
+	"errors"
+	"machine"
+	"time"
+)
+
+// i2cConfig is the configuration of the I2C bus to the EMC2101
+var i2cConfig = machine.I2CConfig{
+	Frequency: 100 * machine.KHz,
+	SDA:       machine.I2C0_SDA_PIN,
+	SCL:       machine.I2C0_SCL_PIN,
+}
+
+const (
+	// i2cHalfClock is half a clock period at 100kHz
+	i2cHalfClock = 5 * time.Microsecond
+	// i2cStretchTimeout is the maximum time a slave may hold SCL low when it's released
+	i2cStretchTimeout = time.Millisecond
+)
+
+var (
+	errSDAStuck = errors.New("SDA stuck low")
+	errSCLStuck = errors.New("SCL stuck low")
+)
+
+// releaseLine lets the pull-up raise an open-drain bus line, a slave may still hold it low
+func releaseLine(pin machine.Pin) {
+	pin.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+}
+
+// pullLine drives an open-drain bus line low, the output level is set before enabling the output
+func pullLine(pin machine.Pin) {
+	pin.Low()
+	pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+}
+
+// releaseSCL releases SCL and waits while a slave stretches the clock
+func releaseSCL(scl machine.Pin) error {
+	releaseLine(scl)
+	deadline := time.Now().Add(i2cStretchTimeout)
+	for !scl.Get() {
+		if time.Now().After(deadline) {
+			return errSCLStuck
+		}
+	}
+	time.Sleep(i2cHalfClock)
+	return nil
+}
+
+// recoverI2C frees the I2C bus from a slave holding SDA low after an interrupted transfer.
+// SCL is clocked until the slave releases SDA (at most 9 clocks to finish a byte and its acknowledge), followed by a
+// stop condition. Lines are only pulled low or released, never driven high, as the bus is open-drain.
+// The I2C peripheral is reinitialised afterward.
+func recoverI2C() error {
+	scl, sda := i2cConfig.SCL, i2cConfig.SDA
+	releaseLine(sda)
+	if err := releaseSCL(scl); err != nil {
+		return err
+	}
+
+	for i := 0; i < 9 && !sda.Get(); i++ {
+		pullLine(scl)
+		time.Sleep(i2cHalfClock)
+		if err := releaseSCL(scl); err != nil {
+			return err
+		}
+	}
+	if !sda.Get() {
+		return errSDAStuck
+	}
+
+	// Stop condition: SDA rises while SCL is high
+	pullLine(scl)
+	pullLine(sda)
+	time.Sleep(i2cHalfClock)
+	if err := releaseSCL(scl); err != nil {
+		return err
+	}
+	releaseLine(sda)
+	time.Sleep(i2cHalfClock)
+
+	return machine.I2C0.Configure(i2cConfig)
+}
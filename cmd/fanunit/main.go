@@ -9,11 +9,24 @@ import (
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/emc2101"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/firmware"
 	"tinygo.org/x/drivers/ws2812"
 )
 
+// firmwareVersion is reported to the blades
+var firmwareVersion = smartfanunit.FirmwareVersion{Major: 0, Minor: 7, Patch: 0}
+
+// buttonPin adapts the button pin to firmware.Button, presses pull the pin low
+type buttonPin machine.Pin
+
+func (p buttonPin) SetInterrupt(callback func()) error {
+	return machine.Pin(p).SetInterrupt(machine.PinFalling, func(machine.Pin) {
+		callback()
+	})
+}
+
 func main() {
-	var controller *Controller
+	var controller *firmware.Controller
 	var emc emc2101.EMC2101
 	var bgrLeds ws2812.Device
 	var err error
@@ -47,27 +60,21 @@ func main() {
 	// Configure button
 	machine.GP12.Configure(machine.PinConfig{Mode: machine.PinInput})
 
-	// Setup emc2101
-	machine.I2C0.Configure(machine.I2CConfig{
-		Frequency: 100 * machine.KHz,
-		SDA:       machine.I2C0_SDA_PIN,
-		SCL:       machine.I2C0_SCL_PIN,
-	})
+	// Setup emc2101, it's initialised by the controller which recovers the I2C bus if that fails
+	machine.I2C0.Configure(i2cConfig)
 	emc = emc2101.New(machine.I2C0)
-	err = emc.Init()
-	if err != nil {
-		println("[!] Failed to initialize emc2101:", err.Error())
-		goto errprint
-	}
 
 	println("[+] IO initialized, starting controller...")
 
 	// Run controller
-	controller = &Controller{
+	controller = &firmware.Controller{
+		FirmwareVersion: firmwareVersion,
 		DefaultFanSpeed: 40,
 		LEDs:            bgrLeds,
 		FanController:   emc,
-		ButtonPin:       machine.GP12,
+		Button:          buttonPin(machine.GP12),
+		Reset:           machine.CPUReset,
+		RecoverBus:      recoverI2C,
 		LeftUART:        machine.UART0,
 		RightUART:       machine.UART1,
 	}
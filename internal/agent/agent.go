@@ -3,7 +3,9 @@ package agent
 import (
 	"context"
 	"errors"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,6 +15,7 @@ import (
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/ledengine"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 	"go.uber.org/zap"
 )
 
@@ -35,12 +38,14 @@ var (
 type Event int
 
 const (
-	NoopEvent = iota
+	NoopEvent Event = iota
 	IdentifyEvent
 	IdentifyConfirmEvent
 	CriticalEvent
 	CriticalResetEvent
 	EdgeButtonEvent
+	PowerStatusChangedEvent
+	FanUnitChangedEvent
 )
 
 func (e Event) String() string {
@@ -57,6 +62,10 @@ func (e Event) String() string {
 		return "critical_reset"
 	case EdgeButtonEvent:
 		return "edge_button"
+	case PowerStatusChangedEvent:
+		return "power_status_changed"
+	case FanUnitChangedEvent:
+		return "fan_unit_changed"
 	default:
 		return "unknown"
 	}
@@ -77,12 +86,41 @@ type ComputeBladeAgentConfig struct {
 	// Critical temperature of the compute blade (used to trigger critical mode)
 	CriticalTemperatureThreshold uint `mapstructure:"critical_temperature_threshold"`
 
+	// ThermalSources selects the thermal sources used by the fan controller and the critical threshold
+	ThermalSources ThermalSourcesConfig `mapstructure:"thermal_sources"`
+
 	// FanSpeed allows to set a fixed fan speed (in percent)
 	FanSpeed *fancontroller.FanOverrideOpts `mapstructure:"fan_speed"`
 	// FanControllerConfig is the configuration of the fan controller
 	FanControllerConfig fancontroller.FanControllerConfig `mapstructure:"fan_controller"`
 
 	ComputeBladeHalOpts hal.ComputeBladeHalOpts `mapstructure:"hal"`
+
+	// EventJournal configures the history of handled events
+	EventJournal EventJournalConfig `mapstructure:"event_journal"`
+
+	// TelemetryHistory configures the rolling history of sampled telemetry
+	TelemetryHistory TelemetryHistoryConfig `mapstructure:"telemetry_history"`
+
+	// Power configures the power monitor and the low-power policy
+	Power PowerConfig `mapstructure:"power"`
+
+	// Storage configures the optional NVMe health monitor
+	Storage StorageConfig `mapstructure:"storage"`
+}
+
+// ComputeBladeStatus is a snapshot of the blade status
+type ComputeBladeStatus struct {
+	StealthMode    bool
+	IdentifyActive bool
+	CriticalActive bool
+	Temperature    float64
+	FanRPM         float64
+	PowerStatus    hal.PowerStatus
+	LowPowerActive bool
+	FanUnitLink    hal.FanUnitLinkStatus
+	FanUnitKind    hal.FanUnitKind
+	FanUnitInfo    hal.FanUnitInfo
 }
 
 // ComputeBladeAgent implements the core-logic of the agent. It is responsible for handling events and interfacing with the hardware.
@@ -98,6 +136,20 @@ type ComputeBladeAgent interface {
 
 	// WaitForIdentifyConfirm blocks until the user confirms the identify mode
 	WaitForIdentifyConfirm(ctx context.Context) error
+	// ListEvents returns the handled events matching the filter, oldest first
+	ListEvents(ctx context.Context, filter EventFilter) ([]EventRecord, error)
+	// GetTelemetrySamples returns the raw telemetry samples of the given window, oldest first
+	GetTelemetrySamples(ctx context.Context, window time.Duration) ([]TelemetrySample, error)
+	// GetTelemetryAggregates returns the telemetry of the given window downsampled to the given resolution
+	GetTelemetryAggregates(ctx context.Context, window, resolution time.Duration) ([]TelemetryBucket, error)
+	// GetStatus returns the current status of the blade
+	GetStatus(ctx context.Context) (ComputeBladeStatus, error)
+}
+
+// eventMessage is an event queued for the event handler together with its origin
+type eventMessage struct {
+	event  Event
+	source EventSource
 }
 
 // computeBladeAgentImpl is the implementation of the ComputeBladeAgent interface
@@ -109,15 +161,29 @@ type computeBladeAgentImpl struct {
 	topLedEngine  ledengine.LedEngine
 
 	fanController fancontroller.FanController
+	fanUnitCurve  *smartfanunit.FanCurve // nil if the fan curve isn't pushed to the smart fan unit
+	fanUnitLUT    *smartfanunit.FanLUT   // nil if the lookup table isn't pushed to the smart fan unit
+	nvmeMonitor   *hal.NvmeMonitor       // nil if the storage monitor is disabled
+
+	journal   EventJournal
+	eventChan chan eventMessage
+	// powerHookChan holds the pending execution of the low-power hook, only the latest one is kept
+	powerHookChan chan powerHookRequest
+
+	telemetry        *TelemetryHistory
+	fanTargetPercent atomic.Int32 // last fan speed set by the fan controller, -1 if unknown
+
+	stealthMode    atomic.Bool
+	powerStatus    atomic.Uint32 // hal.PowerStatus
+	lowPowerActive atomic.Bool
 
-	eventChan chan Event
+	criticalByTemperature atomic.Bool // critical mode has been entered due to the critical temperature threshold
 }
 
 func NewComputeBladeAgent(ctx context.Context, opts ComputeBladeAgentConfig) (ComputeBladeAgent, error) {
 	var err error
 
-	// blade, err := hal.NewCm4Hal(hal.ComputeBladeHalOpts{
-	blade, err := hal.NewCm4Hal(ctx, opts.ComputeBladeHalOpts)
+	blade, err := hal.NewComputeBladeHal(ctx, opts.ComputeBladeHalOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -143,34 +209,92 @@ func NewComputeBladeAgent(ctx context.Context, opts ComputeBladeAgentConfig) (Co
 		return nil, err
 	}
 
-	return &computeBladeAgentImpl{
+	var fanUnitCurve *smartfanunit.FanCurve
+	if opts.FanControllerConfig.FanUnit.Enabled {
+		curve, err := opts.FanControllerConfig.FanUnitCurve()
+		if err != nil {
+			return nil, err
+		}
+		fanUnitCurve = &curve
+	}
+	var fanUnitLUT *smartfanunit.FanLUT
+	if opts.FanControllerConfig.FanUnit.LUT.Enabled {
+		lut, err := opts.FanControllerConfig.FanUnitLUT()
+		if err != nil {
+			return nil, err
+		}
+		fanUnitLUT = &lut
+	}
+
+	journal, err := NewEventJournal(opts.EventJournal)
+	if err != nil {
+		return nil, err
+	}
+
+	var nvmeMonitor *hal.NvmeMonitor
+	if opts.Storage.Enabled {
+		nvmeMonitor, err = hal.NewNvmeMonitor(hal.NvmeMonitorOpts{
+			Devices:   opts.Storage.Devices,
+			Interval:  opts.Storage.Interval,
+			SysfsRoot: opts.ComputeBladeHalOpts.SysfsRoot,
+		}, blade.ThermalSources())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	agent := &computeBladeAgentImpl{
 		opts:          opts,
 		blade:         blade,
 		edgeLedEngine: edgeLedEngine,
 		topLedEngine:  topLedEngine,
 		fanController: fanController,
+		fanUnitCurve:  fanUnitCurve,
+		fanUnitLUT:    fanUnitLUT,
+		nvmeMonitor:   nvmeMonitor,
 		state:         NewComputeBladeState(),
+		journal:       journal,
 		eventChan: make(
-			chan Event,
+			chan eventMessage,
 			10,
 		), // backlog of 10 events. They should process fast but we e.g. don't want to miss button presses
-	}, nil
+		powerHookChan: make(chan powerHookRequest, 1),
+		telemetry:     NewTelemetryHistory(opts.TelemetryHistory),
+	}
+	agent.fanTargetPercent.Store(-1)
+
+	return agent, nil
 }
 
 func (a *computeBladeAgentImpl) Run(origCtx context.Context) error {
 	var wg sync.WaitGroup
 	ctx, cancelCtx := context.WithCancelCause(origCtx)
 	defer a.cleanup(ctx)
+	defer cancelCtx(nil)
 
 	log.FromContext(ctx).Info("Starting ComputeBlade agent")
 
 	// Ingest noop event to initialise metrics
 	a.state.RegisterEvent(NoopEvent)
 
+	a.warnUnknownThermalSources(ctx)
+
 	// Set defaults
-	if err := a.blade.SetStealthMode(a.opts.StealthModeEnabled); err != nil {
+	if err := a.setStealthMode(a.opts.StealthModeEnabled); err != nil {
 		return err
 	}
+	if a.fanUnitCurve != nil {
+		// Pushed to the smart fan unit once connected
+		if err := a.blade.SetFanUnitCurve(*a.fanUnitCurve); err != nil {
+			return err
+		}
+	}
+	if a.fanUnitLUT != nil {
+		// Pushed to the smart fan unit once connected
+		if err := a.blade.SetFanUnitLUT(*a.fanUnitLUT); err != nil {
+			return err
+		}
+	}
 
 	// Run HAL
 	wg.Add(1)
@@ -197,7 +321,7 @@ func (a *computeBladeAgentImpl) Run(origCtx context.Context) error {
 				return
 			}
 			select {
-			case a.eventChan <- Event(EdgeButtonEvent):
+			case a.eventChan <- eventMessage{event: EdgeButtonEvent, source: EventSourceButton}:
 			default:
 				log.FromContext(ctx).Warn("Edge button press event dropped due to backlog")
 				droppedEventCounter.WithLabelValues(Event(EdgeButtonEvent).String()).Inc()
@@ -241,6 +365,70 @@ func (a *computeBladeAgentImpl) Run(origCtx context.Context) error {
 		}
 	}()
 
+	// Start power monitor
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.FromContext(ctx).Info("Starting power monitor")
+		err := a.runPowerMonitor(ctx)
+		if err != nil && err != context.Canceled {
+			log.FromContext(ctx).Error("Power monitor failed", zap.Error(err))
+			cancelCtx(err)
+		}
+	}()
+
+	// Start power hook runner
+	if a.opts.Power.LowPower.Enabled && a.opts.Power.LowPower.Hook != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.FromContext(ctx).Info("Starting power hook runner")
+			err := a.runPowerHooks(ctx)
+			if err != nil && err != context.Canceled {
+				log.FromContext(ctx).Error("Power hook runner failed", zap.Error(err))
+				cancelCtx(err)
+			}
+		}()
+	}
+
+	// Start fan unit monitor
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.FromContext(ctx).Info("Starting fan unit monitor")
+		err := a.runFanUnitMonitor(ctx)
+		if err != nil && err != context.Canceled {
+			log.FromContext(ctx).Error("Fan unit monitor failed", zap.Error(err))
+			cancelCtx(err)
+		}
+	}()
+
+	// Start storage monitor
+	if a.nvmeMonitor != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.FromContext(ctx).Info("Starting storage monitor", zap.Strings("devices", a.nvmeMonitor.Devices()))
+			err := a.nvmeMonitor.Run(ctx)
+			if err != nil && err != context.Canceled {
+				log.FromContext(ctx).Error("Storage monitor failed", zap.Error(err))
+				cancelCtx(err)
+			}
+		}()
+	}
+
+	// Start telemetry sampler
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.FromContext(ctx).Info("Starting telemetry sampler")
+		err := a.runTelemetrySampler(ctx)
+		if err != nil && err != context.Canceled {
+			log.FromContext(ctx).Error("Telemetry sampler failed", zap.Error(err))
+			cancelCtx(err)
+		}
+	}()
+
 	// Start event handler
 	wg.Add(1)
 	go func() {
@@ -250,8 +438,8 @@ func (a *computeBladeAgentImpl) Run(origCtx context.Context) error {
 			select {
 			case <-ctx.Done():
 				return
-			case event := <-a.eventChan:
-				err := a.handleEvent(ctx, event)
+			case msg := <-a.eventChan:
+				err := a.handleEvent(ctx, msg.event, msg.source)
 				if err != nil && err != context.Canceled {
 					log.FromContext(ctx).Error("Event handler failed", zap.Error(err))
 					cancelCtx(err)
@@ -281,12 +469,14 @@ func (a *computeBladeAgentImpl) cleanup(ctx context.Context) {
 	}
 }
 
-func (a *computeBladeAgentImpl) handleEvent(ctx context.Context, event Event) error {
-	log.FromContext(ctx).Info("Handling event", zap.String("event", event.String()))
+func (a *computeBladeAgentImpl) handleEvent(ctx context.Context, event Event, source EventSource) error {
+	log.FromContext(ctx).Info("Handling event", zap.String("event", event.String()), zap.String("source", string(source)))
 	eventCounter.WithLabelValues(event.String()).Inc()
 
 	// register event in state
+	previousState := describeState(a.state)
 	a.state.RegisterEvent(event)
+	a.recordEvent(ctx, event, source, previousState)
 
 	// Dispatch incoming events to the right handler(s)
 	switch event {
@@ -302,6 +492,12 @@ func (a *computeBladeAgentImpl) handleEvent(ctx context.Context, event Event) er
 	case IdentifyConfirmEvent:
 		// Handle identify event
 		return a.handleIdentifyConfirm(ctx)
+	case PowerStatusChangedEvent:
+		// Apply or lift low-power policy
+		return a.handlePowerStatusChanged(ctx)
+	case FanUnitChangedEvent:
+		// The HAL restores fan speed and LED color, the event is only recorded
+		log.FromContext(ctx).Info("Fan unit changed", zap.String("kind", a.blade.FanUnitKind().String()))
 	case EdgeButtonEvent:
 		// Handle edge button press to toggle identify mode
 		event := Event(IdentifyEvent)
@@ -309,7 +505,7 @@ func (a *computeBladeAgentImpl) handleEvent(ctx context.Context, event Event) er
 			event = Event(IdentifyConfirmEvent)
 		}
 		select {
-		case a.eventChan <- Event(event):
+		case a.eventChan <- eventMessage{event: event, source: source}:
 		default:
 			log.FromContext(ctx).Warn("Edge button press event dropped due to backlog")
 			droppedEventCounter.WithLabelValues(event.String()).Inc()
@@ -319,6 +515,30 @@ func (a *computeBladeAgentImpl) handleEvent(ctx context.Context, event Event) er
 	return nil
 }
 
+// recordEvent adds a handled event to the journal, including the current telemetry
+func (a *computeBladeAgentImpl) recordEvent(ctx context.Context, event Event, source EventSource, previousState string) {
+	record := EventRecord{
+		Timestamp:     time.Now(),
+		Event:         event.String(),
+		Source:        source,
+		PreviousState: previousState,
+		NewState:      describeState(a.state),
+	}
+	if temp, err := a.blade.GetTemperature(); err == nil {
+		record.Temperature = &temp
+	}
+	if rpm, err := a.blade.GetFanRPM(); err == nil {
+		record.FanRPM = &rpm
+	}
+	if event == PowerStatusChangedEvent {
+		record.PowerStatus = hal.PowerStatus(a.powerStatus.Load()).String()
+	}
+
+	if err := a.journal.Record(record); err != nil {
+		log.FromContext(ctx).Error("Failed to record event", zap.Error(err))
+	}
+}
+
 func (a *computeBladeAgentImpl) handleIdentifyActive(ctx context.Context) error {
 	log.FromContext(ctx).Info("Identify active")
 	return a.edgeLedEngine.SetPattern(ledengine.NewBurstPattern(led.Color{}, a.opts.IdentifyLedColor))
@@ -336,7 +556,7 @@ func (a *computeBladeAgentImpl) handleCriticalActive(ctx context.Context) error
 	a.fanController.Override(&fancontroller.FanOverrideOpts{Percent: 100})
 
 	// Disable stealth mode (turn on LEDs)
-	setStealthModeError := a.blade.SetStealthMode(false)
+	setStealthModeError := a.setStealthMode(false)
 
 	// Set critical pattern for top LED
 	setPatternTopLedErr := a.topLedEngine.SetPattern(
@@ -352,7 +572,7 @@ func (a *computeBladeAgentImpl) handleCriticalReset(ctx context.Context) error {
 	a.fanController.Override(nil)
 
 	// Reset stealth mode
-	if err := a.blade.SetStealthMode(a.opts.StealthModeEnabled); err != nil {
+	if err := a.setStealthMode(a.opts.StealthModeEnabled); err != nil {
 		return err
 	}
 
@@ -365,7 +585,7 @@ func (a *computeBladeAgentImpl) handleCriticalReset(ctx context.Context) error {
 }
 
 func (a *computeBladeAgentImpl) Close() error {
-	return errors.Join(a.blade.Close())
+	return errors.Join(a.blade.Close(), a.journal.Close())
 }
 
 // runTopLedEngine runs the top LED engine
@@ -401,25 +621,75 @@ func (a *computeBladeAgentImpl) runFanController(ctx context.Context) error {
 		case <-ticker.C:
 		}
 
+		// Enter/leave critical mode based on the critical temperature
+		if err := a.checkCriticalTemperature(ctx); err != nil {
+			return err
+		}
+
 		// Get temperature
-		temp, err := a.blade.GetTemperature()
+		temp, err := a.blade.ThermalSources().Temperature(ctx, a.opts.ThermalSources.FanController...)
 		if err != nil {
 			log.FromContext(ctx).Error("Failed to get temperature", zap.Error(err))
 			temp = 100 // set to a high value to trigger the maximum speed defined by the fan curve
 		}
 		// Derive fan speed from temperature
-		speed := a.fanController.GetFanSpeed(temp)
+		speed := a.capFanSpeed(a.fanController.GetFanSpeed(temp))
 		// Set fan speed
 		if err := a.blade.SetFanSpeed(speed); err != nil {
 			log.FromContext(ctx).Error("Failed to set fan speed", zap.Error(err))
 		}
+		a.fanTargetPercent.Store(int32(speed))
+	}
+}
+
+// runTelemetrySampler periodically adds the current telemetry to the history
+func (a *computeBladeAgentImpl) runTelemetrySampler(ctx context.Context) error {
+	interval := a.opts.TelemetryHistory.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		sample := TelemetrySample{
+			Timestamp:          time.Now(),
+			SocTemperature:     math.NaN(),
+			AirFlowTemperature: math.NaN(),
+			FanTargetPercent:   math.NaN(),
+			FanRPM:             math.NaN(),
+		}
+		if temp, err := a.blade.GetTemperature(); err == nil {
+			sample.SocTemperature = temp
+		}
+		if temp, err := a.blade.GetAirFlowTemperature(); err == nil {
+			sample.AirFlowTemperature = temp
+		}
+		if percent := a.fanTargetPercent.Load(); percent >= 0 {
+			sample.FanTargetPercent = float64(percent)
+		}
+		if rpm, err := a.blade.GetFanRPM(); err == nil {
+			sample.FanRPM = rpm
+		}
+		a.telemetry.Add(sample)
 	}
 }
 
 // EmitEvent dispatches an event to the event handler
 func (a *computeBladeAgentImpl) EmitEvent(ctx context.Context, event Event) error {
+	return a.emitEvent(ctx, event, EventSourceAPI)
+}
+
+// emitEvent dispatches an event with the given source to the event handler
+func (a *computeBladeAgentImpl) emitEvent(ctx context.Context, event Event, source EventSource) error {
 	select {
-	case a.eventChan <- event:
+	case a.eventChan <- eventMessage{event: event, source: source}:
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
@@ -440,10 +710,62 @@ func (a *computeBladeAgentImpl) SetStealthMode(_ context.Context, enabled bool)
 	if a.state.CriticalActive() {
 		return errors.New("cannot set stealth mode while the blade is in a critical state")
 	}
-	return a.blade.SetStealthMode(enabled)
+	return a.setStealthMode(enabled)
+}
+
+// setStealthMode sets the stealth mode on the blade and keeps track of it
+func (a *computeBladeAgentImpl) setStealthMode(enabled bool) error {
+	if err := a.blade.SetStealthMode(enabled); err != nil {
+		return err
+	}
+	a.stealthMode.Store(enabled)
+	return nil
 }
 
 // WaitForIdentifyConfirm waits for the identify confirm event
 func (a *computeBladeAgentImpl) WaitForIdentifyConfirm(ctx context.Context) error {
 	return a.state.WaitForIdentifyConfirm(ctx)
 }
+
+// ListEvents returns the journaled events matching the filter
+func (a *computeBladeAgentImpl) ListEvents(_ context.Context, filter EventFilter) ([]EventRecord, error) {
+	return a.journal.List(filter), nil
+}
+
+// GetTelemetrySamples returns the raw telemetry samples of the given window
+func (a *computeBladeAgentImpl) GetTelemetrySamples(_ context.Context, window time.Duration) ([]TelemetrySample, error) {
+	return a.telemetry.Samples(time.Now().Add(-window)), nil
+}
+
+// GetTelemetryAggregates returns the downsampled telemetry of the given window
+func (a *computeBladeAgentImpl) GetTelemetryAggregates(_ context.Context, window, resolution time.Duration) ([]TelemetryBucket, error) {
+	if resolution <= 0 {
+		return nil, errors.New("resolution must be positive")
+	}
+	return a.telemetry.Aggregate(time.Now().Add(-window), resolution), nil
+}
+
+// GetStatus aggregates the current status of the blade
+func (a *computeBladeAgentImpl) GetStatus(_ context.Context) (ComputeBladeStatus, error) {
+	temp, err := a.blade.GetTemperature()
+	if err != nil {
+		return ComputeBladeStatus{}, err
+	}
+
+	status := ComputeBladeStatus{
+		StealthMode:    a.stealthMode.Load(),
+		IdentifyActive: a.state.IdentifyActive(),
+		CriticalActive: a.state.CriticalActive(),
+		Temperature:    temp,
+		PowerStatus:    hal.PowerStatus(a.powerStatus.Load()),
+		LowPowerActive: a.lowPowerActive.Load(),
+		FanUnitLink:    a.blade.FanUnitLinkStatus(),
+		FanUnitKind:    a.blade.FanUnitKind(),
+		FanUnitInfo:    a.blade.FanUnitInfo(),
+	}
+	// The fan RPM is unknown e.g. without smart fan unit telemetry or with RPM reporting disabled
+	if rpm, err := a.blade.GetFanRPM(); err == nil {
+		status.FanRPM = rpm
+	}
+	return status, nil
+}
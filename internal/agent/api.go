@@ -2,11 +2,15 @@ package agent
 
 import (
 	"context"
+	"math"
 
 	bladeapiv1alpha1 "github.com/uptime-induestries/compute-blade-agent/api/bladeapi/v1alpha1"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // ComputeBladeAgent implementing the BladeAgentServiceServer
@@ -60,6 +64,152 @@ func (service *agentGrpcService) SetStealthMode(ctx context.Context, req *bladea
 }
 
 // GetStatus aggregates the status of the blade
-func (service *agentGrpcService) GetStatus(context.Context, *emptypb.Empty) (*bladeapiv1alpha1.StatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+func (service *agentGrpcService) GetStatus(ctx context.Context, _ *emptypb.Empty) (*bladeapiv1alpha1.StatusResponse, error) {
+	bladeStatus, err := service.Agent.GetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	powerStatus := bladeapiv1alpha1.PowerStatus_POE_OR_USBC
+	if bladeStatus.PowerStatus == hal.PowerPoe802at {
+		powerStatus = bladeapiv1alpha1.PowerStatus_POE_802_AT
+	}
+
+	fanUnit := bladeapiv1alpha1.FanUnit_DEFAULT
+	var smartFanUnit *bladeapiv1alpha1.SmartFanUnitInfo
+	if bladeStatus.FanUnitKind == hal.FanUnitKindSmart {
+		fanUnit = bladeapiv1alpha1.FanUnit_SMART
+		smartFanUnit = &bladeapiv1alpha1.SmartFanUnitInfo{
+			ProtocolVersion: uint32(bladeStatus.FanUnitInfo.ProtocolVersion),
+			FirmwareVersion: bladeStatus.FanUnitInfo.FirmwareVersion,
+			Capabilities:    bladeStatus.FanUnitInfo.Capabilities.Names(),
+		}
+		if blades := bladeStatus.FanUnitInfo.BladeStatus; blades != nil {
+			smartFanUnit.Side = blades.Side.String()
+			for _, side := range []smartfanunit.BladeSide{smartfanunit.BladeSideLeft, smartfanunit.BladeSideRight} {
+				if blades.Alive(side) {
+					smartFanUnit.AliveBlades = append(smartFanUnit.AliveBlades, side.String())
+				}
+			}
+		}
+	}
+
+	return &bladeapiv1alpha1.StatusResponse{
+		StealthMode:    bladeStatus.StealthMode,
+		IdentifyActive: bladeStatus.IdentifyActive,
+		CriticalActive: bladeStatus.CriticalActive,
+		Temperature:    int64(bladeStatus.Temperature),
+		FanRpm:         int64(bladeStatus.FanRPM),
+		PowerStatus:    powerStatus,
+		LowPowerActive: bladeStatus.LowPowerActive,
+		FanUnitLink:    bladeapiv1alpha1.FanUnitLinkStatus(bladeStatus.FanUnitLink),
+		FanUnit:        fanUnit,
+		SmartFanUnit:   smartFanUnit,
+	}, nil
+}
+
+// ListEvents returns the journal of handled events
+func (service *agentGrpcService) ListEvents(
+	ctx context.Context,
+	req *bladeapiv1alpha1.ListEventsRequest,
+) (*bladeapiv1alpha1.ListEventsResponse, error) {
+	filter := EventFilter{
+		Types: req.GetTypes(),
+		Limit: int(req.GetLimit()),
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+
+	records, err := service.Agent.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &bladeapiv1alpha1.ListEventsResponse{
+		Events: make([]*bladeapiv1alpha1.EventRecord, 0, len(records)),
+	}
+	for _, record := range records {
+		resp.Events = append(resp.Events, &bladeapiv1alpha1.EventRecord{
+			Timestamp:     timestamppb.New(record.Timestamp),
+			Event:         record.Event,
+			Source:        string(record.Source),
+			PreviousState: record.PreviousState,
+			NewState:      record.NewState,
+			Temperature:   record.Temperature,
+			FanRpm:        record.FanRPM,
+			PowerStatus:   record.PowerStatus,
+		})
+	}
+	return resp, nil
+}
+
+// GetTelemetryHistory returns the recent telemetry of the blade
+func (service *agentGrpcService) GetTelemetryHistory(
+	ctx context.Context,
+	req *bladeapiv1alpha1.GetTelemetryHistoryRequest,
+) (*bladeapiv1alpha1.GetTelemetryHistoryResponse, error) {
+	if req.GetWindow().AsDuration() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "a positive window is required")
+	}
+	window := req.GetWindow().AsDuration()
+	resp := &bladeapiv1alpha1.GetTelemetryHistoryResponse{}
+
+	// Raw samples
+	if req.GetResolution().AsDuration() <= 0 {
+		samples, err := service.Agent.GetTelemetrySamples(ctx, window)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			resp.Samples = append(resp.Samples, &bladeapiv1alpha1.TelemetrySample{
+				Timestamp:          timestamppb.New(sample.Timestamp),
+				SocTemperature:     optionalFloat(sample.SocTemperature),
+				AirflowTemperature: optionalFloat(sample.AirFlowTemperature),
+				FanTargetPercent:   optionalFloat(sample.FanTargetPercent),
+				FanRpm:             optionalFloat(sample.FanRPM),
+			})
+		}
+		return resp, nil
+	}
+
+	// Downsampled aggregates
+	buckets, err := service.Agent.GetTelemetryAggregates(ctx, window, req.GetResolution().AsDuration())
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range buckets {
+		resp.Buckets = append(resp.Buckets, &bladeapiv1alpha1.TelemetryBucket{
+			Start:              timestamppb.New(bucket.Start),
+			End:                timestamppb.New(bucket.End),
+			Samples:            uint32(bucket.Samples),
+			SocTemperature:     telemetryAggregateToProto(bucket.SocTemperature),
+			AirflowTemperature: telemetryAggregateToProto(bucket.AirFlowTemperature),
+			FanTargetPercent:   telemetryAggregateToProto(bucket.FanTargetPercent),
+			FanRpm:             telemetryAggregateToProto(bucket.FanRPM),
+		})
+	}
+	return resp, nil
+}
+
+// optionalFloat maps NaN (-> value not available) to an unset optional field
+func optionalFloat(val float64) *float64 {
+	if math.IsNaN(val) {
+		return nil
+	}
+	return &val
+}
+
+func telemetryAggregateToProto(agg *TelemetryAggregate) *bladeapiv1alpha1.TelemetryAggregate {
+	if agg == nil {
+		return nil
+	}
+	return &bladeapiv1alpha1.TelemetryAggregate{
+		Min: agg.Min,
+		Max: agg.Max,
+		Avg: agg.Avg,
+	}
 }
@@ -0,0 +1,17 @@
+package agent
+
+import (
+	"context"
+)
+
+// runFanUnitMonitor emits an event whenever the fan unit changes (e.g. the smart fan unit has been connected or lost)
+func (a *computeBladeAgentImpl) runFanUnitMonitor(ctx context.Context) error {
+	for {
+		if _, err := a.blade.WaitForFanUnitChange(ctx); err != nil {
+			return err
+		}
+		if err := a.emitEvent(ctx, FanUnitChangedEvent, EventSourceAgent); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/util"
+)
+
+// EventSource describes who or what triggered an event
+type EventSource string
+
+const (
+	// EventSourceAPI is used for events emitted through the gRPC API (e.g. bladectl)
+	EventSourceAPI EventSource = "api"
+	// EventSourceButton is used for events caused by the edge or fan unit button
+	EventSourceButton EventSource = "button"
+	// EventSourceAgent is used for events raised by the agent itself
+	EventSourceAgent EventSource = "agent"
+)
+
+// EventJournalConfig configures the journal of handled events
+type EventJournalConfig struct {
+	// Size is the maximum number of events kept in the journal
+	Size int `mapstructure:"size"`
+	// Path optionally persists the journal to disk so it survives agent restarts
+	Path string `mapstructure:"path"`
+}
+
+// EventRecord is a single entry of the event journal
+type EventRecord struct {
+	Timestamp     time.Time   `json:"timestamp"`
+	Event         string      `json:"event"`
+	Source        EventSource `json:"source"`
+	PreviousState string      `json:"previous_state"`
+	NewState      string      `json:"new_state"`
+	// Telemetry at the time the event was handled; nil if it could not be read
+	Temperature *float64 `json:"temperature,omitempty"`
+	FanRPM      *float64 `json:"fan_rpm,omitempty"`
+	// PowerStatus is only set for power status changes
+	PowerStatus string `json:"power_status,omitempty"`
+}
+
+// EventFilter restricts the events returned by the journal
+type EventFilter struct {
+	// Since and Until limit the time range; zero values are unbounded
+	Since time.Time
+	Until time.Time
+	// Types limits the result to the given event types (e.g. "identify"); empty matches all
+	Types []string
+	// Limit returns only the newest n matching events; 0 returns all
+	Limit int
+}
+
+// Matches returns true if the record satisfies the filter
+func (f EventFilter) Matches(record EventRecord) bool {
+	if !f.Since.IsZero() && record.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && record.Timestamp.After(f.Until) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, record.Event) {
+		return false
+	}
+	return true
+}
+
+// EventJournal keeps a bounded history of handled events
+type EventJournal interface {
+	// Record appends an event to the journal
+	Record(record EventRecord) error
+	// List returns all events matching the filter, oldest first
+	List(filter EventFilter) []EventRecord
+	// Close flushes and closes the journal
+	Close() error
+}
+
+type eventJournalImpl struct {
+	records *util.RingBuffer[EventRecord]
+
+	// Persistence, only used when a path is configured
+	mu           sync.Mutex
+	path         string
+	file         *os.File
+	linesWritten int
+}
+
+// NewEventJournal creates an event journal. If a path is configured, previous events are loaded from disk.
+func NewEventJournal(config EventJournalConfig) (EventJournal, error) {
+	size := config.Size
+	if size <= 0 {
+		size = 512
+	}
+
+	j := &eventJournalImpl{
+		records: util.NewRingBuffer[EventRecord](size),
+		path:    config.Path,
+	}
+	if j.path == "" {
+		return j, nil
+	}
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	// Compact on startup so the file never grows beyond twice the journal size
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// load reads persisted records; malformed lines (e.g. from a torn write) are skipped
+func (j *eventJournalImpl) load() error {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record EventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		j.records.Push(record)
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the persisted journal with the records currently held in memory
+func (j *eventJournalImpl) compact() error {
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			return err
+		}
+		j.file = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	records := j.records.Items()
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return err
+	}
+
+	j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	j.linesWritten = len(records)
+	return nil
+}
+
+func (j *eventJournalImpl) Record(record EventRecord) error {
+	j.records.Push(record)
+	if j.path == "" {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.linesWritten >= 2*j.records.Cap() {
+		if err := j.compact(); err != nil {
+			return fmt.Errorf("failed to compact event journal: %w", err)
+		}
+		return nil
+	}
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(raw, '\n')); err != nil {
+		return fmt.Errorf("failed to persist event: %w", err)
+	}
+	j.linesWritten++
+	return nil
+}
+
+func (j *eventJournalImpl) List(filter EventFilter) []EventRecord {
+	result := []EventRecord{}
+	for _, record := range j.records.Items() {
+		if filter.Matches(record) {
+			result = append(result, record)
+		}
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result
+}
+
+func (j *eventJournalImpl) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// describeState summarises the blade state for the event journal
+func describeState(state ComputebladeState) string {
+	switch {
+	case state.CriticalActive() && state.IdentifyActive():
+		return "critical+identify"
+	case state.CriticalActive():
+		return "critical"
+	case state.IdentifyActive():
+		return "identify"
+	default:
+		return "normal"
+	}
+}
@@ -0,0 +1,122 @@
+package agent_test
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/internal/agent"
+)
+
+func TestEventJournal_Bounded(t *testing.T) {
+	t.Parallel()
+
+	journal, err := agent.NewEventJournal(agent.EventJournalConfig{Size: 3})
+	require.NoError(t, err)
+	defer journal.Close()
+
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, journal.Record(agent.EventRecord{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Event:     agent.IdentifyEvent.String(),
+			Source:    agent.EventSourceAPI,
+		}))
+	}
+
+	events := journal.List(agent.EventFilter{})
+	assert.Len(t, events, 3)
+	assert.Equal(t, base.Add(2*time.Second), events[0].Timestamp)
+	assert.Equal(t, base.Add(4*time.Second), events[2].Timestamp)
+}
+
+func TestEventJournal_Filter(t *testing.T) {
+	t.Parallel()
+
+	journal, err := agent.NewEventJournal(agent.EventJournalConfig{Size: 10})
+	require.NoError(t, err)
+	defer journal.Close()
+
+	base := time.Now()
+	for i, event := range []agent.Event{
+		agent.IdentifyEvent,
+		agent.CriticalEvent,
+		agent.IdentifyConfirmEvent,
+		agent.CriticalResetEvent,
+		agent.IdentifyEvent,
+	} {
+		assert.NoError(t, journal.Record(agent.EventRecord{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Event:     event.String(),
+			Source:    agent.EventSourceButton,
+		}))
+	}
+
+	// Type filter
+	events := journal.List(agent.EventFilter{Types: []string{"critical", "critical_reset"}})
+	assert.Len(t, events, 2)
+	assert.Equal(t, "critical", events[0].Event)
+	assert.Equal(t, "critical_reset", events[1].Event)
+
+	// Time filter
+	events = journal.List(agent.EventFilter{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)})
+	assert.Len(t, events, 3)
+	assert.Equal(t, "critical", events[0].Event)
+
+	// Limit returns the newest events
+	events = journal.List(agent.EventFilter{Types: []string{"identify"}, Limit: 1})
+	assert.Len(t, events, 1)
+	assert.Equal(t, base.Add(4*time.Minute), events[0].Timestamp)
+}
+
+func TestEventJournal_Persistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal", "events.jsonl")
+	temp := 42.5
+
+	journal, err := agent.NewEventJournal(agent.EventJournalConfig{Size: 2, Path: path})
+	require.NoError(t, err)
+	base := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, journal.Record(agent.EventRecord{
+			Timestamp:   base.Add(time.Duration(i) * time.Second),
+			Event:       agent.CriticalEvent.String(),
+			Source:      agent.EventSourceAgent,
+			Temperature: &temp,
+		}))
+	}
+	assert.NoError(t, journal.Close())
+
+	// The file is compacted regularly and never exceeds twice the journal size
+	assert.LessOrEqual(t, countLines(t, path), 4)
+
+	// Reopening the journal restores the newest events
+	journal, err = agent.NewEventJournal(agent.EventJournalConfig{Size: 2, Path: path})
+	require.NoError(t, err)
+	defer journal.Close()
+
+	events := journal.List(agent.EventFilter{})
+	require.Len(t, events, 2)
+	assert.True(t, base.Add(6*time.Second).Equal(events[1].Timestamp))
+	assert.Equal(t, agent.EventSourceAgent, events[1].Source)
+	assert.Equal(t, &temp, events[1].Temperature)
+	assert.Nil(t, events[1].FanRPM)
+}
+
+func countLines(t *testing.T, path string) int {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
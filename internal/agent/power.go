@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"go.uber.org/zap"
+)
+
+const (
+	powerHookTimeout          = 30 * time.Second
+	powerMonitorRetryInterval = 5 * time.Second
+)
+
+// PowerConfig configures the power monitor of the agent
+type PowerConfig struct {
+	// LowPower is the policy applied when the blade is not powered by PoE+ (802.3at)
+	LowPower LowPowerConfig `mapstructure:"low_power"`
+}
+
+// LowPowerConfig configures the policy applied when the blade is not powered by PoE+ (802.3at)
+type LowPowerConfig struct {
+	// Enabled enables the low-power policy
+	Enabled bool `mapstructure:"enabled"`
+	// MaxFanSpeedPercent caps the fan speed; 0 disables the cap. The cap is not applied in critical mode.
+	MaxFanSpeedPercent uint8 `mapstructure:"max_fan_speed_percent"`
+	// LedBrightness scales the brightness of the LEDs (0-1)
+	LedBrightness float64 `mapstructure:"led_brightness"`
+	// Hook is an executable called with the new power status (poe+, poeOrUsbC) whenever the power status changes
+	Hook string `mapstructure:"hook"`
+}
+
+// powerHookRequest is an execution of the low-power hook
+type powerHookRequest struct {
+	status   hal.PowerStatus
+	lowPower bool
+}
+
+// runPowerMonitor emits an event with the initial power status and whenever the power status changes
+func (a *computeBladeAgentImpl) runPowerMonitor(ctx context.Context) error {
+	status, err := a.blade.GetPowerStatus()
+	if err != nil {
+		log.FromContext(ctx).Error("Failed to get power status", zap.Error(err))
+	}
+
+	known := false
+	for {
+		if err == nil && (!known || hal.PowerStatus(a.powerStatus.Load()) != status) {
+			known = true
+			a.powerStatus.Store(uint32(status))
+			if err := a.emitEvent(ctx, PowerStatusChangedEvent, EventSourceAgent); err != nil {
+				return err
+			}
+		}
+
+		// Pass the last known status so that a change after the initial read is not missed
+		status, err = a.blade.WaitForPowerStatusChange(ctx, hal.PowerStatus(a.powerStatus.Load()))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.FromContext(ctx).Error("Failed to wait for power status change", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(powerMonitorRetryInterval):
+			}
+			status, err = a.blade.GetPowerStatus()
+		}
+	}
+}
+
+// handlePowerStatusChanged applies or lifts the low-power policy
+func (a *computeBladeAgentImpl) handlePowerStatusChanged(ctx context.Context) error {
+	status := hal.PowerStatus(a.powerStatus.Load())
+	policy := a.opts.Power.LowPower
+	lowPower := policy.Enabled && status != hal.PowerPoe802at
+	log.FromContext(ctx).Info("Power status changed", zap.String("status", status.String()), zap.Bool("low_power", lowPower))
+
+	a.lowPowerActive.Store(lowPower)
+
+	brightness := 1.0
+	if lowPower && policy.LedBrightness > 0 && policy.LedBrightness < 1 {
+		brightness = policy.LedBrightness
+	}
+	a.edgeLedEngine.SetBrightness(brightness)
+	a.topLedEngine.SetBrightness(brightness)
+
+	if policy.Enabled && policy.Hook != "" {
+		a.queuePowerHook(powerHookRequest{status: status, lowPower: lowPower})
+	}
+	return nil
+}
+
+// queuePowerHook queues an execution of the low-power hook, replacing a pending one as only the latest status matters
+func (a *computeBladeAgentImpl) queuePowerHook(req powerHookRequest) {
+	for {
+		select {
+		case a.powerHookChan <- req:
+			return
+		default:
+		}
+		select {
+		case <-a.powerHookChan:
+		default:
+		}
+	}
+}
+
+// runPowerHooks executes the queued low-power hooks one after another
+func (a *computeBladeAgentImpl) runPowerHooks(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case req := <-a.powerHookChan:
+			a.runPowerHook(ctx, req.status, req.lowPower)
+		}
+	}
+}
+
+// runPowerHook executes the configured low-power hook
+func (a *computeBladeAgentImpl) runPowerHook(ctx context.Context, status hal.PowerStatus, lowPower bool) {
+	hookCtx, cancel := context.WithTimeout(ctx, powerHookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(hookCtx, a.opts.Power.LowPower.Hook, status.String())
+	cmd.Env = append(os.Environ(), fmt.Sprintf("BLADE_LOW_POWER=%t", lowPower))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.FromContext(ctx).Error("Power hook failed", zap.Error(err), zap.ByteString("output", out))
+		return
+	}
+	log.FromContext(ctx).Info("Power hook executed", zap.ByteString("output", out))
+}
+
+// capFanSpeed limits the fan speed while the low-power policy is active (except in critical mode)
+func (a *computeBladeAgentImpl) capFanSpeed(speed uint8) uint8 {
+	maxSpeed := a.opts.Power.LowPower.MaxFanSpeedPercent
+	if !a.lowPowerActive.Load() || a.state.CriticalActive() || maxSpeed == 0 || speed <= maxSpeed {
+		return speed
+	}
+	return maxSpeed
+}
@@ -0,0 +1,16 @@
+package agent
+
+import (
+	"time"
+)
+
+// StorageConfig configures the optional NVMe health monitor.
+// The composite temperature of each device is available as thermal source <device>/composite (e.g. nvme0/composite).
+type StorageConfig struct {
+	// Enabled enables the NVMe health monitor
+	Enabled bool `mapstructure:"enabled"`
+	// Interval in which the health data is read
+	Interval time.Duration `mapstructure:"interval"`
+	// Devices are the NVMe controllers to monitor (e.g. nvme0), all controllers are discovered if empty
+	Devices []string `mapstructure:"devices"`
+}
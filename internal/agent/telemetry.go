@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"math"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/util"
+)
+
+// TelemetryHistoryConfig configures the rolling telemetry history kept by the agent
+type TelemetryHistoryConfig struct {
+	// Interval between two samples
+	Interval time.Duration `mapstructure:"interval"`
+	// Size is the number of samples kept (Size*Interval is the covered time range)
+	Size int `mapstructure:"size"`
+}
+
+// TelemetrySample is a single telemetry reading. Values that could not be read are NaN.
+type TelemetrySample struct {
+	Timestamp          time.Time
+	SocTemperature     float64
+	AirFlowTemperature float64
+	FanTargetPercent   float64
+	FanRPM             float64
+}
+
+// TelemetryAggregate summarises a single metric over a time range
+type TelemetryAggregate struct {
+	Min float64
+	Max float64
+	Avg float64
+}
+
+// TelemetryBucket holds the aggregated samples of a time range. Aggregates are nil if no valid value was sampled.
+type TelemetryBucket struct {
+	Start              time.Time
+	End                time.Time
+	Samples            int
+	SocTemperature     *TelemetryAggregate
+	AirFlowTemperature *TelemetryAggregate
+	FanTargetPercent   *TelemetryAggregate
+	FanRPM             *TelemetryAggregate
+}
+
+// TelemetryHistory is a rolling history of telemetry samples
+type TelemetryHistory struct {
+	samples *util.RingBuffer[TelemetrySample]
+}
+
+// NewTelemetryHistory creates a telemetry history with the configured size
+func NewTelemetryHistory(config TelemetryHistoryConfig) *TelemetryHistory {
+	size := config.Size
+	if size <= 0 {
+		size = 720
+	}
+	return &TelemetryHistory{
+		samples: util.NewRingBuffer[TelemetrySample](size),
+	}
+}
+
+// Add appends a sample to the history, dropping the oldest one if the history is full
+func (h *TelemetryHistory) Add(sample TelemetrySample) {
+	h.samples.Push(sample)
+}
+
+// Samples returns all samples taken at or after since, oldest first
+func (h *TelemetryHistory) Samples(since time.Time) []TelemetrySample {
+	result := []TelemetrySample{}
+	for _, sample := range h.samples.Items() {
+		if !sample.Timestamp.Before(since) {
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+// Aggregate downsamples all samples taken at or after since into buckets of the given resolution.
+// Buckets without samples are omitted.
+func (h *TelemetryHistory) Aggregate(since time.Time, resolution time.Duration) []TelemetryBucket {
+	buckets := []TelemetryBucket{}
+	if resolution <= 0 {
+		return buckets
+	}
+
+	var (
+		current *TelemetryBucket
+		values  [4][]float64
+	)
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.SocTemperature = aggregate(values[0])
+		current.AirFlowTemperature = aggregate(values[1])
+		current.FanTargetPercent = aggregate(values[2])
+		current.FanRPM = aggregate(values[3])
+		buckets = append(buckets, *current)
+		values = [4][]float64{}
+	}
+
+	for _, sample := range h.Samples(since) {
+		idx := sample.Timestamp.Sub(since) / resolution
+		start := since.Add(idx * resolution)
+		if current == nil || !current.Start.Equal(start) {
+			flush()
+			current = &TelemetryBucket{Start: start, End: start.Add(resolution)}
+		}
+		current.Samples++
+		values[0] = append(values[0], sample.SocTemperature)
+		values[1] = append(values[1], sample.AirFlowTemperature)
+		values[2] = append(values[2], sample.FanTargetPercent)
+		values[3] = append(values[3], sample.FanRPM)
+	}
+	flush()
+
+	return buckets
+}
+
+// aggregate calculates min/max/avg, ignoring NaN values
+func aggregate(values []float64) *TelemetryAggregate {
+	var (
+		result TelemetryAggregate
+		sum    float64
+		count  int
+	)
+	for _, val := range values {
+		if math.IsNaN(val) {
+			continue
+		}
+		if count == 0 || val < result.Min {
+			result.Min = val
+		}
+		if count == 0 || val > result.Max {
+			result.Max = val
+		}
+		sum += val
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	result.Avg = sum / float64(count)
+	return &result
+}
@@ -0,0 +1,76 @@
+package agent_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/internal/agent"
+)
+
+func TestTelemetryHistory_Samples(t *testing.T) {
+	t.Parallel()
+
+	history := agent.NewTelemetryHistory(agent.TelemetryHistoryConfig{Size: 3})
+	base := time.Now()
+	for i := 0; i < 4; i++ {
+		history.Add(agent.TelemetrySample{Timestamp: base.Add(time.Duration(i) * time.Second), SocTemperature: float64(i)})
+	}
+
+	// Oldest sample got dropped
+	samples := history.Samples(time.Time{})
+	require.Len(t, samples, 3)
+	assert.Equal(t, 1.0, samples[0].SocTemperature)
+
+	samples = history.Samples(base.Add(2 * time.Second))
+	require.Len(t, samples, 2)
+	assert.Equal(t, 2.0, samples[0].SocTemperature)
+	assert.Equal(t, 3.0, samples[1].SocTemperature)
+}
+
+func TestTelemetryHistory_Aggregate(t *testing.T) {
+	t.Parallel()
+
+	history := agent.NewTelemetryHistory(agent.TelemetryHistoryConfig{Size: 100})
+	base := time.Now()
+	add := func(offset time.Duration, temp, airflow float64) {
+		history.Add(agent.TelemetrySample{
+			Timestamp:          base.Add(offset),
+			SocTemperature:     temp,
+			AirFlowTemperature: airflow,
+			FanTargetPercent:   50,
+			FanRPM:             math.NaN(),
+		})
+	}
+	// First bucket
+	add(0, 40, math.NaN())
+	add(2*time.Second, 50, 30)
+	add(4*time.Second, 60, math.NaN())
+	// Second bucket is empty, third bucket has a single sample
+	add(21*time.Second, 45, 25)
+
+	buckets := history.Aggregate(base, 10*time.Second)
+	require.Len(t, buckets, 2)
+
+	assert.Equal(t, base, buckets[0].Start)
+	assert.Equal(t, base.Add(10*time.Second), buckets[0].End)
+	assert.Equal(t, 3, buckets[0].Samples)
+	assert.Equal(t, &agent.TelemetryAggregate{Min: 40, Max: 60, Avg: 50}, buckets[0].SocTemperature)
+	assert.Equal(t, &agent.TelemetryAggregate{Min: 30, Max: 30, Avg: 30}, buckets[0].AirFlowTemperature)
+	assert.Equal(t, &agent.TelemetryAggregate{Min: 50, Max: 50, Avg: 50}, buckets[0].FanTargetPercent)
+	assert.Nil(t, buckets[0].FanRPM)
+
+	assert.Equal(t, base.Add(20*time.Second), buckets[1].Start)
+	assert.Equal(t, 1, buckets[1].Samples)
+	assert.Equal(t, &agent.TelemetryAggregate{Min: 45, Max: 45, Avg: 45}, buckets[1].SocTemperature)
+}
+
+func TestTelemetryHistory_AggregateInvalidResolution(t *testing.T) {
+	t.Parallel()
+
+	history := agent.NewTelemetryHistory(agent.TelemetryHistoryConfig{})
+	history.Add(agent.TelemetrySample{Timestamp: time.Now()})
+	assert.Empty(t, history.Aggregate(time.Time{}, 0))
+}
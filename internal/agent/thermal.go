@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"go.uber.org/zap"
+)
+
+// criticalTemperatureHysteresis is the distance below the critical threshold required to leave critical mode
+const criticalTemperatureHysteresis = 5.0
+
+// ThermalSourcesConfig selects the thermal sources (see computeblade_temperature metric) used by the agent.
+// The maximum temperature over the listed sources is used, defaults to the SoC temperature if empty.
+type ThermalSourcesConfig struct {
+	// FanController are the sources the fan speed is derived from
+	FanController []string `mapstructure:"fan_controller"`
+	// Critical are the sources compared against the critical temperature threshold
+	Critical []string `mapstructure:"critical"`
+}
+
+// warnUnknownThermalSources logs configured thermal sources which have not been discovered
+func (a *computeBladeAgentImpl) warnUnknownThermalSources(ctx context.Context) {
+	registry := a.blade.ThermalSources()
+	for _, names := range [][]string{a.opts.ThermalSources.FanController, a.opts.ThermalSources.Critical} {
+		for _, name := range names {
+			if _, ok := registry.Source(name); !ok {
+				log.FromContext(ctx).Warn("Configured thermal source not found",
+					zap.String("source", name),
+					zap.Strings("available", registry.Names()),
+				)
+			}
+		}
+	}
+}
+
+// checkCriticalTemperature enters critical mode when the threshold is reached and leaves it again
+// once the temperature dropped below the threshold minus the hysteresis
+func (a *computeBladeAgentImpl) checkCriticalTemperature(ctx context.Context) error {
+	threshold := float64(a.opts.CriticalTemperatureThreshold)
+	if threshold <= 0 {
+		return nil
+	}
+
+	temp, err := a.blade.ThermalSources().Temperature(ctx, a.opts.ThermalSources.Critical...)
+	if err != nil {
+		log.FromContext(ctx).Error("Failed to get critical temperature", zap.Error(err))
+		return nil
+	}
+
+	switch {
+	case !a.criticalByTemperature.Load() && !a.state.CriticalActive() && temp >= threshold:
+		log.FromContext(ctx).Warn("Critical temperature reached", zap.Float64("temperature", temp), zap.Float64("threshold", threshold))
+		a.criticalByTemperature.Store(true)
+		return a.emitEvent(ctx, CriticalEvent, EventSourceAgent)
+	case a.criticalByTemperature.Load() && temp < threshold-criticalTemperatureHysteresis:
+		a.criticalByTemperature.Store(false)
+		if !a.state.CriticalActive() {
+			// critical mode has already been reset manually
+			return nil
+		}
+		return a.emitEvent(ctx, CriticalResetEvent, EventSourceAgent)
+	}
+	return nil
+}
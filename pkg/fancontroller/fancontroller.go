@@ -25,6 +25,8 @@ type FanControllerStep struct {
 type FanControllerConfig struct {
 	// Steps defines the temperature/speed steps for the fan controller
 	Steps []FanControllerStep `mapstructure:"steps"`
+	// FanUnit configures the fan curve applied by the smart fan unit on its own
+	FanUnit FanUnitCurveConfig `mapstructure:"fan_unit"`
 }
 
 // FanController is a simple fan controller that reacts to temperature changes with a linear function
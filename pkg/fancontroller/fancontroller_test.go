@@ -2,9 +2,12 @@
 package fancontroller_test
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/fancontroller"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 )
 
 func TestFanControllerLinear_GetFanSpeed(t *testing.T) {
@@ -145,3 +148,92 @@ func TestFanControllerLinear_ConstructionErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestFanControllerConfig_FanUnitCurve(t *testing.T) {
+	t.Parallel()
+
+	config := fancontroller.FanControllerConfig{
+		Steps: []fancontroller.FanControllerStep{
+			{Temperature: 44.6, Percent: 40},
+			{Temperature: 55, Percent: 80},
+		},
+		FanUnit: fancontroller.FanUnitCurveConfig{
+			Enabled:         true,
+			Sensor:          "max",
+			WatchdogTimeout: 30 * time.Second,
+		},
+	}
+
+	// The steps of the fan controller are used without fan unit steps
+	curve, err := config.FanUnitCurve()
+	if err != nil {
+		t.Fatalf("Failed to convert fan curve: %v", err)
+	}
+	expected := smartfanunit.FanCurve{
+		Sensor:          smartfanunit.FanCurveSensorMax,
+		WatchdogTimeout: 30 * time.Second,
+		Steps:           []smartfanunit.FanCurveStep{{Temperature: 45, Percent: 40}, {Temperature: 55, Percent: 80}},
+	}
+	if !reflect.DeepEqual(curve, expected) {
+		t.Errorf("Expected fan curve %+v, but got %+v", expected, curve)
+	}
+
+	invalidConfigs := map[string]func(c *fancontroller.FanControllerConfig){
+		"unknown sensor":     func(c *fancontroller.FanControllerConfig) { c.FanUnit.Sensor = "soc" },
+		"missing watchdog":   func(c *fancontroller.FanControllerConfig) { c.FanUnit.WatchdogTimeout = 0 },
+		"watchdog too short": func(c *fancontroller.FanControllerConfig) { c.FanUnit.WatchdogTimeout = 5 * time.Second },
+		"negative temperature": func(c *fancontroller.FanControllerConfig) {
+			c.FanUnit.Steps = []fancontroller.FanControllerStep{{Temperature: -5}, {Temperature: 40}}
+		},
+	}
+	for name, modify := range invalidConfigs {
+		invalid := config
+		modify(&invalid)
+		if _, err := invalid.FanUnitCurve(); err == nil {
+			t.Errorf("Expected error for %s, but got nil", name)
+		}
+	}
+}
+
+func TestFanControllerConfig_FanUnitLUT(t *testing.T) {
+	t.Parallel()
+
+	config := fancontroller.FanControllerConfig{
+		Steps: []fancontroller.FanControllerStep{
+			{Temperature: 40, Percent: 40},
+			{Temperature: 55.4, Percent: 60},
+			{Temperature: 70, Percent: 100},
+		},
+		FanUnit: fancontroller.FanUnitCurveConfig{
+			LUT: fancontroller.FanUnitLUTConfig{Enabled: true, Hysteresis: 3},
+		},
+	}
+
+	// Each speed applies from the temperature of the step below, so the fan never runs slower than the curve
+	lut, err := config.FanUnitLUT()
+	if err != nil {
+		t.Fatalf("Failed to convert lookup table: %v", err)
+	}
+	expected := smartfanunit.FanLUT{
+		Hysteresis: 3,
+		Steps:      []smartfanunit.FanCurveStep{{Temperature: 0, Percent: 40}, {Temperature: 40, Percent: 60}, {Temperature: 55, Percent: 100}},
+	}
+	if !reflect.DeepEqual(lut, expected) {
+		t.Errorf("Expected lookup table %+v, but got %+v", expected, lut)
+	}
+
+	invalidConfigs := map[string]func(c *fancontroller.FanControllerConfig){
+		"no steps":            func(c *fancontroller.FanControllerConfig) { c.Steps = nil },
+		"hysteresis too high": func(c *fancontroller.FanControllerConfig) { c.FanUnit.LUT.Hysteresis = 32 },
+		"temperature too high": func(c *fancontroller.FanControllerConfig) {
+			c.FanUnit.Steps = []fancontroller.FanControllerStep{{Temperature: 130}, {Temperature: 140}}
+		},
+	}
+	for name, modify := range invalidConfigs {
+		invalid := config
+		modify(&invalid)
+		if _, err := invalid.FanUnitLUT(); err == nil {
+			t.Errorf("Expected error for %s, but got nil", name)
+		}
+	}
+}
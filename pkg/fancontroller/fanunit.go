@@ -0,0 +1,112 @@
+package fancontroller
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+)
+
+// FanUnitCurveConfig configures the fan curve of the smart fan unit.
+// The smart fan unit applies the curve based on its own temperature sensors if a blade stops sending fan speed
+// requests, e.g. because the agent crashed or the blade has been powered off.
+type FanUnitCurveConfig struct {
+	// Enabled pushes the fan curve to the smart fan unit
+	Enabled bool `mapstructure:"enabled"`
+	// Sensor is the temperature sensor of the fan unit the curve is based on: internal, external or max
+	Sensor string `mapstructure:"sensor"`
+	// WatchdogTimeout is the time without fan speed requests of a blade after which the fan unit applies the curve
+	WatchdogTimeout time.Duration `mapstructure:"watchdog_timeout"`
+	// Steps defines the temperature/speed steps of the curve, the steps of the fan controller if empty
+	Steps []FanControllerStep `mapstructure:"steps"`
+	// LUT programs the steps into the lookup table of the EMC2101
+	LUT FanUnitLUTConfig `mapstructure:"lut"`
+}
+
+// FanUnitLUTConfig configures the lookup table of the EMC2101 on the smart fan unit.
+// The EMC2101 drives the fan on its own based on the external diode, so the fan keeps following the temperature even
+// if the fan unit firmware hangs. While the lookup table is enabled, fan speed requests of the blades don't apply,
+// except for full speed requests in critical state.
+type FanUnitLUTConfig struct {
+	// Enabled pushes the lookup table to the smart fan unit
+	Enabled bool `mapstructure:"enabled"`
+	// Hysteresis in °C the temperature has to drop below a step before the speed of the step below applies
+	Hysteresis uint8 `mapstructure:"hysteresis"`
+}
+
+// FanUnitCurve returns the fan curve of the smart fan unit
+func (c FanControllerConfig) FanUnitCurve() (smartfanunit.FanCurve, error) {
+	curve := smartfanunit.FanCurve{
+		WatchdogTimeout: c.FanUnit.WatchdogTimeout,
+	}
+
+	switch c.FanUnit.Sensor {
+	case "internal":
+		curve.Sensor = smartfanunit.FanCurveSensorInternal
+	case "external", "":
+		curve.Sensor = smartfanunit.FanCurveSensorExternal
+	case "max":
+		curve.Sensor = smartfanunit.FanCurveSensorMax
+	default:
+		return smartfanunit.FanCurve{}, fmt.Errorf("unknown fan unit sensor %q", c.FanUnit.Sensor)
+	}
+
+	steps, err := c.fanUnitSteps()
+	if err != nil {
+		return smartfanunit.FanCurve{}, err
+	}
+	curve.Steps = steps
+
+	if err := curve.Validate(); err != nil {
+		return smartfanunit.FanCurve{}, fmt.Errorf("%w: 2 to %d steps with ascending temperatures, speed between 0 and 100 and a watchdog timeout between %s and 255s",
+			err, smartfanunit.MaxFanCurveSteps, smartfanunit.MinFanCurveWatchdogTimeout)
+	}
+	return curve, nil
+}
+
+// FanUnitLUT returns the lookup table of the EMC2101 on the smart fan unit.
+// The lookup table doesn't interpolate between steps, so the speed of each step applies from the temperature of the
+// step below on and the fan never runs slower than with the fan curve.
+func (c FanControllerConfig) FanUnitLUT() (smartfanunit.FanLUT, error) {
+	steps, err := c.fanUnitSteps()
+	if err != nil {
+		return smartfanunit.FanLUT{}, err
+	}
+
+	lut := smartfanunit.FanLUT{Hysteresis: c.FanUnit.LUT.Hysteresis}
+	for i, step := range steps {
+		if i > 0 {
+			step.Temperature = steps[i-1].Temperature
+		} else {
+			step.Temperature = 0
+		}
+		lut.Steps = append(lut.Steps, step)
+	}
+
+	if len(lut.Steps) == 0 || lut.Validate() != nil {
+		return smartfanunit.FanLUT{}, fmt.Errorf("%w: 1 to %d steps with ascending temperatures up to %d, speed between 0 and 100 and a hysteresis up to %d",
+			smartfanunit.ErrInvalidFanLUT, smartfanunit.MaxFanLUTSteps, smartfanunit.MaxFanLUTTemperature, smartfanunit.MaxFanLUTHysteresis)
+	}
+	return lut, nil
+}
+
+// fanUnitSteps returns the steps of the smart fan unit, the steps of the fan controller without fan unit steps
+func (c FanControllerConfig) fanUnitSteps() ([]smartfanunit.FanCurveStep, error) {
+	steps := c.FanUnit.Steps
+	if len(steps) == 0 {
+		steps = c.Steps
+	}
+
+	var fanUnitSteps []smartfanunit.FanCurveStep
+	for _, step := range steps {
+		if step.Temperature < 0 || step.Temperature > math.MaxUint8 {
+			return nil, fmt.Errorf("fan unit temperature must be between 0 and %d", math.MaxUint8)
+		}
+		fanUnitSteps = append(fanUnitSteps, smartfanunit.FanCurveStep{
+			Temperature: uint8(math.Round(step.Temperature)),
+			Percent:     step.Percent,
+		})
+	}
+	return fanUnitSteps, nil
+}
@@ -0,0 +1,17 @@
+package hal
+
+import "bytes"
+
+// ParseDeviceTreeCompatible returns the compute module based on the SoC listed in the device tree compatible property
+// (a list of NUL-terminated strings, e.g. "raspberrypi,5-compute-module\x00brcm,bcm2712\x00")
+func ParseDeviceTreeCompatible(compatible []byte) ComputeModule {
+	for _, entry := range bytes.Split(compatible, []byte{0}) {
+		switch string(bytes.TrimSpace(entry)) {
+		case "brcm,bcm2711":
+			return ComputeModuleCm4
+		case "brcm,bcm2712":
+			return ComputeModuleCm5
+		}
+	}
+	return ComputeModuleUnknown
+}
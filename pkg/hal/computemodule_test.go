@@ -0,0 +1,17 @@
+package hal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal"
+)
+
+func TestParseDeviceTreeCompatible(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, hal.ComputeModuleCm4, hal.ParseDeviceTreeCompatible([]byte("raspberrypi,4-compute-module\x00brcm,bcm2711\x00")))
+	assert.Equal(t, hal.ComputeModuleCm5, hal.ParseDeviceTreeCompatible([]byte("raspberrypi,5-compute-module\x00brcm,bcm2712\x00")))
+	assert.Equal(t, hal.ComputeModuleUnknown, hal.ParseDeviceTreeCompatible([]byte("raspberrypi,3-model-b\x00brcm,bcm2837\x00")))
+	assert.Equal(t, "cm5", hal.ComputeModuleCm5.String())
+}
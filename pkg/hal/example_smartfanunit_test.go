@@ -36,11 +36,11 @@ func ExampleNewSmartFanUnit() {
 		panic(err)
 	}
 
-	tmp, err := client.AirFlowTemperature(ctx)
+	temps, err := client.Temperatures(ctx)
 	if err != nil {
 		panic(err)
 	}
-	log.Println("AirflowTemp", tmp)
+	log.Println("Temperatures", temps)
 	rpm, err := client.FanSpeedRPM(ctx)
 	if err != nil {
 		panic(err)
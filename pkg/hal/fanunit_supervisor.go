@@ -0,0 +1,432 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"go.uber.org/zap"
+)
+
+const (
+	// fanUnitProbeInterval is the interval in which the smart fan unit is probed while the standard fan unit is used
+	fanUnitProbeInterval = 30 * time.Second
+	// fanUnitProbeTimeout is the time to wait for data of a smart fan unit, notifications are sent every 2 seconds
+	fanUnitProbeTimeout = 3 * time.Second
+	// fanUnitTelemetryTimeout is the time without any packet after which the smart fan unit is considered lost
+	fanUnitTelemetryTimeout = 10 * time.Second
+)
+
+// linkStatusReporter is implemented by fan units connected through a serial link, e.g. the smart fan unit
+type linkStatusReporter interface {
+	// LinkStatus returns the health of the link
+	LinkStatus() FanUnitLinkStatus
+}
+
+// infoReporter is implemented by fan units reporting their firmware info, e.g. the smart fan unit
+type infoReporter interface {
+	// Info returns the firmware info
+	Info() FanUnitInfo
+}
+
+// telemetryReporter is implemented by fan units reporting telemetry, e.g. the smart fan unit
+type telemetryReporter interface {
+	// LastTelemetry returns the time the last packet has been received
+	LastTelemetry() time.Time
+}
+
+// tachReporter is implemented by fan units measuring the fan speed with a tach signal, e.g. the standard fan unit
+type tachReporter interface {
+	// FanSpinning returns true if the tach signal is the one of a spinning fan
+	FanSpinning() bool
+}
+
+// fanCurveSetter is implemented by fan units applying a fan curve on their own, e.g. the smart fan unit
+type fanCurveSetter interface {
+	// SetFanCurve configures the fan curve
+	SetFanCurve(ctx context.Context, curve smartfanunit.FanCurve) error
+}
+
+// fanLUTSetter is implemented by fan units with a hardware lookup table, e.g. the smart fan unit
+type fanLUTSetter interface {
+	// SetFanLUT configures the lookup table
+	SetFanLUT(ctx context.Context, lut smartfanunit.FanLUT) error
+}
+
+// fanUnitFactory creates fan units on behalf of the fanUnitSupervisor
+type fanUnitFactory struct {
+	// ProbeSmart returns true if a smart fan unit is connected
+	ProbeSmart func(ctx context.Context) (bool, error)
+	// NewSmart connects to the smart fan unit
+	NewSmart func() (FanUnit, error)
+	// NewStandard sets up the standard fan unit
+	NewStandard func() (FanUnit, error)
+}
+
+// fanUnitSupervisor is a FanUnit delegating to the currently connected fan unit.
+// While the standard fan unit is used, the smart fan unit is probed periodically. Once the smart fan unit stops
+// sending telemetry, it falls back to the standard fan unit. The last fan speed and LED color are restored
+// whenever the fan unit changes, as are the fan curve and lookup table of the smart fan unit.
+type fanUnitSupervisor struct {
+	factory fanUnitFactory
+
+	probeInterval    time.Duration
+	probeTimeout     time.Duration
+	telemetryTimeout time.Duration
+
+	mu         sync.Mutex
+	current    FanUnit
+	speed      *uint8
+	ledColor   *led.Color
+	fanCurve   *smartfanunit.FanCurve
+	fanLUT     *smartfanunit.FanLUT
+	changeChan chan struct{}
+	// smartLost is set once the smart fan unit has been lost, until a smart fan unit is connected again
+	smartLost bool
+}
+
+func newFanUnitSupervisor(initial FanUnit, factory fanUnitFactory) *fanUnitSupervisor {
+	return &fanUnitSupervisor{
+		factory:          factory,
+		probeInterval:    fanUnitProbeInterval,
+		probeTimeout:     fanUnitProbeTimeout,
+		telemetryTimeout: fanUnitTelemetryTimeout,
+		current:          initial,
+		changeChan:       make(chan struct{}),
+	}
+}
+
+// unit returns the current fan unit and a channel closed once it has been replaced
+func (s *fanUnitSupervisor) unit() (FanUnit, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current, s.changeChan
+}
+
+func (s *fanUnitSupervisor) Kind() FanUnitKind {
+	fu, _ := s.unit()
+	return fu.Kind()
+}
+
+// Run runs the current fan unit and replaces it if the connected fan unit changes
+func (s *fanUnitSupervisor) Run(ctx context.Context) error {
+	for {
+		fu, _ := s.unit()
+		setFanUnitMetric(fu.Kind())
+		setFanUnitLinkMetric(s.LinkStatus())
+		if fu.Kind() != FanUnitKindSmart {
+			smartFanUnitInfo.Reset()
+			smartFanUnitBladeAlive.Reset()
+			smartFanUnitBusRecoveries.Reset()
+			fanUnitTemperature.Reset()
+		}
+
+		unitCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		var runErr error
+		go func() {
+			defer close(done)
+			runErr = fu.Run(unitCtx)
+		}()
+
+		probe := s.watch(ctx, fu, done)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if fu.Kind() == FanUnitKindSmart {
+			// Reads from the serial port don't return on cancellation, closing the port unblocks them
+			closeErr := fu.Close()
+			<-done
+			log.FromContext(ctx).Warn("Lost smart fan unit, falling back to standard fan unit", zap.Error(errors.Join(runErr, closeErr)))
+			if err := s.replace(ctx, s.factory.NewStandard); err != nil {
+				return err
+			}
+			continue
+		}
+
+		<-done
+		if !probe {
+			// Standard fan unit failed, there is nothing to fall back to
+			return runErr
+		}
+
+		probeCtx, cancelProbe := context.WithTimeout(ctx, s.probeTimeout)
+		present, err := s.factory.ProbeSmart(probeCtx)
+		cancelProbe()
+		if err != nil || !present {
+			continue
+		}
+		log.FromContext(ctx).Info("Detected smart fan unit")
+		if err := s.replace(ctx, s.factory.NewSmart); err != nil {
+			log.FromContext(ctx).Error("Failed to connect to smart fan unit", zap.Error(err))
+			continue
+		}
+		if err := fu.Close(); err != nil {
+			log.FromContext(ctx).Warn("Failed to close standard fan unit", zap.Error(err))
+		}
+	}
+}
+
+// watch blocks until the fan unit stopped or has to be replaced.
+// Returns true if the fan unit has been stopped to probe the smart fan unit.
+func (s *fanUnitSupervisor) watch(ctx context.Context, fu FanUnit, done <-chan struct{}) bool {
+	interval := s.probeInterval
+	if fu.Kind() == FanUnitKindSmart {
+		interval = s.telemetryTimeout / 4
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-done:
+			return false
+		case <-ticker.C:
+		}
+
+		if fu.Kind() == FanUnitKindSmart {
+			if reporter, ok := fu.(telemetryReporter); ok && time.Since(reporter.LastTelemetry()) > s.telemetryTimeout {
+				return false
+			}
+			continue
+		}
+
+		// Without RPM reporting or with the fan turned off, a stopped fan doesn't hint at a missing standard fan unit
+		if fu.Kind() == FanUnitKindStandardNoRPM || !s.fanSpeedSet() {
+			continue
+		}
+
+		// A spinning fan means the standard fan unit is connected. The smart fan unit uses the tach pin for its UART,
+		// so its packets produce edges as well and the tach signal has to be checked for the pattern of a fan.
+		if reporter, ok := fu.(tachReporter); ok {
+			if reporter.FanSpinning() {
+				continue
+			}
+		} else if rpm, err := fu.FanSpeedRPM(ctx); err == nil && rpm > 0 {
+			continue
+		}
+		return true
+	}
+}
+
+// fanSpeedSet returns true if a non-zero fan speed has been set
+func (s *fanUnitSupervisor) fanSpeedSet() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed != nil && *s.speed > 0
+}
+
+// replace switches to a new fan unit and restores the fan speed and LED color
+func (s *fanUnitSupervisor) replace(ctx context.Context, newFanUnit func() (FanUnit, error)) error {
+	next, err := newFanUnit()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.current.Kind() == FanUnitKindSmart || next.Kind() == FanUnitKindSmart {
+		s.smartLost = next.Kind() != FanUnitKindSmart
+	}
+	s.current = next
+	speed, ledColor, fanCurve, fanLUT := s.speed, s.ledColor, s.fanCurve, s.fanLUT
+	close(s.changeChan)
+	s.changeChan = make(chan struct{})
+	s.mu.Unlock()
+
+	if speed != nil {
+		err = errors.Join(err, next.SetFanSpeedPercent(ctx, *speed))
+	}
+	if ledColor != nil {
+		err = errors.Join(err, next.SetLed(ctx, *ledColor))
+	}
+	if setter, ok := next.(fanCurveSetter); ok && fanCurve != nil {
+		err = errors.Join(err, setter.SetFanCurve(ctx, *fanCurve))
+	}
+	if setter, ok := next.(fanLUTSetter); ok && fanLUT != nil {
+		err = errors.Join(err, setter.SetFanLUT(ctx, *fanLUT))
+	}
+	if err != nil {
+		log.FromContext(ctx).Error("Failed to restore fan unit settings", zap.Error(err))
+	}
+	log.FromContext(ctx).Info("Fan unit changed", zap.String("kind", next.Kind().String()))
+	return nil
+}
+
+// LinkStatus returns the health of the link to the smart fan unit.
+// After falling back to the standard fan unit, the link is reported as lost.
+func (s *fanUnitSupervisor) LinkStatus() FanUnitLinkStatus {
+	s.mu.Lock()
+	fu, smartLost := s.current, s.smartLost
+	s.mu.Unlock()
+
+	if reporter, ok := fu.(linkStatusReporter); ok {
+		return reporter.LinkStatus()
+	}
+	if smartLost {
+		return FanUnitLinkLost
+	}
+	return FanUnitLinkNone
+}
+
+// Info returns the firmware info of the current fan unit, the zero value if it doesn't report any
+func (s *fanUnitSupervisor) Info() FanUnitInfo {
+	fu, _ := s.unit()
+	if reporter, ok := fu.(infoReporter); ok {
+		return reporter.Info()
+	}
+	return FanUnitInfo{}
+}
+
+// WaitForChange blocks until the fan unit changes and returns the kind of the new fan unit
+func (s *fanUnitSupervisor) WaitForChange(ctx context.Context) (FanUnitKind, error) {
+	_, changeChan := s.unit()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-changeChan:
+		return s.Kind(), nil
+	}
+}
+
+func (s *fanUnitSupervisor) SetFanSpeedPercent(ctx context.Context, percent uint8) error {
+	s.mu.Lock()
+	s.speed = &percent
+	fu := s.current
+	s.mu.Unlock()
+	return fu.SetFanSpeedPercent(ctx, percent)
+}
+
+func (s *fanUnitSupervisor) SetLed(ctx context.Context, color led.Color) error {
+	s.mu.Lock()
+	s.ledColor = &color
+	fu := s.current
+	s.mu.Unlock()
+	return fu.SetLed(ctx, color)
+}
+
+// SetFanCurve configures the fan curve of the current fan unit, if it applies one on its own
+func (s *fanUnitSupervisor) SetFanCurve(ctx context.Context, curve smartfanunit.FanCurve) error {
+	s.mu.Lock()
+	s.fanCurve = &curve
+	fu := s.current
+	s.mu.Unlock()
+	if setter, ok := fu.(fanCurveSetter); ok {
+		return setter.SetFanCurve(ctx, curve)
+	}
+	return nil
+}
+
+// SetFanLUT configures the lookup table of the current fan unit, if it has one
+func (s *fanUnitSupervisor) SetFanLUT(ctx context.Context, lut smartfanunit.FanLUT) error {
+	s.mu.Lock()
+	s.fanLUT = &lut
+	fu := s.current
+	s.mu.Unlock()
+	if setter, ok := fu.(fanLUTSetter); ok {
+		return setter.SetFanLUT(ctx, lut)
+	}
+	return nil
+}
+
+func (s *fanUnitSupervisor) FanSpeedRPM(ctx context.Context) (float64, error) {
+	fu, _ := s.unit()
+	return fu.FanSpeedRPM(ctx)
+}
+
+// WaitForButtonPress blocks until the button of the current fan unit is pressed, following fan unit changes
+func (s *fanUnitSupervisor) WaitForButtonPress(ctx context.Context) error {
+	for {
+		fu, changeChan := s.unit()
+		waitCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changeChan:
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+		err := fu.WaitForButtonPress(waitCtx)
+		cancel()
+
+		select {
+		case <-changeChan:
+			if ctx.Err() == nil {
+				continue
+			}
+		default:
+		}
+		return err
+	}
+}
+
+func (s *fanUnitSupervisor) Temperatures(ctx context.Context) (map[string]float32, error) {
+	fu, _ := s.unit()
+	return fu.Temperatures(ctx)
+}
+
+func (s *fanUnitSupervisor) Close() error {
+	fu, _ := s.unit()
+	return fu.Close()
+}
+
+// setFanUnitMetric marks the given fan unit as present
+func setFanUnitMetric(kind FanUnitKind) {
+	fanUnit.WithLabelValues("standard").Set(0)
+	fanUnit.WithLabelValues("smart").Set(0)
+	if kind == FanUnitKindSmart {
+		fanUnit.WithLabelValues("smart").Set(1)
+	} else {
+		fanUnit.WithLabelValues("standard").Set(1)
+	}
+}
+
+// setFanUnitInfoMetric exposes the firmware info of the smart fan unit
+func setFanUnitInfoMetric(info FanUnitInfo) {
+	smartFanUnitInfo.Reset()
+	smartFanUnitInfo.WithLabelValues(
+		strconv.Itoa(int(info.ProtocolVersion)),
+		info.FirmwareVersion,
+		strings.Join(info.Capabilities.Names(), ","),
+	).Set(1)
+}
+
+// setFanUnitBladeAliveMetric exposes the blades reported alive by the smart fan unit
+func setFanUnitBladeAliveMetric(status smartfanunit.BladeStatus) {
+	smartFanUnitBladeAlive.Reset()
+	for _, side := range []smartfanunit.BladeSide{smartfanunit.BladeSideLeft, smartfanunit.BladeSideRight} {
+		value := 0.0
+		if status.Alive(side) {
+			value = 1
+		}
+		smartFanUnitBladeAlive.WithLabelValues(side.String(), strconv.FormatBool(side == status.Side)).Set(value)
+	}
+}
+
+// setFanUnitBusRecoveryMetric exposes the I2C bus recoveries reported by the smart fan unit
+func setFanUnitBusRecoveryMetric(stats smartfanunit.BusRecoveryStats) {
+	smartFanUnitBusRecoveries.WithLabelValues("recovered").Set(float64(stats.Recoveries))
+	smartFanUnitBusRecoveries.WithLabelValues("failed_attempt").Set(float64(stats.FailedAttempts))
+}
+
+// setFanUnitLinkMetric marks the given link status as active
+func setFanUnitLinkMetric(status FanUnitLinkStatus) {
+	for _, s := range []FanUnitLinkStatus{FanUnitLinkNone, FanUnitLinkOk, FanUnitLinkDegraded, FanUnitLinkLost} {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		smartFanUnitLinkStatus.WithLabelValues(s.String()).Set(value)
+	}
+}
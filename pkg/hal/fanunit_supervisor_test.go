@@ -0,0 +1,406 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+)
+
+// fakeFanUnit is an in-memory fan unit recording the last settings
+type fakeFanUnit struct {
+	kind FanUnitKind
+
+	mu            sync.Mutex
+	speed         *uint8
+	ledColor      *led.Color
+	fanCurve      *smartfanunit.FanCurve
+	fanLUT        *smartfanunit.FanLUT
+	rpm           float64
+	lastTelemetry time.Time
+	closed        chan struct{}
+	closeOnce     sync.Once
+}
+
+func newFakeFanUnit(kind FanUnitKind) *fakeFanUnit {
+	return &fakeFanUnit{kind: kind, lastTelemetry: time.Now(), closed: make(chan struct{})}
+}
+
+func (f *fakeFanUnit) Kind() FanUnitKind {
+	return f.kind
+}
+
+func (f *fakeFanUnit) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.closed:
+		return nil
+	}
+}
+
+func (f *fakeFanUnit) SetFanSpeedPercent(_ context.Context, percent uint8) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.speed = &percent
+	return nil
+}
+
+func (f *fakeFanUnit) SetLed(_ context.Context, color led.Color) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ledColor = &color
+	return nil
+}
+
+func (f *fakeFanUnit) SetFanCurve(_ context.Context, curve smartfanunit.FanCurve) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fanCurve = &curve
+	return nil
+}
+
+func (f *fakeFanUnit) SetFanLUT(_ context.Context, lut smartfanunit.FanLUT) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fanLUT = &lut
+	return nil
+}
+
+func (f *fakeFanUnit) FanSpeedRPM(_ context.Context) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rpm, nil
+}
+
+func (f *fakeFanUnit) WaitForButtonPress(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fakeFanUnit) Temperatures(_ context.Context) (map[string]float32, error) {
+	return nil, ErrSensorNotAvailable
+}
+
+func (f *fakeFanUnit) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return nil
+}
+
+func (f *fakeFanUnit) LastTelemetry() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastTelemetry
+}
+
+func (f *fakeFanUnit) setRPM(rpm float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rpm = rpm
+}
+
+func (f *fakeFanUnit) setLastTelemetry(ts time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastTelemetry = ts
+}
+
+func (f *fakeFanUnit) settings() (*uint8, *led.Color) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.speed, f.ledColor
+}
+
+func (f *fakeFanUnit) isClosed() bool {
+	select {
+	case <-f.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// tachFanUnit is a fake standard fan unit measuring the fan speed with a tachometer
+type tachFanUnit struct {
+	*fakeFanUnit
+	tach tachometer
+}
+
+func (f *tachFanUnit) FanSpeedRPM(_ context.Context) (float64, error) {
+	return f.tach.rpm(time.Now()), nil
+}
+
+func (f *tachFanUnit) FanSpinning() bool {
+	return f.tach.spinning(time.Now())
+}
+
+func newTestFanUnitSupervisor(initial FanUnit, factory fanUnitFactory) *fanUnitSupervisor {
+	s := newFanUnitSupervisor(initial, factory)
+	s.probeInterval = 10 * time.Millisecond
+	s.probeTimeout = 10 * time.Millisecond
+	s.telemetryTimeout = 40 * time.Millisecond
+	return s
+}
+
+func TestFanUnitSupervisor_SmartFallback(t *testing.T) {
+	t.Parallel()
+
+	smart := newFakeFanUnit(FanUnitKindSmart)
+	smart.setLastTelemetry(time.Now().Add(time.Hour))
+	standard := newFakeFanUnit(FanUnitKindStandard)
+	s := newTestFanUnitSupervisor(smart, fanUnitFactory{
+		ProbeSmart:  func(context.Context) (bool, error) { return false, nil },
+		NewSmart:    func() (FanUnit, error) { return smart, nil },
+		NewStandard: func() (FanUnit, error) { return standard, nil },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, s.SetFanSpeedPercent(ctx, 42))
+	require.NoError(t, s.SetLed(ctx, led.Color{Red: 1}))
+
+	go s.Run(ctx) //nolint:errcheck
+
+	// Telemetry is received, the smart fan unit is kept
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, FanUnitKind(FanUnitKindSmart), s.Kind())
+
+	// Telemetry stops, the supervisor falls back to the standard fan unit
+	_, changeChan := s.unit()
+	smart.setLastTelemetry(time.Now().Add(-time.Second))
+	select {
+	case <-changeChan:
+		assert.Equal(t, FanUnitKind(FanUnitKindStandard), s.Kind())
+	case <-time.After(time.Second):
+		t.Fatal("fan unit didn't change")
+	}
+	assert.True(t, smart.isClosed())
+	assert.Equal(t, FanUnitLinkLost, s.LinkStatus())
+
+	speed, ledColor := standard.settings()
+	require.NotNil(t, speed)
+	require.NotNil(t, ledColor)
+	assert.Equal(t, uint8(42), *speed)
+	assert.Equal(t, led.Color{Red: 1}, *ledColor)
+}
+
+func TestFanUnitSupervisor_ProbeSmart(t *testing.T) {
+	t.Parallel()
+
+	smart := newFakeFanUnit(FanUnitKindSmart)
+	standard := newFakeFanUnit(FanUnitKindStandard)
+	standard.setRPM(1200)
+
+	var probes atomic.Int32
+	var present atomic.Bool
+	s := newTestFanUnitSupervisor(standard, fanUnitFactory{
+		ProbeSmart: func(context.Context) (bool, error) {
+			probes.Add(1)
+			return present.Load(), nil
+		},
+		NewSmart:    func() (FanUnit, error) { return smart, nil },
+		NewStandard: func() (FanUnit, error) { return standard, nil },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	curve := smartfanunit.FanCurve{WatchdogTimeout: 10 * time.Second, Steps: []smartfanunit.FanCurveStep{{Temperature: 30, Percent: 20}, {Temperature: 50, Percent: 100}}}
+	require.NoError(t, s.SetFanSpeedPercent(ctx, 60))
+	require.NoError(t, s.SetFanCurve(ctx, curve))
+	lut := smartfanunit.FanLUT{Hysteresis: 4, Steps: []smartfanunit.FanCurveStep{{Temperature: 0, Percent: 40}}}
+	require.NoError(t, s.SetFanLUT(ctx, lut))
+	go s.Run(ctx) //nolint:errcheck
+
+	// The fan is spinning, so the standard fan unit is connected and nothing is probed
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, probes.Load())
+
+	// Without any fan spinning, the smart fan unit is probed but not present
+	standard.setRPM(0)
+	assert.Eventually(t, func() bool { return probes.Load() > 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, FanUnitKind(FanUnitKindStandard), s.Kind())
+
+	// Once the smart fan unit responds, it replaces the standard fan unit
+	_, changeChan := s.unit()
+	present.Store(true)
+	select {
+	case <-changeChan:
+		assert.Equal(t, FanUnitKind(FanUnitKindSmart), s.Kind())
+	case <-time.After(time.Second):
+		t.Fatal("fan unit didn't change")
+	}
+	assert.Eventually(t, standard.isClosed, time.Second, 5*time.Millisecond)
+
+	speed, _ := smart.settings()
+	require.NotNil(t, speed)
+	assert.Equal(t, uint8(60), *speed)
+	smart.mu.Lock()
+	defer smart.mu.Unlock()
+	require.NotNil(t, smart.fanCurve)
+	assert.Equal(t, curve, *smart.fanCurve)
+	require.NotNil(t, smart.fanLUT)
+	assert.Equal(t, lut, *smart.fanLUT)
+}
+
+func TestFanUnitSupervisor_ProbeSmartOnlyWhileFanIsSet(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		kind     FanUnitKind
+		setSpeed bool
+		speed    uint8
+	}{
+		{name: "speed not set", kind: FanUnitKindStandard},
+		{name: "fan turned off", kind: FanUnitKindStandard, setSpeed: true, speed: 0},
+		{name: "rpm reporting disabled", kind: FanUnitKindStandardNoRPM, setSpeed: true, speed: 60},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var probes atomic.Int32
+			standard := newFakeFanUnit(tc.kind)
+			s := newTestFanUnitSupervisor(standard, fanUnitFactory{
+				ProbeSmart: func(context.Context) (bool, error) {
+					probes.Add(1)
+					return false, nil
+				},
+				NewStandard: func() (FanUnit, error) { return standard, nil },
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.setSpeed {
+				require.NoError(t, s.SetFanSpeedPercent(ctx, tc.speed))
+			}
+			go s.Run(ctx) //nolint:errcheck
+
+			// The fan isn't spinning, but that doesn't mean the standard fan unit has been removed
+			time.Sleep(50 * time.Millisecond)
+			assert.Zero(t, probes.Load())
+			assert.False(t, standard.isClosed())
+		})
+	}
+}
+
+func TestFanUnitSupervisor_ProbeSmartOnUartBursts(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		edges func(tach *tachometer, now time.Time)
+		probe bool
+	}{
+		{
+			name: "fan spinning",
+			edges: func(tach *tachometer, now time.Time) {
+				for i := 0; i <= 100; i++ {
+					tach.addEdge(time.Duration(i)*10*time.Millisecond, now)
+				}
+			},
+		},
+		{
+			// The smart fan unit sends a packet every 2 seconds on the tach pin
+			name: "uart bursts",
+			edges: func(tach *tachometer, now time.Time) {
+				for i := 0; i < 5; i++ {
+					addUartBurst(tach, time.Duration(i)*2*time.Second, 16, now)
+				}
+			},
+			probe: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var probes atomic.Int32
+			standard := &tachFanUnit{fakeFanUnit: newFakeFanUnit(FanUnitKindStandard)}
+			tc.edges(&standard.tach, time.Now())
+			s := newTestFanUnitSupervisor(standard, fanUnitFactory{
+				ProbeSmart: func(context.Context) (bool, error) {
+					probes.Add(1)
+					return false, nil
+				},
+				NewStandard: func() (FanUnit, error) { return standard, nil },
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, s.SetFanSpeedPercent(ctx, 60))
+			go s.Run(ctx) //nolint:errcheck
+
+			if tc.probe {
+				assert.Eventually(t, func() bool { return probes.Load() > 0 }, time.Second, 5*time.Millisecond)
+			} else {
+				time.Sleep(50 * time.Millisecond)
+				assert.Zero(t, probes.Load())
+			}
+		})
+	}
+}
+
+func TestFanUnitSupervisor_WaitForChange(t *testing.T) {
+	t.Parallel()
+
+	smart := newFakeFanUnit(FanUnitKindSmart)
+	standard := newFakeFanUnit(FanUnitKindStandard)
+	s := newTestFanUnitSupervisor(smart, fanUnitFactory{
+		NewStandard: func() (FanUnit, error) { return standard, nil },
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, changeChan := s.unit()
+	go func() {
+		<-time.After(10 * time.Millisecond)
+		assert.NoError(t, s.replace(ctx, s.factory.NewStandard))
+	}()
+	kind, err := s.WaitForChange(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, FanUnitKind(FanUnitKindStandard), kind)
+	<-changeChan
+
+	// No further change, waiting times out
+	waitCtx, cancelWait := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelWait()
+	_, err = s.WaitForChange(waitCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFanUnitSupervisor_WaitForButtonPress(t *testing.T) {
+	t.Parallel()
+
+	smart := newFakeFanUnit(FanUnitKindSmart)
+	standard := newFakeFanUnit(FanUnitKindStandard)
+	s := newTestFanUnitSupervisor(smart, fanUnitFactory{
+		NewStandard: func() (FanUnit, error) { return standard, nil },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.WaitForButtonPress(ctx) }()
+
+	// Replacing the fan unit doesn't abort waiting for a button press
+	require.NoError(t, s.replace(ctx, s.factory.NewStandard))
+	select {
+	case <-done:
+		t.Fatal("WaitForButtonPress returned on fan unit change")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,51 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"github.com/warthog618/gpiod"
+)
+
+// gpioLine is the subset of a requested GPIO line used by the HAL
+type gpioLine interface {
+	Value() (int, error)
+	SetValue(value int) error
+	Close() error
+}
+
+// gpioChip requests GPIO lines, it's implemented by gpiod and can be replaced in tests
+type gpioChip interface {
+	RequestLine(offset int, options ...gpiod.LineReqOption) (gpioLine, error)
+	Close() error
+}
+
+// gpiodChip adapts a gpiod.Chip to the gpioChip interface
+type gpiodChip struct {
+	chip *gpiod.Chip
+}
+
+func newGpiodChip(chip *gpiod.Chip) *gpiodChip {
+	return &gpiodChip{chip: chip}
+}
+
+// openGpioChip opens the GPIO chip with the given name (e.g. gpiochip0)
+func openGpioChip(name string) (gpioChip, error) {
+	chip, err := gpiod.NewChip(name)
+	if err != nil {
+		return nil, err
+	}
+	return newGpiodChip(chip), nil
+}
+
+func (c *gpiodChip) RequestLine(offset int, options ...gpiod.LineReqOption) (gpioLine, error) {
+	line, err := c.chip.RequestLine(offset, options...)
+	if err != nil {
+		// Avoid returning a non-nil interface holding a nil line
+		return nil, err
+	}
+	return line, nil
+}
+
+func (c *gpiodChip) Close() error {
+	return c.chip.Close()
+}
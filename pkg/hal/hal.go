@@ -2,11 +2,14 @@ package hal
 
 import (
 	"context"
+	"errors"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 )
 
 type FanUnitKind uint8
+type FanUnitLinkStatus uint8
 type ComputeModule uint8
 type PowerStatus uint8
 
@@ -27,6 +30,76 @@ const (
 	FanUnitKindSmart
 )
 
+func (k FanUnitKind) String() string {
+	switch k {
+	case FanUnitKindStandard:
+		return "standard"
+	case FanUnitKindStandardNoRPM:
+		return "standard_no_rpm"
+	case FanUnitKindSmart:
+		return "smart"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// FanUnitLinkNone means there is no link to the fan unit, e.g. the standard fan unit is used
+	FanUnitLinkNone FanUnitLinkStatus = iota
+	// FanUnitLinkOk means the smart fan unit sends telemetry without protocol errors
+	FanUnitLinkOk
+	// FanUnitLinkDegraded means telemetry of the smart fan unit is delayed or protocol errors occurred recently
+	FanUnitLinkDegraded
+	// FanUnitLinkLost means the smart fan unit stopped sending telemetry
+	FanUnitLinkLost
+)
+
+func (s FanUnitLinkStatus) String() string {
+	switch s {
+	case FanUnitLinkNone:
+		return "none"
+	case FanUnitLinkOk:
+		return "ok"
+	case FanUnitLinkDegraded:
+		return "degraded"
+	case FanUnitLinkLost:
+		return "lost"
+	default:
+		return "unknown"
+	}
+}
+
+// FanUnitInfo describes the firmware of the smart fan unit
+type FanUnitInfo struct {
+	// ProtocolVersion of the firmware, 0 if it doesn't support the version handshake
+	ProtocolVersion uint8
+	// FirmwareVersion of the firmware, empty if unknown
+	FirmwareVersion string
+	// Capabilities are the optional protocol features supported by the firmware
+	Capabilities smartfanunit.Capabilities
+	// BladeStatus is the status of the blades reported in response to heartbeats, nil until reported
+	BladeStatus *smartfanunit.BladeStatus
+	// BusRecovery counts the recoveries of the I2C bus to the EMC2101 reported by the fan unit
+	BusRecovery smartfanunit.BusRecoveryStats
+}
+
+const (
+	ComputeModuleUnknown ComputeModule = iota
+	ComputeModuleCm4
+	ComputeModuleCm5
+)
+
+func (m ComputeModule) String() string {
+	switch m {
+	case ComputeModuleCm4:
+		return "cm4"
+	case ComputeModuleCm5:
+		return "cm5"
+	default:
+		return "unknown"
+	}
+}
+
 const (
 	PowerPoeOrUsbC = iota
 	PowerPoe802at
@@ -37,8 +110,67 @@ const (
 	LedEdge
 )
 
+// Temperature sensors of the smart fan unit
+var (
+	// FanUnitSensorInternal is the internal temperature sensor of the EMC2101, measuring the inlet air
+	FanUnitSensorInternal = smartfanunit.TemperatureSensorInternal.String()
+	// FanUnitSensorExternal is the external temperature diode of the EMC2101, measuring the outlet air
+	FanUnitSensorExternal = smartfanunit.TemperatureSensorExternal.String()
+)
+
+// FanUnitSensorAirFlow is the only sensor of smart fan units reporting a single air flow temperature,
+// the internal sensor for the left blade and the external sensor for the right blade
+const FanUnitSensorAirFlow = "airflow"
+
+// ErrSensorNotAvailable is returned when a sensor is not present on the hardware (e.g. the standard fan unit)
+var ErrSensorNotAvailable = errors.New("sensor not available")
+
+// HalBackend selects how the fan PWM and the WS281x LEDs are driven
+type HalBackend string
+
+const (
+	// HalBackendDevmem accesses the PWM and GPIO registers directly through /dev/mem (default)
+	HalBackendDevmem HalBackend = "devmem"
+	// HalBackendKernel uses the kernel PWM subsystem (/sys/class/pwm) and SPI (/dev/spidev*) instead of /dev/mem
+	HalBackendKernel HalBackend = "kernel"
+)
+
+// DefaultFanPwmFrequency is the PWM frequency of the standard fan unit in Hz, as expected by Noctua fans
+const DefaultFanPwmFrequency = 25000
+
 type ComputeBladeHalOpts struct {
 	RpmReportingStandardFanUnit bool `mapstructure:"rpm_reporting_standard_fan_unit"`
+	// SysfsRoot is the root of the sysfs used to discover thermal sources and PWM chips (default /sys)
+	SysfsRoot string `mapstructure:"sysfs_root"`
+	// Backend selects how the fan PWM and the LEDs are driven (devmem or kernel)
+	Backend HalBackend `mapstructure:"backend"`
+	// Kernel configures the kernel backend
+	Kernel KernelBackendOpts `mapstructure:"kernel"`
+	// FanPwmFrequency is the PWM frequency of the standard fan unit in Hz (default 25khz)
+	FanPwmFrequency uint `mapstructure:"fan_pwm_frequency"`
+	// FanMinDutyPercent is the minimum duty cycle of the standard fan unit for fans that stall at low duty cycles.
+	// Fan speeds above 0% are raised to at least this value, 0% still turns the fan off.
+	FanMinDutyPercent uint8 `mapstructure:"fan_min_duty_percent"`
+	// FanPulsesPerRevolution is the number of tach pulses per revolution of the standard fan unit (default 2)
+	FanPulsesPerRevolution uint `mapstructure:"fan_pulses_per_revolution"`
+}
+
+// fanPwmFrequency returns the configured PWM frequency of the standard fan unit
+func (opts ComputeBladeHalOpts) fanPwmFrequency() uint {
+	if opts.FanPwmFrequency == 0 {
+		return DefaultFanPwmFrequency
+	}
+	return opts.FanPwmFrequency
+}
+
+// KernelBackendOpts configures the PWM channel and SPI device used by the kernel backend
+type KernelBackendOpts struct {
+	// PwmChip is the sysfs PWM chip driving the fan PWM output (GPIO 12), e.g. pwmchip0
+	PwmChip string `mapstructure:"pwm_chip"`
+	// PwmChannel is the channel of the PWM chip driving the fan PWM output
+	PwmChannel int `mapstructure:"pwm_channel"`
+	// SpiDevice is the SPI device whose MOSI line drives the WS281x LEDs, e.g. /dev/spidev0.0
+	SpiDevice string `mapstructure:"spi_device"`
 }
 
 // ComputeBladeHal abstracts hardware details of the Compute Blade and provides a simple interface
@@ -57,10 +189,28 @@ type ComputeBladeHal interface {
 	SetLed(idx uint, color led.Color) error
 	// GetPowerStatus returns the current power status of the blade
 	GetPowerStatus() (PowerStatus, error)
+	// WaitForPowerStatusChange blocks until the power status differs from the last known status and returns the new status
+	WaitForPowerStatusChange(ctx context.Context, last PowerStatus) (PowerStatus, error)
 	// GetTemperature returns the current temperature of the SoC in °C
 	GetTemperature() (float64, error)
+	// ThermalSources returns the registry of all thermal sources of the blade
+	ThermalSources() *ThermalRegistry
+	// GetAirFlowTemperature returns the airflow temperature reported by the fan unit in °C
+	GetAirFlowTemperature() (float64, error)
 	// GetEdgeButtonPressChan returns a channel emitting edge button press events
 	WaitForEdgeButtonPress(ctx context.Context) error
+	// FanUnitKind returns the kind of the currently connected fan unit
+	FanUnitKind() FanUnitKind
+	// WaitForFanUnitChange blocks until the fan unit changes (e.g. the smart fan unit has been connected or lost) and returns the new kind
+	WaitForFanUnitChange(ctx context.Context) (FanUnitKind, error)
+	// FanUnitLinkStatus returns the health of the link to the smart fan unit
+	FanUnitLinkStatus() FanUnitLinkStatus
+	// FanUnitInfo returns the firmware info of the smart fan unit, the zero value for the standard fan unit
+	FanUnitInfo() FanUnitInfo
+	// SetFanUnitCurve configures the fan curve the smart fan unit applies once the blades stop sending fan speed requests
+	SetFanUnitCurve(curve smartfanunit.FanCurve) error
+	// SetFanUnitLUT configures the lookup table the EMC2101 of the smart fan unit drives the fan with
+	SetFanUnitLUT(lut smartfanunit.FanLUT) error
 }
 
 // FanUnit abstracts the fan unit
@@ -84,8 +234,9 @@ type FanUnit interface {
 	// WaitForButtonPress blocks until the button is pressed. Noop if the button is not available.
 	WaitForButtonPress(context.Context) error
 
-	// AirFlowTemperature returns the temperature of the air flow. Noop if the sensor is not available.
-	AirFlowTemperature(context.Context) (float32, error)
+	// Temperatures returns the temperatures in °C by sensor name, e.g. FanUnitSensorInternal and FanUnitSensorExternal
+	// of the smart fan unit. Returns ErrSensorNotAvailable if no sensor is available.
+	Temperatures(context.Context) (map[string]float32, error)
 
 	Close() error
 }
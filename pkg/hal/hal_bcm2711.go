@@ -6,20 +6,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
-	"strconv"
-	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
-	"github.com/warthog618/gpiod"
-	"github.com/warthog618/gpiod/device/rpi"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -38,9 +31,11 @@ const (
 
 	bcm2711RegPwmCtl  = 0x00
 	bcm2711RegPwmRng1 = 0x04
+	bcm2711RegPwmDat1 = 0x05
 	bcm2711RegPwmFif1 = 0x06
 
 	bcm2711RegPwmCtlBitPwen2 = 8 // Enable (pwm2)
+	bcm2711RegPwmCtlBitMsen1 = 7 // M/S mode; 0: PWM algorithm, 1: mark-space
 	bcm2711RegPwmCtlBitClrf1 = 6 // Clear FIFO
 	bcm2711RegPwmCtlBitUsef1 = 5 // Use FIFO
 	bcm2711RegPwmCtlBitSbit1 = 3 // Line level when not transmitting
@@ -52,48 +47,45 @@ const (
 	bcm2711RegPwmclkDiv            = 0x29
 	bcm2711RegPwmclkCntrlBitSrcOsc = 0
 	bcm2711RegPwmclkCntrlBitEnable = 4
+	bcm2711PwmclkSourceFrequency   = 54000000 // oscillator
+	bcm2711PwmclkMaxDivisor        = 0xfff    // 12 bits
 
-	bcm2711DebounceInterval = 100 * time.Millisecond
+	// The fan PWM clock is chosen so a period has ~1000 ticks, but at least 100 for 1% resolution
+	bcm2711FanPwmTargetRange = 1000
+	bcm2711FanPwmMinRange    = 100
 
-	bcm2711ThermalZonePath = "/sys/class/thermal/thermal_zone0/temp"
+	bcm2711SmartFanUnitDev = "/dev/ttyAMA5" // UART5
 
-	smartFanUnitDev = "/dev/ttyAMA5" // UART5
+	// PWM0 is shared between the fan and the LED data line, every LED frame interrupts the fan signal.
+	// Frames are therefore only sent if a color changed and at most once per bcm2711LedMinFrameInterval,
+	// changes within the interval are coalesced into a single frame.
+	bcm2711LedMinFrameInterval = 50 * time.Millisecond
 )
 
 type bcm2711 struct {
-	// Config options
-	opts ComputeBladeHalOpts
+	*computeBlade
 
 	wrMutex sync.Mutex
 
 	// Keep track of the currently set fanspeed so it can later be restored after setting the ws281x LEDs
 	currFanSpeed uint8
+	// Fan PWM clock divisor and range (ticks per period) for the configured frequency
+	fanPwmDivisor uint32
+	fanPwmRange   uint32
 
-	devmem    *os.File
-	gpioMem8  []uint8
-	gpioMem   []uint32
-	pwmMem8   []uint8
-	pwmMem    []uint32
-	clkMem8   []uint8
-	clkMem    []uint32
-	gpioChip0 *gpiod.Chip
+	devmem  *os.File
+	gpioReg registerBlock
+	pwmReg  registerBlock
+	clkReg  registerBlock
 
 	// Save LED colors so the pixels can be updated individually
-	leds [2]led.Color
-
-	// Stealth mode output
-	stealthModeLine *gpiod.Line
-
-	// Edge button input
-	edgeButtonLine         *gpiod.Line
-	edgeButtonDebounceChan chan struct{}
-	edgeButtonWatchChan    chan struct{}
-
-	// PoE detection input
-	poeLine *gpiod.Line
-
-	// Fan unit
-	fanUnit FanUnit
+	ledMutex      sync.Mutex
+	leds          [2]led.Color
+	sentLeds      [2]led.Color
+	ledsSent      bool
+	ledPending    bool
+	lastLedFrame  time.Time
+	ledFlushTimer *time.Timer
 }
 
 func NewCm4Hal(ctx context.Context, opts ComputeBladeHalOpts) (ComputeBladeHal, error) {
@@ -103,302 +95,184 @@ func NewCm4Hal(ctx context.Context, opts ComputeBladeHalOpts) (ComputeBladeHal,
 		return nil, err
 	}
 
-	gpioChip0, err := gpiod.NewChip("gpiochip0")
+	gpioChip0, err := openGpioChip("gpiochip0")
 	if err != nil {
 		return nil, err
 	}
 
 	// Setup memory mappings
-	gpioMem, gpioMem8, err := mmap(devmem, bcm2711GpioAddr, bcm2711PageSize)
+	gpioReg, err := mmapRegisters(devmem, bcm2711GpioAddr, bcm2711PageSize)
 	if err != nil {
 		return nil, err
 	}
-	pwmMem, pwmMem8, err := mmap(devmem, bcm2711RegPwmAddr, bcm2711PageSize)
+	pwmReg, err := mmapRegisters(devmem, bcm2711RegPwmAddr, bcm2711PageSize)
 	if err != nil {
 		return nil, err
 	}
-	clkMem, clkMem8, err := mmap(devmem, bcm2711ClkAddr, bcm2711PageSize)
+	clkReg, err := mmapRegisters(devmem, bcm2711ClkAddr, bcm2711PageSize)
 	if err != nil {
 		return nil, err
 	}
 
-	bcm := &bcm2711{
-		devmem:                 devmem,
-		gpioMem:                gpioMem,
-		gpioMem8:               gpioMem8,
-		pwmMem:                 pwmMem,
-		pwmMem8:                pwmMem8,
-		clkMem:                 clkMem,
-		clkMem8:                clkMem8,
-		gpioChip0:              gpioChip0,
-		opts:                   opts,
-		edgeButtonDebounceChan: make(chan struct{}, 1),
-		edgeButtonWatchChan:    make(chan struct{}),
-	}
+	bcm := newBcm2711(newComputeBlade(opts, gpioChip0), gpioReg, pwmReg, clkReg)
+	bcm.devmem = devmem
 
-	computeModule.WithLabelValues("cm4").Set(1)
+	computeModule.WithLabelValues(ComputeModuleCm4.String()).Set(1)
 
 	log.FromContext(ctx).Info("starting hal setup", zap.String("hal", "bcm2711"))
-	err = bcm.setup(ctx)
+	err = bcm.setup(ctx, bcm2711SmartFanUnitDev, bcm)
 	if err != nil {
 		return nil, err
 	}
 	return bcm, nil
 }
 
-// Close cleans all memory mappings
-func (bcm *bcm2711) Close() error {
-	errs := errors.Join(
-		bcm.fanUnit.Close(),
-		syscall.Munmap(bcm.gpioMem8),
-		syscall.Munmap(bcm.pwmMem8),
-		syscall.Munmap(bcm.clkMem8),
-		bcm.devmem.Close(),
-		bcm.gpioChip0.Close(),
-		bcm.poeLine.Close(),
-		bcm.stealthModeLine.Close(),
-	)
-
-	return errs
+// newBcm2711 creates the bcm2711 HAL operating on the given GPIO, PWM and clock manager registers
+func newBcm2711(cb *computeBlade, gpioReg, pwmReg, clkReg registerBlock) *bcm2711 {
+	bcm := &bcm2711{
+		computeBlade: cb,
+		gpioReg:      gpioReg,
+		pwmReg:       pwmReg,
+		clkReg:       clkReg,
+	}
+	// Restoring the fan PWM after LED updates requires a valid configuration, even if the fan PWM is not enabled
+	bcm.fanPwmDivisor, bcm.fanPwmRange, _ = bcm2711FanPwmConfig(DefaultFanPwmFrequency)
+	return bcm
 }
 
-// Init initialises GPIOs and sets sane defaults
-func (bcm *bcm2711) setup(ctx context.Context) error {
-	var err error = nil
-
-	// Register edge event handler for edge button
-	bcm.edgeButtonLine, err = bcm.gpioChip0.RequestLine(
-		rpi.GPIO20, gpiod.WithEventHandler(bcm.handleEdgeButtonEdge),
-		gpiod.WithFallingEdge, gpiod.WithPullUp, gpiod.WithDebounce(50*time.Millisecond))
-	if err != nil {
-		return err
+// bcm2711FanPwmConfig returns the PWM clock divisor and range for the given fan PWM frequency
+func bcm2711FanPwmConfig(frequency uint) (divisor uint32, rng uint32, err error) {
+	if frequency == 0 {
+		return 0, 0, fmt.Errorf("invalid fan pwm frequency 0")
 	}
+	div := uint64(bcm2711PwmclkSourceFrequency) / (uint64(frequency) * bcm2711FanPwmTargetRange)
+	div = max(div, 2)
+	div = min(div, bcm2711PwmclkMaxDivisor)
 
-	// Register input for PoE detection
-	bcm.poeLine, err = bcm.gpioChip0.RequestLine(rpi.GPIO23, gpiod.AsInput, gpiod.WithPullUp)
-	if err != nil {
-		return err
+	rng64 := uint64(bcm2711PwmclkSourceFrequency) / div / uint64(frequency)
+	if rng64 < bcm2711FanPwmMinRange {
+		return 0, 0, fmt.Errorf("fan pwm frequency %dHz too high for 1%% resolution", frequency)
 	}
+	return uint32(div), uint32(rng64), nil
+}
 
-	// Register output for stealth mode
-	bcm.stealthModeLine, err = bcm.gpioChip0.RequestLine(rpi.GPIO21, gpiod.AsOutput(1))
-	if err != nil {
-		return err
+// Close cleans all memory mappings
+func (bcm *bcm2711) Close() error {
+	// Cancel pending LED frames so they are not written to unmapped memory
+	bcm.ledMutex.Lock()
+	defer bcm.ledMutex.Unlock()
+	if bcm.ledFlushTimer != nil {
+		bcm.ledFlushTimer.Stop()
 	}
+	bcm.ledPending = false
 
-	// Setup correct fan unit
-	log.FromContext(ctx).Info("detecting fan unit")
-	detectCtx, cancel := context.WithTimeout(ctx, 3*time.Second) // temp events are sent every 2 seconds
-	defer cancel()
-
-	if smartFanUnitPresent, err := SmartFanUnitPresent(detectCtx, smartFanUnitDev); err == nil && smartFanUnitPresent {
-		log.FromContext(ctx).Error("detected smart fan unit")
-		bcm.fanUnit, err = NewSmartFanUnit(smartFanUnitDev)
-		if err != nil {
-			return err
-		}
-	} else {
-		log.FromContext(ctx).Info("no smart fan unit detected, assuming standard fan unit", zap.Error(err))
-		// FAN PWM output for standard fan unit (GPIO 12)
-		// -> bcm2711RegGpfsel1 8:6, alt0
-		bcm.gpioMem[bcm2711RegGpfsel1] = (bcm.gpioMem[bcm2711RegGpfsel1] &^ (0b111 << 6)) | (0b100 << 6)
-		bcm.fanUnit = &standardFanUnitBcm2711{
-			GpioChip0:           bcm.gpioChip0,
-			DisableRPMreporting: !bcm.opts.RpmReportingStandardFanUnit,
-			SetFanSpeedPwmFunc: func(speed uint8) error {
-				bcm.setFanSpeedPWM(speed)
-				return nil
-			},
-		}
+	errs := errors.Join(
+		bcm.computeBlade.close(),
+		bcm.gpioReg.close(),
+		bcm.pwmReg.close(),
+		bcm.clkReg.close(),
+	)
+	if bcm.devmem != nil {
+		errs = errors.Join(errs, bcm.devmem.Close())
 	}
 
-	return nil
-}
-
-func (bcm *bcm2711) Run(parentCtx context.Context) error {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
-
-	group := errgroup.Group{}
-
-	group.Go(func() error {
-		defer cancel()
-		return bcm.fanUnit.Run(ctx)
-	})
-
-	return group.Wait()
-}
-
-func (bcm *bcm2711) handleEdgeButtonEdge(evt gpiod.LineEvent) {
-	// Despite the debounce, we still get multiple events for a single button press
-	// -> This is an in-software debounce to ensure we only get one event per button press
-	select {
-	case bcm.edgeButtonDebounceChan <- struct{}{}:
-		go func() {
-			// Manually debounce the button
-			<-bcm.edgeButtonDebounceChan
-			time.Sleep(bcm2711DebounceInterval)
-			edgeButtonEventCount.Inc()
-			close(bcm.edgeButtonWatchChan)
-			bcm.edgeButtonWatchChan = make(chan struct{})
-		}()
-	default:
-		// noop
-		return
-	}
+	return errs
 }
 
-// WaitForEdgeButtonPress blocks until the edge button has been pressed
-func (bcm *bcm2711) WaitForEdgeButtonPress(parentCtx context.Context) error {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
-
-	fanUnitChan := make(chan struct{})
-	go func() {
-		err := bcm.fanUnit.WaitForButtonPress(ctx)
-		if err != nil && err != context.Canceled {
-			log.FromContext(ctx).Error("failed to wait for button press", zap.Error(err))
-		} else {
-			close(fanUnitChan)
-		}
-	}()
-
-	// Either wait for the context to be cancelled or the edge button to be pressed
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-bcm.edgeButtonWatchChan:
-		return nil
-	case <-fanUnitChan:
-		return nil
+// enableFanPwm routes PWM0 to the fan PWM output (GPIO 12)
+func (bcm *bcm2711) enableFanPwm() error {
+	divisor, rng, err := bcm2711FanPwmConfig(bcm.opts.fanPwmFrequency())
+	if err != nil {
+		return err
 	}
-}
+	bcm.wrMutex.Lock()
+	bcm.fanPwmDivisor, bcm.fanPwmRange = divisor, rng
+	bcm.wrMutex.Unlock()
 
-func (bcm *bcm2711) GetFanRPM() (float64, error) {
-	rpm, err := bcm.fanUnit.FanSpeedRPM(context.TODO())
-	return float64(rpm), err
+	// -> bcm2711RegGpfsel1 8:6, alt0
+	bcm.gpioReg.write(bcm2711RegGpfsel1, (bcm.gpioReg.read(bcm2711RegGpfsel1)&^(0b111<<6))|(0b100<<6))
+	return nil
 }
 
-func (bcm *bcm2711) GetPowerStatus() (PowerStatus, error) {
-	// GPIO 23 is used for PoE detection
-	val, err := bcm.poeLine.Value()
-	if err != nil {
-		return PowerPoeOrUsbC, err
-	}
+// enableSmartFanUnitUart routes UART5 to GPIO 13 (RX) and optionally GPIO 12 (TX)
+func (bcm *bcm2711) enableSmartFanUnitUart(tx bool) error {
+	bcm.wrMutex.Lock()
+	defer bcm.wrMutex.Unlock()
 
-	if val > 0 {
-		powerStatus.WithLabelValues(fmt.Sprint(PowerPoe802at)).Set(1)
-		powerStatus.WithLabelValues(fmt.Sprint(PowerPoeOrUsbC)).Set(0)
-		return PowerPoe802at, nil
+	// -> bcm2711RegGpfsel1 11:9, alt4
+	gpfsel := (bcm.gpioReg.read(bcm2711RegGpfsel1) &^ (0b111 << 9)) | (0b011 << 9)
+	if tx {
+		// -> bcm2711RegGpfsel1 8:6, alt4
+		gpfsel = (gpfsel &^ (0b111 << 6)) | (0b011 << 6)
 	}
-	powerStatus.WithLabelValues(fmt.Sprint(PowerPoe802at)).Set(0)
-	powerStatus.WithLabelValues(fmt.Sprint(PowerPoeOrUsbC)).Set(1)
-	return PowerPoeOrUsbC, nil
+	bcm.gpioReg.write(bcm2711RegGpfsel1, gpfsel)
+	return nil
 }
 
 func (bcm *bcm2711) setPwm0Freq(targetFrequency uint64) error {
 	// Calculate PWM divisor based on target frequency
-	divisor := 54000000 / targetFrequency
-	realDivisor := divisor & 0xfff // 12 bits
-	if divisor != realDivisor {
+	divisor := bcm2711PwmclkSourceFrequency / targetFrequency
+	if divisor > bcm2711PwmclkMaxDivisor {
 		return fmt.Errorf("invalid frequency, max divisor is 4095, calculated divisor is %d", divisor)
 	}
+	bcm.setPwm0Divisor(uint32(divisor))
+	return nil
+}
 
+// setPwm0Divisor sets the integer divisor of the PWM clock (54MHz oscillator)
+func (bcm *bcm2711) setPwm0Divisor(divisor uint32) {
 	// Stop pwm for both channels; this is required to set the new configuration
-	bcm.pwmMem[bcm2711RegPwmCtl] &^= (1 << bcm2711RegPwmCtlBitPwen1) | (1 << bcm2711RegPwmCtlBitPwen2)
+	bcm.pwmReg.write(bcm2711RegPwmCtl, bcm.pwmReg.read(bcm2711RegPwmCtl)&^((1<<bcm2711RegPwmCtlBitPwen1)|(1<<bcm2711RegPwmCtlBitPwen2)))
 	time.Sleep(time.Microsecond * 10)
 
 	// Stop clock w/o any changes, they cannot be made in the same step
-	bcm.clkMem[bcm2711RegPwmclkCntrl] = bcm2711ClkManagerPwd | (bcm.clkMem[bcm2711RegPwmclkCntrl] &^ (1 << 4))
+	bcm.clkReg.write(bcm2711RegPwmclkCntrl, bcm2711ClkManagerPwd|(bcm.clkReg.read(bcm2711RegPwmclkCntrl)&^(1<<4)))
 	time.Sleep(time.Microsecond * 10)
 
 	// Wait for the clock to not be busy so we can perform the changes
-	for bcm.clkMem[bcm2711RegPwmclkCntrl]&(1<<7) != 0 {
+	for bcm.clkReg.read(bcm2711RegPwmclkCntrl)&(1<<7) != 0 {
 		time.Sleep(time.Microsecond * 10)
 	}
 
 	// passwd, disabled, source (oscillator)
-	bcm.clkMem[bcm2711RegPwmclkCntrl] = bcm2711ClkManagerPwd | (0 << bcm2711RegPwmclkCntrlBitEnable) | (1 << bcm2711RegPwmclkCntrlBitSrcOsc)
+	bcm.clkReg.write(bcm2711RegPwmclkCntrl, bcm2711ClkManagerPwd|(0<<bcm2711RegPwmclkCntrlBitEnable)|(1<<bcm2711RegPwmclkCntrlBitSrcOsc))
 	time.Sleep(time.Microsecond * 10)
 
-	bcm.clkMem[bcm2711RegPwmclkDiv] = bcm2711ClkManagerPwd | (uint32(divisor) << 12)
+	bcm.clkReg.write(bcm2711RegPwmclkDiv, bcm2711ClkManagerPwd|(divisor<<12))
 	time.Sleep(time.Microsecond * 10)
 
 	// Start clock (passwd, enable, source)
-	bcm.clkMem[bcm2711RegPwmclkCntrl] = bcm2711ClkManagerPwd | (1 << bcm2711RegPwmclkCntrlBitEnable) | (1 << bcm2711RegPwmclkCntrlBitSrcOsc)
+	bcm.clkReg.write(bcm2711RegPwmclkCntrl, bcm2711ClkManagerPwd|(1<<bcm2711RegPwmclkCntrlBitEnable)|(1<<bcm2711RegPwmclkCntrlBitSrcOsc))
 	time.Sleep(time.Microsecond * 10)
 
 	// Start pwm for both channels again
-	bcm.pwmMem[bcm2711RegPwmCtl] &= (1 << bcm2711RegPwmCtlBitPwen1)
+	bcm.pwmReg.write(bcm2711RegPwmCtl, bcm.pwmReg.read(bcm2711RegPwmCtl)&(1<<bcm2711RegPwmCtlBitPwen1))
 	time.Sleep(time.Microsecond * 10)
-
-	return nil
 }
 
-// SetFanSpeed sets the fanspeed of a blade in percent (standard fan unit)
-func (bcm *bcm2711) SetFanSpeed(speed uint8) error {
-	fanTargetPercent.Set(float64(speed))
-	return bcm.fanUnit.SetFanSpeedPercent(context.TODO(), speed)
+// setFanSpeedPWM sets the fan duty cycle
+func (bcm *bcm2711) setFanSpeedPWM(speed uint8) error {
+	bcm.wrMutex.Lock()
+	defer bcm.wrMutex.Unlock()
+	return bcm.applyFanSpeedPWM(speed)
 }
 
-func (bcm *bcm2711) setFanSpeedPWM(speed uint8) {
-	// Noctua fans are expecting a 25khz signal, where duty cycle controls fan on/speed/off
-	// With the usage of the FIFO, we can alter the duty cycle by the number of bits set in the FIFO, maximum of 32.
-	// We therefore need a frequency of 32*25khz = 800khz, which is a divisor of 67.5 (thus we'll use 68).
-	// This results in an actual period frequency of 24.8khz, which is within the specifications of Noctua fans.
-	err := bcm.setPwm0Freq(800000)
-	if err != nil {
-		// we know it produces a valid divisor, so this should never happen
-		panic(err)
-	}
+// applyFanSpeedPWM configures PWM0 for the fan, wrMutex must be held
+func (bcm *bcm2711) applyFanSpeedPWM(speed uint8) error {
+	// The fan is driven in M/S mode: the output is high for DAT1 out of RNG1 clock ticks.
+	// Noctua fans are expecting a 25khz signal, with a 27MHz clock (divisor 2) this results in a range of 1080.
+	bcm.setPwm0Divisor(bcm.fanPwmDivisor)
 
-	// Using hardware ticks would offer a better resultion, but this works for now.
-	var targetvalue uint32 = 0
-	if speed == 0 {
-		targetvalue = 0
-	} else if speed <= 100 {
-		for i := 0; i <= int((float64(speed)/100.0)*32.0); i++ {
-			targetvalue |= (1 << i)
-		}
-	} else {
-		targetvalue = ^(uint32(0))
-	}
+	speed = min(speed, 100)
+	duty := (uint32(speed)*bcm.fanPwmRange + 50) / 100
 
-	// Use fifo, repeat, ...
-	bcm.pwmMem[bcm2711RegPwmCtl] = (1 << bcm2711RegPwmCtlBitPwen1) | (1 << bcm2711RegPwmCtlBitMode1) | (1 << bcm2711RegPwmCtlBitRptl1) | (1 << bcm2711RegPwmCtlBitUsef1)
-	time.Sleep(10 * time.Microsecond)
-	bcm.pwmMem[bcm2711RegPwmRng1] = 32
-	time.Sleep(10 * time.Microsecond)
-	bcm.pwmMem[bcm2711RegPwmFif1] = targetvalue
+	bcm.pwmReg.write(bcm2711RegPwmRng1, bcm.fanPwmRange)
+	bcm.pwmReg.write(bcm2711RegPwmDat1, duty)
+	bcm.pwmReg.write(bcm2711RegPwmCtl, (1<<bcm2711RegPwmCtlBitPwen1)|(1<<bcm2711RegPwmCtlBitMsen1))
 
 	// Store fan speed for later use
 	bcm.currFanSpeed = speed
-}
-
-func (bcm *bcm2711) SetStealthMode(enable bool) error {
-	if enable {
-		stealthModeEnabled.Set(1)
-		return bcm.stealthModeLine.SetValue(1)
-	} else {
-		stealthModeEnabled.Set(0)
-		return bcm.stealthModeLine.SetValue(0)
-	}
-}
-
-// serializePwmDataFrame converts a byte to a 24 bit PWM data frame for WS281x LEDs
-func serializePwmDataFrame(data uint8) uint32 {
-	var result uint32 = 0
-	for i := 7; i >= 0; i-- {
-		if i != 7 {
-			result <<= 3
-		}
-		if (uint32(data)&(1<<i))>>i == 0 {
-			result |= 0b100 // -__
-		} else {
-			result |= 0b110 // --_
-		}
-	}
-	return result
+	return nil
 }
 
 func (bcm *bcm2711) SetLed(idx uint, color led.Color) error {
@@ -406,91 +280,112 @@ func (bcm *bcm2711) SetLed(idx uint, color led.Color) error {
 		return fmt.Errorf("invalid led index %d, supported: [0, 1]", idx)
 	}
 
-	// Update the fan unit LED if the index is the same as the fan unit LED index
+	// Update the fan unit LED if the index is the same as the fan unit LED index, sent in the background
 	if idx == LedEdge {
-		bcm.fanUnit.SetLed(context.TODO(), color)
+		bcm.setFanUnitLed(color)
 	}
 
+	bcm.ledMutex.Lock()
+	defer bcm.ledMutex.Unlock()
 	bcm.leds[idx] = color
 
-	return bcm.updateLEDs()
+	return bcm.scheduleLedFrame()
+}
+
+// scheduleLedFrame sends the LED colors if they changed, rate limited to one frame per bcm2711LedMinFrameInterval.
+// ledMutex must be held.
+func (bcm *bcm2711) scheduleLedFrame() error {
+	if bcm.ledPending || (bcm.ledsSent && bcm.leds == bcm.sentLeds) {
+		ledFramesSkippedCount.Inc()
+		return nil
+	}
+
+	wait := bcm2711LedMinFrameInterval - time.Since(bcm.lastLedFrame)
+	if wait <= 0 {
+		return bcm.flushLeds()
+	}
+
+	// Coalesce all changes until the interval has passed
+	bcm.ledPending = true
+	bcm.ledFlushTimer = time.AfterFunc(wait, func() {
+		bcm.ledMutex.Lock()
+		defer bcm.ledMutex.Unlock()
+		if !bcm.ledPending {
+			// Cancelled by Close
+			return
+		}
+		bcm.ledPending = false
+		if bcm.ledsSent && bcm.leds == bcm.sentLeds {
+			ledFramesSkippedCount.Inc()
+			return
+		}
+		if err := bcm.flushLeds(); err != nil {
+			zap.L().Error("Failed to update LEDs", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// flushLeds sends the current LED colors, ledMutex must be held
+func (bcm *bcm2711) flushLeds() error {
+	bcm.lastLedFrame = time.Now()
+	if err := bcm.updateLEDs(); err != nil {
+		return err
+	}
+	bcm.sentLeds = bcm.leds
+	bcm.ledsSent = true
+	return nil
 }
 
 // updateLEDs sets the color of the WS281x LEDs
-func (bcm *bcm2711) updateLEDs() error {
+func (bcm *bcm2711) updateLEDs() (err error) {
 	bcm.wrMutex.Lock()
 	defer bcm.wrMutex.Unlock()
 
 	ledColorChangeEventCount.Inc()
 
+	// The fan signal is interrupted from reconfiguring the clock until the fan configuration is restored
+	glitchStart := time.Now()
+	defer func() {
+		fanPwmGlitchSeconds.Observe(time.Since(glitchStart).Seconds())
+	}()
+
 	// Set frequency to 3*800khz.
 	// we'll bit-bang the data, so we'll need to send 3 bits per bit of data.
-	bcm.setPwm0Freq(3 * 800000)
-	time.Sleep(10 * time.Microsecond)
+	if err := bcm.setPwm0Freq(3 * 800000); err != nil {
+		return errors.Join(err, bcm.applyFanSpeedPWM(bcm.currFanSpeed))
+	}
 
 	// WS281x Output (GPIO 18)
 	// -> bcm2711RegGpfsel1 24:26, regular output; it's configured as alt5 whenever pixel data is sent.
 	// This is not optimal but required as the pwm0 peripheral is shared between fan and data line for the LEDs.
-	time.Sleep(10 * time.Microsecond)
-	bcm.gpioMem[bcm2711RegGpfsel1] = (bcm.gpioMem[bcm2711RegGpfsel1] &^ (0b111 << 24)) | (0b010 << 24)
+	bcm.gpioReg.write(bcm2711RegGpfsel1, (bcm.gpioReg.read(bcm2711RegGpfsel1)&^(0b111<<24))|(0b010<<24))
 	time.Sleep(10 * time.Microsecond)
 	defer func() {
 		// Set to regular output again so the PWM signal doesn't confuse the WS2812
-		bcm.gpioMem[bcm2711RegGpfsel1] = (bcm.gpioMem[bcm2711RegGpfsel1] &^ (0b111 << 24)) | (0b001 << 24)
-		bcm.setFanSpeedPWM(bcm.currFanSpeed)
+		bcm.gpioReg.write(bcm2711RegGpfsel1, (bcm.gpioReg.read(bcm2711RegGpfsel1)&^(0b111<<24))|(0b001<<24))
+		err = errors.Join(err, bcm.applyFanSpeedPWM(bcm.currFanSpeed))
 	}()
 
-	bcm.pwmMem[bcm2711RegPwmCtl] = (1 << bcm2711RegPwmCtlBitMode1) | (1 << bcm2711RegPwmCtlBitRptl1) | (0 << bcm2711RegPwmCtlBitSbit1) | (1 << bcm2711RegPwmCtlBitUsef1) | (1 << bcm2711RegPwmCtlBitClrf1)
+	bcm.pwmReg.write(bcm2711RegPwmCtl, (1<<bcm2711RegPwmCtlBitMode1)|(1<<bcm2711RegPwmCtlBitRptl1)|(0<<bcm2711RegPwmCtlBitSbit1)|(1<<bcm2711RegPwmCtlBitUsef1)|(1<<bcm2711RegPwmCtlBitClrf1))
 	time.Sleep(10 * time.Microsecond)
-	// bcm.pwmMem[bcm2711RegPwmRng1] = 32
-	bcm.pwmMem[bcm2711RegPwmRng1] = 24 // we only need 24 bits per LED
+	bcm.pwmReg.write(bcm2711RegPwmRng1, 24) // we only need 24 bits per LED
 	time.Sleep(10 * time.Microsecond)
 
-	// Add sufficient padding to clear 50us of silence with ~412.5ns per bit -> at least 121 bits -> let's be safe and send 6*24=144 bits of silence
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0
-	// Write top LED data
-	bcm.pwmMem[bcm2711RegPwmFif1] = serializePwmDataFrame(bcm.leds[0].Red) << 8
-	bcm.pwmMem[bcm2711RegPwmFif1] = serializePwmDataFrame(bcm.leds[0].Green) << 8
-	bcm.pwmMem[bcm2711RegPwmFif1] = serializePwmDataFrame(bcm.leds[0].Blue) << 8
-	// Write edge LED data
-	bcm.pwmMem[bcm2711RegPwmFif1] = serializePwmDataFrame(bcm.leds[1].Red) << 8
-	bcm.pwmMem[bcm2711RegPwmFif1] = serializePwmDataFrame(bcm.leds[1].Green) << 8
-	bcm.pwmMem[bcm2711RegPwmFif1] = serializePwmDataFrame(bcm.leds[1].Blue) << 8
-	// make sure there's >50us of silence
-	bcm.pwmMem[bcm2711RegPwmFif1] = 0 // auto-repeated, so no need to feed the FIFO further.
-
-	bcm.pwmMem[bcm2711RegPwmCtl] = (1 << bcm2711RegPwmCtlBitPwen1) | (1 << bcm2711RegPwmCtlBitMode1) | (1 << bcm2711RegPwmCtlBitRptl1) | (0 << bcm2711RegPwmCtlBitSbit1) | (1 << bcm2711RegPwmCtlBitUsef1)
-	// sleep for 4*50us to ensure the data is sent. This is probably a bit too gracious but does not have a significant impact, so let's be safe data gets out.
-	time.Sleep(200 * time.Microsecond)
-
-	return nil
-}
-
-// GetTemperature returns the current temperature of the SoC
-func (bcm *bcm2711) GetTemperature() (float64, error) {
-	// Read temperature
-
-	f, err := os.Open(bcm2711ThermalZonePath)
-	if err != nil {
-		return -1, err
-	}
-	raw, err := io.ReadAll(f)
-	if err != nil {
-		return -1, err
+	// Silence, top LED, edge LED and silence again (auto-repeated, so no need to feed the FIFO further)
+	frames := ws281xFrames(bcm.leds)
+	for _, frame := range frames {
+		bcm.pwmReg.write(bcm2711RegPwmFif1, frame)
 	}
 
-	cpuTemp, err := strconv.Atoi(strings.TrimSpace(string(raw)))
-	if err != nil {
-		return -1, err
-	}
+	bcm.pwmReg.write(bcm2711RegPwmCtl, (1<<bcm2711RegPwmCtlBitPwen1)|(1<<bcm2711RegPwmCtlBitMode1)|(1<<bcm2711RegPwmCtlBitRptl1)|(0<<bcm2711RegPwmCtlBitSbit1)|(1<<bcm2711RegPwmCtlBitUsef1))
+	// Wait until the frames are shifted out (24 bits each at 2.4MHz) plus the WS281x latch time
+	time.Sleep(ws281xSerializerDuration(len(frames)) + 50*time.Microsecond)
 
-	temp := float64(cpuTemp) / 1000.0
-	socTemperature.Set(temp)
+	return nil
+}
 
-	return temp, nil
+// ws281xSerializerDuration returns the time it takes to shift out the given number of 24 bit frames at 3*800khz
+func ws281xSerializerDuration(frames int) time.Duration {
+	return time.Duration(frames*24) * time.Second / (3 * 800000)
 }
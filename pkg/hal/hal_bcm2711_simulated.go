@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 	"go.uber.org/zap"
 )
 
@@ -15,7 +16,13 @@ var _ ComputeBladeHal = &SimulatedHal{}
 
 // ComputeBladeMock implements a mock for the ComputeBladeHal interface
 type SimulatedHal struct {
-	logger *zap.Logger
+	logger  *zap.Logger
+	thermal *ThermalRegistry
+}
+
+// NewComputeBladeHal returns the simulated HAL
+func NewComputeBladeHal(ctx context.Context, opts ComputeBladeHalOpts) (ComputeBladeHal, error) {
+	return NewCm4Hal(ctx, opts)
 }
 
 func NewCm4Hal(_ context.Context, _ ComputeBladeHalOpts) (ComputeBladeHal, error) {
@@ -27,8 +34,14 @@ func NewCm4Hal(_ context.Context, _ ComputeBladeHalOpts) (ComputeBladeHal, error
 
 	socTemperature.Set(42)
 
+	thermal := NewThermalRegistry()
+	thermal.Register(NewThermalSource(ThermalSourceSoc, func(_ context.Context) (float64, error) {
+		return 42, nil
+	}))
+
 	return &SimulatedHal{
-		logger: logger,
+		logger:  logger,
+		thermal: thermal,
 	}, nil
 }
 
@@ -67,6 +80,16 @@ func (m *SimulatedHal) GetPowerStatus() (PowerStatus, error) {
 	return PowerPoe802at, nil
 }
 
+func (m *SimulatedHal) WaitForPowerStatusChange(ctx context.Context, last PowerStatus) (PowerStatus, error) {
+	m.logger.Info("WaitForPowerStatusChange")
+	if last != PowerPoe802at {
+		return PowerPoe802at, nil
+	}
+	// The simulated power status never changes
+	<-ctx.Done()
+	return PowerPoe802at, ctx.Err()
+}
+
 func (m *SimulatedHal) WaitForEdgeButtonPress(ctx context.Context) error {
 	m.logger.Info("WaitForEdgeButtonPress")
 	select {
@@ -88,3 +111,42 @@ func (m *SimulatedHal) GetTemperature() (float64, error) {
 	m.logger.Info("GetTemperature")
 	return 42, nil
 }
+
+func (m *SimulatedHal) GetAirFlowTemperature() (float64, error) {
+	m.logger.Info("GetAirFlowTemperature")
+	airFlowTemperature.Set(28)
+	return 28, nil
+}
+
+func (m *SimulatedHal) ThermalSources() *ThermalRegistry {
+	return m.thermal
+}
+
+func (m *SimulatedHal) FanUnitKind() FanUnitKind {
+	return FanUnitKindStandard
+}
+
+func (m *SimulatedHal) WaitForFanUnitChange(ctx context.Context) (FanUnitKind, error) {
+	m.logger.Info("WaitForFanUnitChange")
+	// The simulated fan unit never changes
+	<-ctx.Done()
+	return FanUnitKindStandard, ctx.Err()
+}
+
+func (m *SimulatedHal) FanUnitLinkStatus() FanUnitLinkStatus {
+	return FanUnitLinkNone
+}
+
+func (m *SimulatedHal) FanUnitInfo() FanUnitInfo {
+	return FanUnitInfo{}
+}
+
+func (m *SimulatedHal) SetFanUnitCurve(curve smartfanunit.FanCurve) error {
+	m.logger.Info("SetFanUnitCurve", zap.Any("curve", curve))
+	return curve.Validate()
+}
+
+func (m *SimulatedHal) SetFanUnitLUT(lut smartfanunit.FanLUT) error {
+	m.logger.Info("SetFanUnitLUT", zap.Any("lut", lut))
+	return lut.Validate()
+}
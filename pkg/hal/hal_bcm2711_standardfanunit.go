@@ -4,7 +4,7 @@ package hal
 
 import (
 	"context"
-	"math"
+	"time"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
 	"github.com/warthog618/gpiod"
@@ -12,26 +12,25 @@ import (
 )
 
 type standardFanUnitBcm2711 struct {
-	GpioChip0           *gpiod.Chip
+	GpioChip0           gpioChip
 	SetFanSpeedPwmFunc  func(speed uint8) error
 	DisableRPMreporting bool
+	MinDutyPercent      uint8
 
 	// Fan tach input
-	fanEdgeLine      *gpiod.Line
-	lastFanEdgeEvent *gpiod.LineEvent
-	fanRpm           float64
+	fanEdgeLine gpioLine
+	tach        tachometer
 }
 
-func (fu standardFanUnitBcm2711) Kind() FanUnitKind {
+func (fu *standardFanUnitBcm2711) Kind() FanUnitKind {
 	if fu.DisableRPMreporting {
 		return FanUnitKindStandardNoRPM
 	}
 	return FanUnitKindStandard
 }
 
-func (fu standardFanUnitBcm2711) Run(ctx context.Context) error {
+func (fu *standardFanUnitBcm2711) Run(ctx context.Context) error {
 	var err error
-	fanUnit.WithLabelValues("standard").Set(1)
 
 	// Register edge event handler for fan tach input
 	if !fu.DisableRPMreporting {
@@ -47,34 +46,31 @@ func (fu standardFanUnitBcm2711) Run(ctx context.Context) error {
 		defer fu.fanEdgeLine.Close()
 	}
 
-	<-ctx.Done()
-	return ctx.Err()
+	// Keep the metric current, it has to decay to 0 once the fan stops and no edges arrive anymore
+	ticker := time.NewTicker(tachWindow)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if !fu.DisableRPMreporting {
+				fanSpeed.Set(fu.tach.rpm(time.Now()))
+			}
+		}
+	}
 }
 
-// handleFanEdge handles an edge event on the fan tach input for the standard fan unite.
-// Exponential moving average is used to smooth out the fan speed.
+// handleFanEdge handles an edge event on the fan tach input for the standard fan unit.
 func (fu *standardFanUnitBcm2711) handleFanEdge(evt gpiod.LineEvent) {
-	// Ensure we're always storing the last event
-	defer func() {
-		fu.lastFanEdgeEvent = &evt
-	}()
-
-	// First event, we cannot extrapolate the fan speed yet
-	if fu.lastFanEdgeEvent == nil {
-		return
-	}
-
-	// Calculate time delta between events
-	delta := evt.Timestamp - fu.lastFanEdgeEvent.Timestamp
-	ticksPerSecond := 1000.0 / float64(delta.Milliseconds())
-	rpm := (ticksPerSecond * 60.0) / 2.0 // 2 ticks per revolution
-
-	// Simple moving average to smooth out the fan speed
-	fu.fanRpm = (rpm * 0.1) + (fu.fanRpm * 0.9)
-	fanSpeed.Set(fu.fanRpm)
+	fu.tach.addEdge(evt.Timestamp, time.Now())
 }
 
 func (fu *standardFanUnitBcm2711) SetFanSpeedPercent(_ context.Context, percent uint8) error {
+	// Keep fans that stall at low duty cycles spinning, 0% still turns the fan off
+	if percent > 0 && percent < fu.MinDutyPercent {
+		percent = fu.MinDutyPercent
+	}
 	return fu.SetFanSpeedPwmFunc(percent)
 }
 
@@ -83,7 +79,14 @@ func (fu *standardFanUnitBcm2711) SetLed(_ context.Context, _ led.Color) error {
 }
 
 func (fu *standardFanUnitBcm2711) FanSpeedRPM(_ context.Context) (float64, error) {
-	return fu.fanRpm, nil
+	rpm := fu.tach.rpm(time.Now())
+	fanSpeed.Set(rpm)
+	return rpm, nil
+}
+
+// FanSpinning returns true if the tach signal is the one of a spinning fan and not e.g. the UART of a smart fan unit
+func (fu *standardFanUnitBcm2711) FanSpinning() bool {
+	return fu.tach.spinning(time.Now())
 }
 
 func (fu *standardFanUnitBcm2711) WaitForButtonPress(ctx context.Context) error {
@@ -91,8 +94,8 @@ func (fu *standardFanUnitBcm2711) WaitForButtonPress(ctx context.Context) error
 	return ctx.Err()
 }
 
-func (fu *standardFanUnitBcm2711) AirFlowTemperature(_ context.Context) (float32, error) {
-	return -1 * math.MaxFloat32, nil
+func (fu *standardFanUnitBcm2711) Temperatures(_ context.Context) (map[string]float32, error) {
+	return nil, ErrSensorNotAvailable
 }
 
 func (fu *standardFanUnitBcm2711) Close() error {
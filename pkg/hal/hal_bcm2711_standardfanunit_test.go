@@ -0,0 +1,55 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/device/rpi"
+)
+
+func TestStandardFanUnit_MinDutyPercent(t *testing.T) {
+	t.Parallel()
+
+	var got []uint8
+	fu := &standardFanUnitBcm2711{
+		MinDutyPercent: 20,
+		SetFanSpeedPwmFunc: func(speed uint8) error {
+			got = append(got, speed)
+			return nil
+		},
+	}
+	for _, speed := range []uint8{0, 1, 19, 20, 21, 100} {
+		assert.NoError(t, fu.SetFanSpeedPercent(context.Background(), speed))
+	}
+	assert.Equal(t, []uint8{0, 20, 20, 20, 21, 100}, got)
+}
+
+func TestStandardFanUnit_FanSpeedRPM(t *testing.T) {
+	t.Parallel()
+
+	fu := &standardFanUnitBcm2711{}
+	rpm, err := fu.FanSpeedRPM(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, rpm)
+
+	// Falling edges of a 1200 RPM fan with a bit of jitter, as reported by the kernel
+	ts := 5 * time.Hour
+	for i := 0; i < 40; i++ {
+		jitter := time.Duration(i%3-1) * 50 * time.Microsecond
+		fu.handleFanEdge(gpiod.LineEvent{
+			Offset:    rpi.GPIO13,
+			Timestamp: ts + jitter,
+			Type:      gpiod.LineEventFallingEdge,
+		})
+		ts += 25 * time.Millisecond
+	}
+
+	rpm, err = fu.FanSpeedRPM(context.Background())
+	assert.NoError(t, err)
+	assert.InDelta(t, 1200, rpm, 5)
+}
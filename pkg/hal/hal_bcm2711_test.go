@@ -0,0 +1,416 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/device/rpi"
+)
+
+// fakeBcm2711 is a bcm2711 operating on fake registers and GPIOs instead of /dev/mem and gpiochip0
+type fakeBcm2711 struct {
+	*bcm2711
+	gpio *fakeGpioChip
+	regs struct {
+		gpio, pwm, clk *fakeRegisterBlock
+	}
+}
+
+func newFakeBcm2711() *fakeBcm2711 {
+	f := &fakeBcm2711{gpio: newFakeGpioChip()}
+	f.regs.gpio = newFakeRegisterBlock(bcm2711PageSize)
+	f.regs.pwm = newFakeRegisterBlock(bcm2711PageSize)
+	f.regs.clk = newFakeRegisterBlock(bcm2711PageSize)
+
+	cb := newComputeBlade(ComputeBladeHalOpts{}, f.gpio)
+	cb.fanUnit = &standardFanUnitBcm2711{}
+	f.bcm2711 = newBcm2711(cb, f.regs.gpio, f.regs.pwm, f.regs.clk)
+	return f
+}
+
+func gpfselFunction(gpfsel uint32, pin int) uint32 {
+	return (gpfsel >> (3 * (pin % 10))) & 0b111
+}
+
+func TestBcm2711_EnableSmartFanUnitUart(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	assert.NoError(t, bcm.enableFanPwm())
+
+	// Probing only takes over RX (GPIO 13), the fan PWM keeps running
+	assert.NoError(t, bcm.enableSmartFanUnitUart(false))
+	gpfsel := bcm.regs.gpio.read(bcm2711RegGpfsel1)
+	assert.Equal(t, uint32(0b100), gpfselFunction(gpfsel, 12))
+	assert.Equal(t, uint32(0b011), gpfselFunction(gpfsel, 13))
+
+	assert.NoError(t, bcm.enableSmartFanUnitUart(true))
+	gpfsel = bcm.regs.gpio.read(bcm2711RegGpfsel1)
+	assert.Equal(t, uint32(0b011), gpfselFunction(gpfsel, 12))
+	assert.Equal(t, uint32(0b011), gpfselFunction(gpfsel, 13))
+}
+
+func TestBcm2711_SetPwm0Freq(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		frequency uint64
+		divisor   uint32
+	}{
+		{frequency: 800000, divisor: 67},     // fan: 54MHz / 800khz = 67.5
+		{frequency: 3 * 800000, divisor: 22}, // WS281x: 54MHz / 2.4MHz = 22.5
+		{frequency: 54000000 / 4095, divisor: 4095},
+	}
+	for _, tt := range tests {
+		bcm := newFakeBcm2711()
+		assert.NoError(t, bcm.setPwm0Freq(tt.frequency))
+
+		assert.Equal(t, []uint32{bcm2711ClkManagerPwd | tt.divisor<<12}, bcm.regs.clk.writesTo(bcm2711RegPwmclkDiv))
+		// Disabled before changing the divisor, enabled with the oscillator as source afterwards
+		cntrl := bcm.regs.clk.writesTo(bcm2711RegPwmclkCntrl)
+		assert.Len(t, cntrl, 3)
+		assert.Zero(t, cntrl[1]&(1<<bcm2711RegPwmclkCntrlBitEnable))
+		assert.Equal(t, uint32(bcm2711ClkManagerPwd|1<<bcm2711RegPwmclkCntrlBitEnable|1<<bcm2711RegPwmclkCntrlBitSrcOsc), cntrl[2])
+	}
+}
+
+func TestBcm2711_SetPwm0FreqInvalid(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	assert.Error(t, bcm.setPwm0Freq(10000))
+	assert.Empty(t, bcm.regs.clk.writesTo(bcm2711RegPwmclkDiv))
+}
+
+func TestBcm2711_EnableFanPwm(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	bcm.regs.gpio.preset(bcm2711RegGpfsel1, 0b001<<24)
+	assert.NoError(t, bcm.enableFanPwm())
+
+	// GPIO 12 -> alt0 (PWM0), GPIO 18 untouched
+	gpfsel := bcm.regs.gpio.read(bcm2711RegGpfsel1)
+	assert.Equal(t, uint32(0b100), gpfselFunction(gpfsel, 12))
+	assert.Equal(t, uint32(0b001), gpfselFunction(gpfsel, 18))
+}
+
+func TestBcm2711FanPwmConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		frequency uint
+		divisor   uint32
+		rng       uint32
+	}{
+		{frequency: 25000, divisor: 2, rng: 1080}, // Noctua
+		{frequency: 21000, divisor: 2, rng: 1285},
+		{frequency: 1000, divisor: 54, rng: 1000},
+		{frequency: 100, divisor: 540, rng: 1000},
+		{frequency: 10, divisor: 4095, rng: 1318},
+		{frequency: 270000, divisor: 2, rng: 100},
+	}
+	for _, tt := range tests {
+		divisor, rng, err := bcm2711FanPwmConfig(tt.frequency)
+		assert.NoError(t, err, "frequency %d", tt.frequency)
+		assert.Equal(t, tt.divisor, divisor, "frequency %d", tt.frequency)
+		assert.Equal(t, tt.rng, rng, "frequency %d", tt.frequency)
+	}
+
+	// Less than 1% resolution
+	_, _, err := bcm2711FanPwmConfig(300000)
+	assert.Error(t, err)
+	_, _, err = bcm2711FanPwmConfig(0)
+	assert.Error(t, err)
+}
+
+func TestBcm2711_SetFanSpeedPWM(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		speed uint8
+		duty  uint32
+	}{
+		{speed: 0, duty: 0},
+		{speed: 1, duty: 11},
+		{speed: 2, duty: 22},
+		{speed: 50, duty: 540},
+		{speed: 99, duty: 1069},
+		{speed: 100, duty: 1080},
+		{speed: 200, duty: 1080},
+	}
+	for _, tt := range tests {
+		bcm := newFakeBcm2711()
+		assert.NoError(t, bcm.enableFanPwm())
+		assert.NoError(t, bcm.setFanSpeedPWM(tt.speed))
+
+		// M/S mode at 27MHz / 1080 = 25khz without FIFO
+		assert.Equal(t, []uint32{tt.duty}, bcm.regs.pwm.writesTo(bcm2711RegPwmDat1), "speed %d", tt.speed)
+		assert.Empty(t, bcm.regs.pwm.writesTo(bcm2711RegPwmFif1))
+		assert.Equal(t, uint32(1080), bcm.regs.pwm.read(bcm2711RegPwmRng1))
+		assert.Equal(t, uint32(bcm2711ClkManagerPwd|2<<12), bcm.regs.clk.read(bcm2711RegPwmclkDiv))
+		assert.Equal(t, uint32(1<<bcm2711RegPwmCtlBitPwen1|1<<bcm2711RegPwmCtlBitMsen1), bcm.regs.pwm.read(bcm2711RegPwmCtl))
+	}
+}
+
+func TestBcm2711_SetFanSpeedPWMResolution(t *testing.T) {
+	t.Parallel()
+
+	// Every percent maps to a distinct duty cycle
+	bcm := newFakeBcm2711()
+	assert.NoError(t, bcm.enableFanPwm())
+	for speed := uint8(0); speed <= 100; speed++ {
+		assert.NoError(t, bcm.setFanSpeedPWM(speed))
+	}
+	duties := bcm.regs.pwm.writesTo(bcm2711RegPwmDat1)
+	for i := 1; i < len(duties); i++ {
+		assert.Greater(t, duties[i], duties[i-1])
+	}
+}
+
+func TestBcm2711_FanPwmFrequency(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	bcm.opts.FanPwmFrequency = 100
+	assert.NoError(t, bcm.enableFanPwm())
+	assert.NoError(t, bcm.setFanSpeedPWM(25))
+
+	assert.Equal(t, uint32(bcm2711ClkManagerPwd|540<<12), bcm.regs.clk.read(bcm2711RegPwmclkDiv))
+	assert.Equal(t, uint32(1000), bcm.regs.pwm.read(bcm2711RegPwmRng1))
+	assert.Equal(t, uint32(250), bcm.regs.pwm.read(bcm2711RegPwmDat1))
+
+	bcm.opts.FanPwmFrequency = 1000000
+	assert.Error(t, bcm.enableFanPwm())
+}
+
+func TestBcm2711_UpdateLEDsFifo(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	assert.NoError(t, bcm.setFanSpeedPWM(50))
+	bcm.regs.pwm.resetWrites()
+	bcm.regs.gpio.resetWrites()
+
+	bcm.leds = [2]led.Color{{Red: 0xff, Green: 0x80, Blue: 0x01}, {Blue: 0x20}}
+	assert.NoError(t, bcm.updateLEDs())
+
+	assert.Equal(t, ws281xFrames(bcm.leds), bcm.regs.pwm.writesTo(bcm2711RegPwmFif1))
+	// Serializer with 24 bits per FIFO entry while sending the frames, the fan range and duty cycle are restored afterwards
+	assert.Equal(t, []uint32{24, 1080}, bcm.regs.pwm.writesTo(bcm2711RegPwmRng1))
+	assert.Equal(t, []uint32{540}, bcm.regs.pwm.writesTo(bcm2711RegPwmDat1))
+
+	// GPIO 18 is switched to alt5 (PWM0) for the frames and back to a regular output afterwards
+	gpfsel := bcm.regs.gpio.writesTo(bcm2711RegGpfsel1)
+	assert.Len(t, gpfsel, 2)
+	assert.Equal(t, uint32(0b010), gpfselFunction(gpfsel[0], 18))
+	assert.Equal(t, uint32(0b001), gpfselFunction(gpfsel[1], 18))
+}
+
+func TestBcm2711_UpdateLEDsRestoresFanPwm(t *testing.T) {
+	t.Parallel()
+
+	for _, speed := range []uint8{0, 1, 40, 99, 100} {
+		bcm := newFakeBcm2711()
+		assert.NoError(t, bcm.enableFanPwm())
+		assert.NoError(t, bcm.setFanSpeedPWM(speed))
+		wantPwm, wantClk := bcm.regs.pwm.snapshot(), bcm.regs.clk.snapshot()
+
+		bcm.leds = [2]led.Color{{Red: 255}, {Blue: 32}}
+		assert.NoError(t, bcm.updateLEDs())
+
+		// The FIFO is not used by the fan
+		gotPwm := bcm.regs.pwm.snapshot()
+		gotPwm[bcm2711RegPwmFif1] = wantPwm[bcm2711RegPwmFif1]
+		assert.Equal(t, wantPwm, gotPwm, "pwm registers for speed %d", speed)
+		assert.Equal(t, wantClk, bcm.regs.clk.snapshot(), "clock registers for speed %d", speed)
+
+		// GPIO 12 stays on PWM0 (alt0), GPIO 18 is back to a regular output
+		gpfsel := bcm.regs.gpio.read(bcm2711RegGpfsel1)
+		assert.Equal(t, uint32(0b100), gpfselFunction(gpfsel, 12))
+		assert.Equal(t, uint32(0b001), gpfselFunction(gpfsel, 18))
+	}
+}
+
+func TestBcm2711_SetLedSkipsUnchangedColors(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	assert.NoError(t, bcm.SetLed(LedTop, led.Color{Green: 10}))
+	assert.NotEmpty(t, bcm.regs.pwm.writesTo(bcm2711RegPwmFif1))
+
+	// The same color must not touch the registers, even once the rate limit has passed
+	bcm.regs.pwm.resetWrites()
+	bcm.lastLedFrame = time.Time{}
+	assert.NoError(t, bcm.SetLed(LedTop, led.Color{Green: 10}))
+	assert.Empty(t, bcm.regs.pwm.writesTo(bcm2711RegPwmFif1))
+}
+
+func TestBcm2711_SetLedCoalescesFrames(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	assert.NoError(t, bcm.SetLed(LedTop, led.Color{Red: 1}))
+
+	// Changes within the frame interval are not sent immediately ...
+	bcm.regs.pwm.resetWrites()
+	for i := uint8(2); i <= 10; i++ {
+		assert.NoError(t, bcm.SetLed(LedTop, led.Color{Red: i}))
+	}
+	assert.Empty(t, bcm.regs.pwm.writesTo(bcm2711RegPwmFif1))
+
+	// ... but coalesced into a single frame with the latest colors
+	want := ws281xFrames([2]led.Color{{Red: 10}, {}})
+	assert.Eventually(t, func() bool {
+		return len(bcm.regs.pwm.writesTo(bcm2711RegPwmFif1)) >= len(want)
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, want, bcm.regs.pwm.writesTo(bcm2711RegPwmFif1))
+}
+
+func TestBcm2711_Close(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	bcm.opts.SysfsRoot = t.TempDir()
+	assert.NoError(t, bcm.setup(context.Background(), "/nonexistent", bcm))
+	assert.NoError(t, bcm.Close())
+
+	assert.True(t, bcm.regs.gpio.closed)
+	assert.True(t, bcm.regs.pwm.closed)
+	assert.True(t, bcm.regs.clk.closed)
+	assert.True(t, bcm.gpio.closed)
+	for _, pin := range []int{rpi.GPIO20, rpi.GPIO21, rpi.GPIO23} {
+		assert.True(t, bcm.gpio.line(pin).isClosed(), "GPIO %d", pin)
+	}
+}
+
+func TestBcm2711_Setup(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2711()
+	bcm.opts.SysfsRoot = t.TempDir()
+	assert.NoError(t, bcm.setup(context.Background(), "/nonexistent", bcm))
+
+	// Without a smart fan unit, the fan is driven by PWM0 on GPIO 12
+	current, _ := bcm.fanUnits.unit()
+	assert.IsType(t, &standardFanUnitBcm2711{}, current)
+	assert.Equal(t, uint32(0b100), gpfselFunction(bcm.regs.gpio.read(bcm2711RegGpfsel1), 12))
+
+	// Stealth mode output is requested high
+	stealth := bcm.gpio.line(rpi.GPIO21)
+	assert.NoError(t, bcm.SetStealthMode(true))
+	assert.NoError(t, bcm.SetStealthMode(false))
+	assert.Equal(t, []int{1, 1, 0}, stealth.values())
+
+	// PoE detection
+	bcm.gpio.line(rpi.GPIO23).setInput(1)
+	status, err := bcm.GetPowerStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, PowerStatus(PowerPoe802at), status)
+}
+
+func TestWs281xSerializerDuration(t *testing.T) {
+	t.Parallel()
+
+	// 24 bits at 2.4MHz
+	assert.Equal(t, 10*time.Microsecond, ws281xSerializerDuration(1))
+	assert.Equal(t, 130*time.Microsecond, ws281xSerializerDuration(13))
+}
+
+func TestComputeBlade_WaitForPowerStatusChange(t *testing.T) {
+	t.Parallel()
+
+	cb := newComputeBlade(ComputeBladeHalOpts{}, newFakeGpioChip())
+	poeLine := &fakeGpioLine{value: 1}
+	cb.poeLine = poeLine
+
+	// A change since the last known status is returned right away, even without an edge
+	status, err := cb.WaitForPowerStatusChange(context.Background(), PowerPoeOrUsbC)
+	assert.NoError(t, err)
+	assert.Equal(t, PowerStatus(PowerPoe802at), status)
+
+	done := make(chan PowerStatus)
+	go func() {
+		status, _ := cb.WaitForPowerStatusChange(context.Background(), PowerPoe802at)
+		done <- status
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("returned without a power status change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	poeLine.setInput(0)
+	cb.handlePoeEdge(gpiod.LineEvent{Type: gpiod.LineEventFallingEdge})
+	select {
+	case status := <-done:
+		assert.Equal(t, PowerStatus(PowerPoeOrUsbC), status)
+	case <-time.After(time.Second):
+		t.Fatal("power status change not detected")
+	}
+}
+
+// flakyLedFanUnit is a fake fan unit failing to set the LED a given number of times
+type flakyLedFanUnit struct {
+	*fakeFanUnit
+
+	ledMu       sync.Mutex
+	ledFailures int
+	ledAttempts []led.Color
+}
+
+func (f *flakyLedFanUnit) SetLed(ctx context.Context, color led.Color) error {
+	f.ledMu.Lock()
+	f.ledAttempts = append(f.ledAttempts, color)
+	fail := f.ledFailures > 0
+	if fail {
+		f.ledFailures--
+	}
+	f.ledMu.Unlock()
+
+	if fail {
+		return ErrCommunicationFailed
+	}
+	return f.fakeFanUnit.SetLed(ctx, color)
+}
+
+func (f *flakyLedFanUnit) attempts() []led.Color {
+	f.ledMu.Lock()
+	defer f.ledMu.Unlock()
+	return append([]led.Color(nil), f.ledAttempts...)
+}
+
+func TestComputeBlade_FanUnitLed(t *testing.T) {
+	t.Parallel()
+
+	fu := &flakyLedFanUnit{fakeFanUnit: newFakeFanUnit(FanUnitKindSmart), ledFailures: 1}
+	bcm := newFakeBcm2711()
+	bcm.fanUnit = fu
+
+	// Setting the LED doesn't wait for the fan unit, colors set in the meantime are coalesced
+	assert.NoError(t, bcm.SetLed(LedEdge, led.Color{Red: 1}))
+	assert.NoError(t, bcm.SetLed(LedEdge, led.Color{Red: 2}))
+	assert.Empty(t, fu.attempts())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bcm.runFanUnitLed(ctx) }()
+
+	// The first attempt fails and is retried in the background
+	assert.Eventually(t, func() bool {
+		_, color := fu.settings()
+		return color != nil && *color == led.Color{Red: 2}
+	}, 2*fanUnitLedRetryInterval, 10*time.Millisecond)
+	assert.Equal(t, []led.Color{{Red: 2}, {Red: 2}}, fu.attempts())
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,292 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"go.uber.org/zap"
+)
+
+// On the BCM2712 (CM5), GPIOs and PWM are provided by the RP1 I/O controller which is attached via PCIe.
+// The RP1 peripherals are mapped into the address space of the BCM2712 through PCIe BAR1.
+// Register indices are 32 bit word offsets, see RP1 peripherals datasheet.
+const (
+	bcm2712Rp1PeripheryBaseAddr = 0x1F00000000
+	bcm2712Rp1ClocksMainAddr    = bcm2712Rp1PeripheryBaseAddr + 0x018000
+	bcm2712Rp1Pwm0Addr          = bcm2712Rp1PeripheryBaseAddr + 0x098000
+	bcm2712Rp1IoBank0Addr       = bcm2712Rp1PeripheryBaseAddr + 0x0D0000
+	bcm2712Rp1PadsBank0Addr     = bcm2712Rp1PeripheryBaseAddr + 0x0F0000
+	bcm2712PageSize             = 4096
+
+	bcm2712Rp1GpioChipLabel = "pinctrl-rp1"
+
+	// IO_BANK0: GPIOn_STATUS at 2n, GPIOn_CTRL at 2n+1
+	bcm2712RegGpioCtrlFuncselMask = 0x1f
+
+	// PADS_BANK0: GPIOn at n+1
+	bcm2712RegPadsBitOd = 7 // Output disable
+	bcm2712RegPadsBitIe = 6 // Input enable
+
+	// PWM0
+	bcm2712RegPwmGlobalCtl = 0x00
+	bcm2712RegPwmDutyFifo  = 0x04
+	bcm2712RegPwmChanBase  = 0x05 // CHANn_CTRL at 5+4n, CHANn_RANGE at 6+4n, CHANn_PHASE at 7+4n, CHANn_DUTY at 8+4n
+
+	bcm2712RegPwmGlobalCtlBitSetUpdate = 31 // Apply channel configuration changes
+
+	bcm2712RegPwmChanCtlBitFifoPopMask = 8 // Only pop from the FIFO at the end of a period
+	bcm2712RegPwmChanCtlBitUseFifo     = 5 // Use FIFO
+	bcm2712RegPwmChanCtlModeTrailing   = 0x1
+	bcm2712RegPwmChanCtlModeMsbSerial  = 0x4
+
+	// CLOCKS_MAIN: CLK_PWM0
+	bcm2712RegClkPwm0Ctrl         = 0x1D
+	bcm2712RegClkPwm0DivInt       = 0x1E
+	bcm2712RegClkPwm0DivFrac      = 0x1F
+	bcm2712RegClkCtrlBitEnable    = 11
+	bcm2712ClkPwm0SourceFrequency = 50000000 // xosc
+
+	// Fan PWM: GPIO 12, PWM0 channel 0 (a0)
+	bcm2712FanPwmPin     = 12
+	bcm2712FanPwmChannel = 0
+	bcm2712FanPwmFuncsel = 0
+
+	// WS281x: GPIO 18, PWM0 channel 2 (a3)
+	bcm2712LedPin     = 18
+	bcm2712LedChannel = 2
+	bcm2712LedFuncsel = 3
+
+	// The PWM0 clock is shared by all channels and the range of the fan PWM channel is derived from it.
+	// The serializer sends 3 bits per bit of WS281x data, at 2.5Mhz a WS281x bit takes 1.2us instead of the nominal 1.25us,
+	// which is well within the tolerance of the LEDs and gives the 25khz fan PWM a range of 100 (1% resolution).
+	bcm2712PwmClockFrequency = 2500000
+	bcm2712FanPwmMinRange    = 100
+
+	bcm2712SmartFanUnitDev = "/dev/ttyAMA4" // RP1 UART4 (GPIO 12/13)
+	bcm2712Uart4Funcsel    = 2              // a2: UART4 TX (GPIO 12), RX (GPIO 13)
+)
+
+type bcm2712 struct {
+	*computeBlade
+
+	wrMutex sync.Mutex
+
+	devmem    *os.File
+	ioBankReg registerBlock
+	padsReg   registerBlock
+	pwmReg    registerBlock
+	clkReg    registerBlock
+
+	// Range of the fan PWM channel for the configured frequency
+	fanPwmRange uint32
+
+	// Save LED colors so the pixels can be updated individually
+	ledMutex sync.Mutex
+	leds     [2]led.Color
+}
+
+// NewCm5Hal creates the HAL for the Compute Module 5 (BCM2712 + RP1)
+func NewCm5Hal(ctx context.Context, opts ComputeBladeHalOpts) (ComputeBladeHal, error) {
+	devmem, err := os.OpenFile("/dev/mem", os.O_RDWR|os.O_SYNC, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	gpioChip, err := findGpioChip(bcm2712Rp1GpioChipLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup memory mappings
+	ioBankReg, err := mmapRegisters(devmem, bcm2712Rp1IoBank0Addr, bcm2712PageSize)
+	if err != nil {
+		return nil, err
+	}
+	padsReg, err := mmapRegisters(devmem, bcm2712Rp1PadsBank0Addr, bcm2712PageSize)
+	if err != nil {
+		return nil, err
+	}
+	pwmReg, err := mmapRegisters(devmem, bcm2712Rp1Pwm0Addr, bcm2712PageSize)
+	if err != nil {
+		return nil, err
+	}
+	clkReg, err := mmapRegisters(devmem, bcm2712Rp1ClocksMainAddr, bcm2712PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	bcm := newBcm2712(newComputeBlade(opts, gpioChip), ioBankReg, padsReg, pwmReg, clkReg)
+	bcm.devmem = devmem
+
+	computeModule.WithLabelValues(ComputeModuleCm5.String()).Set(1)
+
+	log.FromContext(ctx).Info("starting hal setup", zap.String("hal", "bcm2712"))
+	bcm.setupPwm()
+	err = bcm.setup(ctx, bcm2712SmartFanUnitDev, bcm)
+	if err != nil {
+		return nil, err
+	}
+	return bcm, nil
+}
+
+// newBcm2712 creates the bcm2712 HAL operating on the given RP1 IO bank, pads, PWM and clock registers
+func newBcm2712(cb *computeBlade, ioBankReg, padsReg, pwmReg, clkReg registerBlock) *bcm2712 {
+	return &bcm2712{
+		computeBlade: cb,
+		ioBankReg:    ioBankReg,
+		padsReg:      padsReg,
+		pwmReg:       pwmReg,
+		clkReg:       clkReg,
+	}
+}
+
+// Close cleans all memory mappings
+func (bcm *bcm2712) Close() error {
+	errs := errors.Join(
+		bcm.computeBlade.close(),
+		bcm.ioBankReg.close(),
+		bcm.padsReg.close(),
+		bcm.pwmReg.close(),
+		bcm.clkReg.close(),
+	)
+	if bcm.devmem != nil {
+		errs = errors.Join(errs, bcm.devmem.Close())
+	}
+
+	return errs
+}
+
+// setupPwm configures the PWM0 clock and routes the WS281x channel to the LED data line
+func (bcm *bcm2712) setupPwm() {
+	bcm.setPwm0Freq(bcm2712PwmClockFrequency)
+
+	// WS281x output (GPIO 18), serializes 24 bits per FIFO entry.
+	// Unlike the BCM2711, the fan and the LEDs use separate channels, so the pin can stay assigned to the PWM.
+	bcm.setGpioFunction(bcm2712LedPin, bcm2712LedFuncsel)
+	bcm.pwmReg.write(bcm2712PwmChanCtl(bcm2712LedChannel), bcm2712RegPwmChanCtlModeMsbSerial|(1<<bcm2712RegPwmChanCtlBitUseFifo))
+	bcm.pwmReg.write(bcm2712PwmChanRange(bcm2712LedChannel), 24)
+	bcm.pwmReg.write(bcm2712RegPwmGlobalCtl, bcm.pwmReg.read(bcm2712RegPwmGlobalCtl)|(1<<bcm2712LedChannel)|(1<<bcm2712RegPwmGlobalCtlBitSetUpdate))
+}
+
+// setPwm0Freq sets the frequency of the PWM0 clock shared by all channels
+func (bcm *bcm2712) setPwm0Freq(targetFrequency uint64) {
+	// Divisor with 16 bit fractional part
+	divisor := (uint64(bcm2712ClkPwm0SourceFrequency) << 16) / targetFrequency
+
+	// Disable the clock while changing the divisor
+	bcm.clkReg.write(bcm2712RegClkPwm0Ctrl, bcm.clkReg.read(bcm2712RegClkPwm0Ctrl)&^(1<<bcm2712RegClkCtrlBitEnable))
+	time.Sleep(10 * time.Microsecond)
+
+	bcm.clkReg.write(bcm2712RegClkPwm0DivInt, uint32(divisor>>16))
+	bcm.clkReg.write(bcm2712RegClkPwm0DivFrac, uint32(divisor&0xffff)<<16) // fraction is stored in the upper 16 bits
+	time.Sleep(10 * time.Microsecond)
+
+	bcm.clkReg.write(bcm2712RegClkPwm0Ctrl, bcm.clkReg.read(bcm2712RegClkPwm0Ctrl)|(1<<bcm2712RegClkCtrlBitEnable))
+	time.Sleep(10 * time.Microsecond)
+}
+
+// setGpioFunction selects the function of an RP1 bank 0 GPIO and enables its pad as output
+func (bcm *bcm2712) setGpioFunction(pin int, funcsel uint32) {
+	ctrl := bcm2712GpioCtrl(pin)
+	bcm.ioBankReg.write(ctrl, (bcm.ioBankReg.read(ctrl)&^bcm2712RegGpioCtrlFuncselMask)|(funcsel&bcm2712RegGpioCtrlFuncselMask))
+
+	pad := bcm2712GpioPad(pin)
+	bcm.padsReg.write(pad, (bcm.padsReg.read(pad)&^(1<<bcm2712RegPadsBitOd))|(1<<bcm2712RegPadsBitIe))
+}
+
+// enableFanPwm routes PWM0 channel 0 to the fan PWM output (GPIO 12)
+func (bcm *bcm2712) enableFanPwm() error {
+	frequency := bcm.opts.fanPwmFrequency()
+	if frequency == 0 || bcm2712PwmClockFrequency/frequency < bcm2712FanPwmMinRange {
+		return fmt.Errorf("fan pwm frequency %dHz too high for 1%% resolution, max %dHz", frequency, bcm2712PwmClockFrequency/bcm2712FanPwmMinRange)
+	}
+	bcm.fanPwmRange = uint32(bcm2712PwmClockFrequency / frequency)
+
+	bcm.setGpioFunction(bcm2712FanPwmPin, bcm2712FanPwmFuncsel)
+	bcm.pwmReg.write(bcm2712PwmChanCtl(bcm2712FanPwmChannel), bcm2712RegPwmChanCtlModeTrailing|(1<<bcm2712RegPwmChanCtlBitFifoPopMask))
+	bcm.pwmReg.write(bcm2712PwmChanRange(bcm2712FanPwmChannel), bcm.fanPwmRange)
+	bcm.pwmReg.write(bcm2712RegPwmGlobalCtl, bcm.pwmReg.read(bcm2712RegPwmGlobalCtl)|(1<<bcm2712FanPwmChannel)|(1<<bcm2712RegPwmGlobalCtlBitSetUpdate))
+	return nil
+}
+
+// enableSmartFanUnitUart routes UART4 to GPIO 13 (RX) and optionally GPIO 12 (TX)
+func (bcm *bcm2712) enableSmartFanUnitUart(tx bool) error {
+	bcm.setGpioFunction(bcm2712FanPwmPin+1, bcm2712Uart4Funcsel)
+	if tx {
+		bcm.setGpioFunction(bcm2712FanPwmPin, bcm2712Uart4Funcsel)
+	}
+	return nil
+}
+
+// setFanSpeedPWM sets the duty cycle of the fan PWM channel
+func (bcm *bcm2712) setFanSpeedPWM(speed uint8) error {
+	if speed > 100 {
+		speed = 100
+	}
+	bcm.pwmReg.write(bcm2712PwmChanDuty(bcm2712FanPwmChannel), (uint32(speed)*bcm.fanPwmRange+50)/100)
+	bcm.pwmReg.write(bcm2712RegPwmGlobalCtl, bcm.pwmReg.read(bcm2712RegPwmGlobalCtl)|(1<<bcm2712RegPwmGlobalCtlBitSetUpdate))
+	return nil
+}
+
+func (bcm *bcm2712) SetLed(idx uint, color led.Color) error {
+	if idx >= 2 {
+		return fmt.Errorf("invalid led index %d, supported: [0, 1]", idx)
+	}
+
+	// Update the fan unit LED if the index is the same as the fan unit LED index, sent in the background
+	if idx == LedEdge {
+		bcm.setFanUnitLed(color)
+	}
+
+	bcm.ledMutex.Lock()
+	defer bcm.ledMutex.Unlock()
+	bcm.leds[idx] = color
+
+	return bcm.updateLEDs()
+}
+
+// updateLEDs sets the color of the WS281x LEDs, ledMutex must be held
+func (bcm *bcm2712) updateLEDs() error {
+	bcm.wrMutex.Lock()
+	defer bcm.wrMutex.Unlock()
+
+	ledColorChangeEventCount.Inc()
+
+	// Silence, top LED, edge LED and silence again
+	for _, frame := range ws281xFrames(bcm.leds) {
+		bcm.pwmReg.write(bcm2712RegPwmDutyFifo, frame)
+	}
+
+	// sleep for 4*50us to ensure the data is sent, analogous to the BCM2711.
+	time.Sleep(200 * time.Microsecond)
+
+	return nil
+}
+
+func bcm2712GpioCtrl(pin int) int {
+	return 2*pin + 1
+}
+
+func bcm2712GpioPad(pin int) int {
+	return pin + 1
+}
+
+func bcm2712PwmChanCtl(channel int) int {
+	return bcm2712RegPwmChanBase + 4*channel
+}
+
+func bcm2712PwmChanRange(channel int) int {
+	return bcm2712RegPwmChanBase + 4*channel + 1
+}
+
+func bcm2712PwmChanDuty(channel int) int {
+	return bcm2712RegPwmChanBase + 4*channel + 3
+}
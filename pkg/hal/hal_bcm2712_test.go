@@ -0,0 +1,163 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+)
+
+// fakeBcm2712 is a bcm2712 operating on fake registers and GPIOs instead of /dev/mem and the RP1 gpiochip
+type fakeBcm2712 struct {
+	*bcm2712
+	gpio *fakeGpioChip
+	regs struct {
+		ioBank, pads, pwm, clk *fakeRegisterBlock
+	}
+}
+
+func newFakeBcm2712() *fakeBcm2712 {
+	f := &fakeBcm2712{gpio: newFakeGpioChip()}
+	f.regs.ioBank = newFakeRegisterBlock(bcm2712PageSize)
+	f.regs.pads = newFakeRegisterBlock(bcm2712PageSize)
+	f.regs.pwm = newFakeRegisterBlock(bcm2712PageSize)
+	f.regs.clk = newFakeRegisterBlock(bcm2712PageSize)
+
+	cb := newComputeBlade(ComputeBladeHalOpts{}, f.gpio)
+	cb.fanUnit = &standardFanUnitBcm2711{}
+	f.bcm2712 = newBcm2712(cb, f.regs.ioBank, f.regs.pads, f.regs.pwm, f.regs.clk)
+	return f
+}
+
+func TestBcm2712_SetupPwm(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2712()
+	bcm.regs.ioBank.preset(bcm2712GpioCtrl(bcm2712LedPin), 0x1f|(1<<12)) // NULL function, keep other bits
+	bcm.regs.pads.preset(bcm2712GpioPad(bcm2712LedPin), 1<<bcm2712RegPadsBitOd)
+
+	bcm.setupPwm()
+
+	// 50MHz / 2.5MHz = 20
+	assert.Equal(t, uint32(20), bcm.regs.clk.read(bcm2712RegClkPwm0DivInt))
+	assert.Equal(t, uint32(0), bcm.regs.clk.read(bcm2712RegClkPwm0DivFrac))
+	assert.NotZero(t, bcm.regs.clk.read(bcm2712RegClkPwm0Ctrl)&(1<<bcm2712RegClkCtrlBitEnable))
+	// The clock is disabled while changing the divisor
+	assert.Equal(t, []uint32{0, 1 << bcm2712RegClkCtrlBitEnable}, bcm.regs.clk.writesTo(bcm2712RegClkPwm0Ctrl))
+
+	// GPIO 18 -> PWM0 channel 2
+	assert.Equal(t, uint32(bcm2712LedFuncsel|(1<<12)), bcm.regs.ioBank.read(37))
+	assert.Equal(t, uint32(1<<bcm2712RegPadsBitIe), bcm.regs.pads.read(19))
+
+	// Channel 2: MSB serializer from FIFO with 24 bits per entry
+	assert.Equal(t, uint32(0x24), bcm.regs.pwm.read(13))
+	assert.Equal(t, uint32(24), bcm.regs.pwm.read(14))
+	assert.Equal(t, uint32(1<<31|1<<2), bcm.regs.pwm.read(bcm2712RegPwmGlobalCtl))
+}
+
+func TestBcm2712_FanPwm(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2712()
+	assert.NoError(t, bcm.enableFanPwm())
+
+	// GPIO 12 -> PWM0 channel 0 (a0)
+	assert.Equal(t, uint32(0), bcm.regs.ioBank.read(25)&bcm2712RegGpioCtrlFuncselMask)
+	assert.Equal(t, uint32(1<<bcm2712RegPadsBitIe), bcm.regs.pads.read(13))
+
+	// Channel 0: trailing edge PWM at 25khz with 1% resolution
+	assert.Equal(t, uint32(0x101), bcm.regs.pwm.read(5))
+	assert.Equal(t, uint32(100), bcm.regs.pwm.read(6))
+	assert.Equal(t, uint32(1<<31|1<<0), bcm.regs.pwm.read(bcm2712RegPwmGlobalCtl))
+
+	for _, tc := range []struct {
+		speed uint8
+		duty  uint32
+	}{
+		{0, 0},
+		{1, 1},
+		{40, 40},
+		{50, 50},
+		{100, 100},
+		{255, 100},
+	} {
+		assert.NoError(t, bcm.setFanSpeedPWM(tc.speed))
+		assert.Equal(t, tc.duty, bcm.regs.pwm.read(8), "speed %d", tc.speed)
+	}
+}
+
+func TestBcm2712_FanPwmFrequency(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2712()
+	bcm.opts.FanPwmFrequency = 1000
+	assert.NoError(t, bcm.enableFanPwm())
+	assert.Equal(t, uint32(2500), bcm.regs.pwm.read(6))
+
+	assert.NoError(t, bcm.setFanSpeedPWM(1))
+	assert.Equal(t, uint32(25), bcm.regs.pwm.read(8))
+
+	// Frequencies leaving less than 1% resolution are rejected
+	bcm.opts.FanPwmFrequency = 25001
+	assert.Error(t, bcm.enableFanPwm())
+	bcm.opts.FanPwmFrequency = 2000000
+	assert.Error(t, bcm.enableFanPwm())
+}
+
+func TestBcm2712_UpdateLEDs(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2712()
+	assert.NoError(t, bcm.SetLed(LedTop, led.Color{Red: 0xff}))
+	bcm.regs.pwm.resetWrites()
+	assert.NoError(t, bcm.SetLed(LedEdge, led.Color{Blue: 0x01}))
+
+	// Silence, top LED (R, G, B), edge LED (R, G, B) and silence again, MSB aligned
+	assert.Equal(t, []uint32{
+		0, 0, 0, 0, 0, 0,
+		0xdb6db600, 0x92492400, 0x92492400,
+		0x92492400, 0x92492400, 0x92492600,
+		0,
+	}, bcm.regs.pwm.writesTo(bcm2712RegPwmDutyFifo))
+	// The fan PWM is not affected by LED updates
+	assert.Equal(t, uint32(0), bcm.regs.pwm.read(bcm2712RegPwmGlobalCtl))
+}
+
+func TestBcm2712_SetLedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2712()
+	var wg sync.WaitGroup
+	for idx := uint(0); idx < 2; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := uint8(1); i <= 10; i++ {
+				assert.NoError(t, bcm.SetLed(idx, led.Color{Green: i}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every frame is complete and the last one has the latest colors of both LEDs
+	fifo := bcm.regs.pwm.writesTo(bcm2712RegPwmDutyFifo)
+	want := ws281xFrames([2]led.Color{{Green: 10}, {Green: 10}})
+	assert.Len(t, fifo, 20*len(want))
+	assert.Equal(t, want, fifo[len(fifo)-len(want):])
+}
+
+func TestBcm2712_Close(t *testing.T) {
+	t.Parallel()
+
+	bcm := newFakeBcm2712()
+	assert.NoError(t, bcm.Close())
+
+	assert.True(t, bcm.regs.ioBank.closed)
+	assert.True(t, bcm.regs.pads.closed)
+	assert.True(t, bcm.regs.pwm.closed)
+	assert.True(t, bcm.regs.clk.closed)
+	assert.True(t, bcm.gpio.closed)
+}
@@ -0,0 +1,534 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"github.com/warthog618/gpiod"
+	"github.com/warthog618/gpiod/device/rpi"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	edgeButtonDebounceInterval = 100 * time.Millisecond
+	thermalUpdateInterval      = 5 * time.Second
+
+	// fanUnitLedTimeout is the time a single attempt to set the LED of the fan unit may take, including its retries
+	fanUnitLedTimeout = time.Second
+	// fanUnitLedRetryInterval is the interval in which failed LED colors are sent again to the fan unit
+	fanUnitLedRetryInterval = time.Second
+	// fanUnitLedRetries is the number of times a failed LED color is sent again, unless a newer color is set
+	fanUnitLedRetries = 3
+
+	deviceTreeCompatiblePath = "/proc/device-tree/compatible"
+)
+
+// fanPwmOutput is the SoC specific PWM output driving the standard fan unit (GPIO 12)
+type fanPwmOutput interface {
+	// enableFanPwm routes the PWM peripheral to the fan PWM pin and configures the fan PWM frequency
+	enableFanPwm() error
+	// setFanSpeedPWM sets the duty cycle of the fan PWM in percent
+	setFanSpeedPWM(speed uint8) error
+}
+
+// smartFanUnitUart is implemented by SoCs which have to route the UART of the smart fan unit (GPIO 12 TX, GPIO 13 RX)
+// to its pins again after they have been used by the standard fan unit
+type smartFanUnitUart interface {
+	// enableSmartFanUnitUart routes the UART RX and, if tx is set, TX to the pins of the smart fan unit.
+	// Probing only requires RX, so the fan PWM on GPIO 12 keeps running.
+	enableSmartFanUnitUart(tx bool) error
+}
+
+// computeBlade implements the SoC independent parts of the ComputeBladeHal (GPIOs, fan unit and thermal sources).
+// SoC specific implementations embed it and provide the fan PWM and the WS281x LED output.
+type computeBlade struct {
+	// Config options
+	opts ComputeBladeHalOpts
+
+	gpio gpioChip
+
+	// Stealth mode output
+	stealthModeLine gpioLine
+
+	// Edge button input
+	edgeButtonLine         gpioLine
+	edgeButtonDebounceChan chan struct{}
+	edgeButtonWatchChan    chan struct{}
+
+	// PoE detection input
+	poeLine      gpioLine
+	poeMutex     sync.Mutex
+	poeWatchChan chan struct{}
+
+	// Fan unit, delegates to the currently connected fan unit (fanUnits)
+	fanUnit  FanUnit
+	fanUnits *fanUnitSupervisor
+	// Pending LED color of the fan unit, sent in the background as sending may block
+	fanUnitLedChan chan led.Color
+
+	// Thermal sources (SoC, hwmon, ...)
+	thermal *ThermalRegistry
+}
+
+// NewComputeBladeHal detects the compute module and returns the matching HAL implementation
+func NewComputeBladeHal(ctx context.Context, opts ComputeBladeHalOpts) (ComputeBladeHal, error) {
+	module, err := DetectComputeModule(deviceTreeCompatiblePath)
+	if err != nil {
+		return nil, err
+	}
+
+	log.FromContext(ctx).Info("detected compute module", zap.String("module", module.String()), zap.String("backend", string(opts.Backend)))
+	switch opts.Backend {
+	case "", HalBackendDevmem:
+	case HalBackendKernel:
+		return NewKernelHal(ctx, opts, module)
+	default:
+		return nil, fmt.Errorf("unsupported hal backend %q", opts.Backend)
+	}
+
+	switch module {
+	case ComputeModuleCm4:
+		return NewCm4Hal(ctx, opts)
+	case ComputeModuleCm5:
+		return NewCm5Hal(ctx, opts)
+	default:
+		return nil, fmt.Errorf("unsupported compute module")
+	}
+}
+
+// DetectComputeModule detects the compute module based on the device tree compatible property
+func DetectComputeModule(compatiblePath string) (ComputeModule, error) {
+	raw, err := os.ReadFile(compatiblePath)
+	if err != nil {
+		return ComputeModuleUnknown, err
+	}
+	return ParseDeviceTreeCompatible(raw), nil
+}
+
+// findGpioChip returns the GPIO chip with the given label (e.g. pinctrl-rp1), the chip numbering is not stable across kernels
+func findGpioChip(label string) (gpioChip, error) {
+	for _, name := range gpiod.Chips() {
+		chip, err := gpiod.NewChip(name)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(chip.Label, label) {
+			return newGpiodChip(chip), nil
+		}
+		chip.Close()
+	}
+	return nil, fmt.Errorf("gpio chip %s not found", label)
+}
+
+func newComputeBlade(opts ComputeBladeHalOpts, gpio gpioChip) *computeBlade {
+	return &computeBlade{
+		opts:                   opts,
+		gpio:                   gpio,
+		edgeButtonDebounceChan: make(chan struct{}, 1),
+		edgeButtonWatchChan:    make(chan struct{}),
+		poeWatchChan:           make(chan struct{}),
+		fanUnitLedChan:         make(chan led.Color, 1),
+		thermal:                NewThermalRegistry(),
+	}
+}
+
+// setup initialises GPIOs, thermal sources and the fan unit
+func (cb *computeBlade) setup(ctx context.Context, smartFanUnitDev string, fanPwm fanPwmOutput) error {
+	var err error = nil
+
+	// Register edge event handler for edge button
+	cb.edgeButtonLine, err = cb.gpio.RequestLine(
+		rpi.GPIO20, gpiod.WithEventHandler(cb.handleEdgeButtonEdge),
+		gpiod.WithFallingEdge, gpiod.WithPullUp, gpiod.WithDebounce(50*time.Millisecond))
+	if err != nil {
+		return err
+	}
+
+	// Register edge event handler for PoE detection
+	cb.poeLine, err = cb.gpio.RequestLine(
+		rpi.GPIO23, gpiod.WithEventHandler(cb.handlePoeEdge),
+		gpiod.WithBothEdges, gpiod.WithPullUp, gpiod.WithDebounce(50*time.Millisecond))
+	if err != nil {
+		return err
+	}
+
+	// Register output for stealth mode
+	cb.stealthModeLine, err = cb.gpio.RequestLine(rpi.GPIO21, gpiod.AsOutput(1))
+	if err != nil {
+		return err
+	}
+
+	// Discover thermal sources
+	thermalSources, err := DiscoverThermalSources(cb.opts.SysfsRoot)
+	if err != nil {
+		return err
+	}
+	cb.thermal.Register(thermalSources...)
+	log.FromContext(ctx).Info("discovered thermal sources", zap.Strings("sources", cb.thermal.Names()))
+
+	// Setup correct fan unit, it's supervised afterwards so the fan unit can be swapped at runtime
+	uart, _ := fanPwm.(smartFanUnitUart)
+	factory := fanUnitFactory{
+		ProbeSmart: func(ctx context.Context) (bool, error) {
+			if uart != nil {
+				// The standard fan unit uses the UART RX pin as tach input
+				if err := uart.enableSmartFanUnitUart(false); err != nil {
+					return false, err
+				}
+			}
+			return SmartFanUnitPresent(ctx, smartFanUnitDev)
+		},
+		NewSmart: func() (FanUnit, error) {
+			if uart != nil {
+				// The standard fan unit uses the UART TX pin as PWM output
+				if err := uart.enableSmartFanUnitUart(true); err != nil {
+					return nil, err
+				}
+			}
+			return NewSmartFanUnit(smartFanUnitDev)
+		},
+		NewStandard: func() (FanUnit, error) {
+			// FAN PWM output for standard fan unit (GPIO 12)
+			if err := fanPwm.enableFanPwm(); err != nil {
+				return nil, err
+			}
+			return &standardFanUnitBcm2711{
+				GpioChip0:           cb.gpio,
+				DisableRPMreporting: !cb.opts.RpmReportingStandardFanUnit,
+				SetFanSpeedPwmFunc:  fanPwm.setFanSpeedPWM,
+				MinDutyPercent:      cb.opts.FanMinDutyPercent,
+				tach:                tachometer{pulsesPerRevolution: cb.opts.FanPulsesPerRevolution},
+			}, nil
+		},
+	}
+
+	log.FromContext(ctx).Info("detecting fan unit")
+	detectCtx, cancel := context.WithTimeout(ctx, fanUnitProbeTimeout) // temp events are sent every 2 seconds
+	defer cancel()
+
+	var initial FanUnit
+	if smartFanUnitPresent, err := SmartFanUnitPresent(detectCtx, smartFanUnitDev); err == nil && smartFanUnitPresent {
+		log.FromContext(ctx).Info("detected smart fan unit")
+		initial, err = factory.NewSmart()
+		if err != nil {
+			return err
+		}
+	} else {
+		log.FromContext(ctx).Info("no smart fan unit detected, assuming standard fan unit", zap.Error(err))
+		initial, err = factory.NewStandard()
+		if err != nil {
+			return err
+		}
+	}
+	cb.fanUnits = newFanUnitSupervisor(initial, factory)
+	cb.fanUnit = cb.fanUnits
+
+	return nil
+}
+
+// close releases the fan unit and all GPIO lines
+func (cb *computeBlade) close() error {
+	var errs []error
+	if cb.fanUnit != nil {
+		errs = append(errs, cb.fanUnit.Close())
+	}
+	for _, line := range []gpioLine{cb.edgeButtonLine, cb.poeLine, cb.stealthModeLine} {
+		if line != nil {
+			errs = append(errs, line.Close())
+		}
+	}
+	errs = append(errs, cb.gpio.Close())
+	return errors.Join(errs...)
+}
+
+func (cb *computeBlade) Run(parentCtx context.Context) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	group := errgroup.Group{}
+
+	group.Go(func() error {
+		defer cancel()
+		return cb.fanUnit.Run(ctx)
+	})
+
+	group.Go(func() error {
+		return cb.runFanUnitLed(ctx)
+	})
+
+	// Keep the metrics of all thermal sources current
+	group.Go(func() error {
+		ticker := time.NewTicker(thermalUpdateInterval)
+		defer ticker.Stop()
+		for {
+			cb.thermal.UpdateMetrics(ctx)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+
+	return group.Wait()
+}
+
+// setFanUnitLed queues the LED color of the fan unit, replacing a pending one as only the latest color matters
+func (cb *computeBlade) setFanUnitLed(color led.Color) {
+	for {
+		select {
+		case cb.fanUnitLedChan <- color:
+			return
+		default:
+		}
+		select {
+		case <-cb.fanUnitLedChan:
+		default:
+		}
+	}
+}
+
+// runFanUnitLed sends the queued LED colors to the fan unit, retrying failed ones unless a newer color is queued
+func (cb *computeBlade) runFanUnitLed(ctx context.Context) error {
+	var color led.Color
+	var retry <-chan time.Time
+	retries := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case color = <-cb.fanUnitLedChan:
+			retries = 0
+		case <-retry:
+			retries++
+		}
+		retry = nil
+
+		sendCtx, cancel := context.WithTimeout(ctx, fanUnitLedTimeout)
+		err := cb.fanUnit.SetLed(sendCtx, color)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		if retries >= fanUnitLedRetries {
+			log.FromContext(ctx).Error("Failed to set fan unit LED, giving up", zap.Error(err))
+			continue
+		}
+		log.FromContext(ctx).Warn("Failed to set fan unit LED, retrying", zap.Error(err))
+		retry = time.After(fanUnitLedRetryInterval)
+	}
+}
+
+func (cb *computeBlade) handleEdgeButtonEdge(evt gpiod.LineEvent) {
+	// Despite the debounce, we still get multiple events for a single button press
+	// -> This is an in-software debounce to ensure we only get one event per button press
+	select {
+	case cb.edgeButtonDebounceChan <- struct{}{}:
+		go func() {
+			// Manually debounce the button
+			<-cb.edgeButtonDebounceChan
+			time.Sleep(edgeButtonDebounceInterval)
+			edgeButtonEventCount.Inc()
+			close(cb.edgeButtonWatchChan)
+			cb.edgeButtonWatchChan = make(chan struct{})
+		}()
+	default:
+		// noop
+		return
+	}
+}
+
+// WaitForEdgeButtonPress blocks until the edge button has been pressed
+func (cb *computeBlade) WaitForEdgeButtonPress(parentCtx context.Context) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	fanUnitChan := make(chan struct{})
+	go func() {
+		err := cb.fanUnit.WaitForButtonPress(ctx)
+		if err != nil && err != context.Canceled {
+			log.FromContext(ctx).Error("failed to wait for button press", zap.Error(err))
+		} else {
+			close(fanUnitChan)
+		}
+	}()
+
+	// Either wait for the context to be cancelled or the edge button to be pressed
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-cb.edgeButtonWatchChan:
+		return nil
+	case <-fanUnitChan:
+		return nil
+	}
+}
+
+func (cb *computeBlade) GetFanRPM() (float64, error) {
+	rpm, err := cb.fanUnit.FanSpeedRPM(context.TODO())
+	return float64(rpm), err
+}
+
+func (cb *computeBlade) GetPowerStatus() (PowerStatus, error) {
+	// GPIO 23 is used for PoE detection
+	val, err := cb.poeLine.Value()
+	if err != nil {
+		return PowerPoeOrUsbC, err
+	}
+
+	status := PowerStatus(PowerPoeOrUsbC)
+	if val > 0 {
+		status = PowerPoe802at
+	}
+	setPowerStatusMetric(status)
+	return status, nil
+}
+
+func (cb *computeBlade) handlePoeEdge(evt gpiod.LineEvent) {
+	// Keep the metric current without polling
+	status := PowerStatus(PowerPoeOrUsbC)
+	if evt.Type == gpiod.LineEventRisingEdge {
+		status = PowerPoe802at
+	}
+	setPowerStatusMetric(status)
+
+	cb.poeMutex.Lock()
+	defer cb.poeMutex.Unlock()
+	close(cb.poeWatchChan)
+	cb.poeWatchChan = make(chan struct{})
+}
+
+// WaitForPowerStatusChange blocks until the PoE detection line differs from the last known power status and returns the new power status
+func (cb *computeBlade) WaitForPowerStatusChange(ctx context.Context, last PowerStatus) (PowerStatus, error) {
+	for {
+		// Take the channel before reading the line so that no edge after the read is missed
+		cb.poeMutex.Lock()
+		watchChan := cb.poeWatchChan
+		cb.poeMutex.Unlock()
+
+		status, err := cb.GetPowerStatus()
+		if err != nil || status != last {
+			return status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return PowerPoeOrUsbC, ctx.Err()
+		case <-watchChan:
+		}
+	}
+}
+
+func setPowerStatusMetric(status PowerStatus) {
+	if status == PowerPoe802at {
+		powerStatus.WithLabelValues(fmt.Sprint(PowerPoe802at)).Set(1)
+		powerStatus.WithLabelValues(fmt.Sprint(PowerPoeOrUsbC)).Set(0)
+		return
+	}
+	powerStatus.WithLabelValues(fmt.Sprint(PowerPoe802at)).Set(0)
+	powerStatus.WithLabelValues(fmt.Sprint(PowerPoeOrUsbC)).Set(1)
+}
+
+// SetFanSpeed sets the fanspeed of a blade in percent
+func (cb *computeBlade) SetFanSpeed(speed uint8) error {
+	fanTargetPercent.Set(float64(speed))
+	return cb.fanUnit.SetFanSpeedPercent(context.TODO(), speed)
+}
+
+func (cb *computeBlade) SetStealthMode(enable bool) error {
+	if enable {
+		stealthModeEnabled.Set(1)
+		return cb.stealthModeLine.SetValue(1)
+	} else {
+		stealthModeEnabled.Set(0)
+		return cb.stealthModeLine.SetValue(0)
+	}
+}
+
+// FanUnitKind returns the kind of the currently connected fan unit
+func (cb *computeBlade) FanUnitKind() FanUnitKind {
+	return cb.fanUnit.Kind()
+}
+
+// WaitForFanUnitChange blocks until the fan unit changes and returns the kind of the new fan unit
+func (cb *computeBlade) WaitForFanUnitChange(ctx context.Context) (FanUnitKind, error) {
+	if cb.fanUnits == nil {
+		<-ctx.Done()
+		return cb.FanUnitKind(), ctx.Err()
+	}
+	return cb.fanUnits.WaitForChange(ctx)
+}
+
+// FanUnitLinkStatus returns the health of the link to the smart fan unit
+func (cb *computeBlade) FanUnitLinkStatus() FanUnitLinkStatus {
+	if cb.fanUnits == nil {
+		return FanUnitLinkNone
+	}
+	return cb.fanUnits.LinkStatus()
+}
+
+// FanUnitInfo returns the firmware info of the smart fan unit
+func (cb *computeBlade) FanUnitInfo() FanUnitInfo {
+	if cb.fanUnits == nil {
+		return FanUnitInfo{}
+	}
+	return cb.fanUnits.Info()
+}
+
+// SetFanUnitCurve configures the fan curve of the smart fan unit, the standard fan unit ignores it
+func (cb *computeBlade) SetFanUnitCurve(curve smartfanunit.FanCurve) error {
+	if err := curve.Validate(); err != nil {
+		return err
+	}
+	if cb.fanUnits == nil {
+		return nil
+	}
+	return cb.fanUnits.SetFanCurve(context.TODO(), curve)
+}
+
+// SetFanUnitLUT configures the lookup table of the smart fan unit, the standard fan unit ignores it
+func (cb *computeBlade) SetFanUnitLUT(lut smartfanunit.FanLUT) error {
+	if err := lut.Validate(); err != nil {
+		return err
+	}
+	if cb.fanUnits == nil {
+		return nil
+	}
+	return cb.fanUnits.SetFanLUT(context.TODO(), lut)
+}
+
+// GetAirFlowTemperature returns the airflow temperature measured by the fan unit, the highest of all sensors
+func (cb *computeBlade) GetAirFlowTemperature() (float64, error) {
+	temps, err := cb.fanUnit.Temperatures(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+	return float64(maxTemperature(temps)), nil
+}
+
+// GetTemperature returns the current temperature of the SoC
+func (cb *computeBlade) GetTemperature() (float64, error) {
+	temp, err := cb.thermal.Temperature(context.TODO(), ThermalSourceSoc)
+	if err != nil {
+		return -1, err
+	}
+	socTemperature.Set(temp)
+
+	return temp, nil
+}
+
+// ThermalSources returns the registry of all thermal sources of the blade
+func (cb *computeBlade) ThermalSources() *ThermalRegistry {
+	return cb.thermal
+}
@@ -0,0 +1,180 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/warthog618/gpiod"
+)
+
+// registerWrite is a register write recorded by fakeRegisterBlock
+type registerWrite struct {
+	Reg   int
+	Value uint32
+}
+
+// fakeRegisterBlock is an in-memory register block recording all writes
+type fakeRegisterBlock struct {
+	mu     sync.Mutex
+	regs   []uint32
+	writes []registerWrite
+	closed bool
+}
+
+func newFakeRegisterBlock(size int) *fakeRegisterBlock {
+	return &fakeRegisterBlock{regs: make([]uint32, size/4)}
+}
+
+func (r *fakeRegisterBlock) read(reg int) uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.regs[reg]
+}
+
+func (r *fakeRegisterBlock) write(reg int, value uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.regs[reg] = value
+	r.writes = append(r.writes, registerWrite{Reg: reg, Value: value})
+}
+
+func (r *fakeRegisterBlock) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+// preset sets a register without recording a write, e.g. to simulate hardware status bits
+func (r *fakeRegisterBlock) preset(reg int, value uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.regs[reg] = value
+}
+
+// snapshot returns a copy of all registers
+func (r *fakeRegisterBlock) snapshot() []uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint32(nil), r.regs...)
+}
+
+// writesTo returns all values written to a register in order
+func (r *fakeRegisterBlock) writesTo(reg int) []uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var values []uint32
+	for _, w := range r.writes {
+		if w.Reg == reg {
+			values = append(values, w.Value)
+		}
+	}
+	return values
+}
+
+// resetWrites discards all recorded writes
+func (r *fakeRegisterBlock) resetWrites() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes = nil
+}
+
+// fakeGpioChip is an in-memory GPIO chip recording all requested lines
+type fakeGpioChip struct {
+	mu     sync.Mutex
+	lines  map[int]*fakeGpioLine
+	closed bool
+}
+
+func newFakeGpioChip() *fakeGpioChip {
+	return &fakeGpioChip{lines: make(map[int]*fakeGpioLine)}
+}
+
+func (c *fakeGpioChip) RequestLine(offset int, options ...gpiod.LineReqOption) (gpioLine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if line, ok := c.lines[offset]; ok && !line.isClosed() {
+		return nil, fmt.Errorf("line %d already requested", offset)
+	}
+
+	line := &fakeGpioLine{}
+	for _, option := range options {
+		if output, ok := option.(gpiod.OutputOption); ok {
+			line.output = true
+			if len(output) > 0 {
+				line.value = output[0]
+			}
+			line.writes = append(line.writes, line.value)
+		}
+	}
+	c.lines[offset] = line
+	return line, nil
+}
+
+func (c *fakeGpioChip) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// line returns a requested line, nil if it hasn't been requested
+func (c *fakeGpioChip) line(offset int) *fakeGpioLine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lines[offset]
+}
+
+// fakeGpioLine is an in-memory GPIO line recording all values set
+type fakeGpioLine struct {
+	mu     sync.Mutex
+	output bool
+	value  int
+	writes []int
+	closed bool
+}
+
+func (l *fakeGpioLine) Value() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.value, nil
+}
+
+func (l *fakeGpioLine) SetValue(value int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.output {
+		return fmt.Errorf("line is not an output")
+	}
+	l.value = value
+	l.writes = append(l.writes, value)
+	return nil
+}
+
+func (l *fakeGpioLine) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+// setInput sets the level of an input line
+func (l *fakeGpioLine) setInput(value int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.value = value
+}
+
+func (l *fakeGpioLine) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+func (l *fakeGpioLine) values() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]int(nil), l.writes...)
+}
@@ -0,0 +1,121 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"go.uber.org/zap"
+)
+
+const (
+	kernelDefaultPwmChip   = "pwmchip0"
+	kernelDefaultSpiDevice = "/dev/spidev0.0"
+)
+
+// kernelHal drives the fan PWM through the kernel PWM subsystem and the WS281x LEDs through SPI.
+// Unlike the devmem backend it doesn't require access to /dev/mem, but the PWM and SPI device tree overlays
+// have to be configured so the fan PWM output (GPIO 12) and the LED data line are driven by the kernel.
+type kernelHal struct {
+	*computeBlade
+
+	fanPwm *sysfsPwm
+
+	ledMutex sync.Mutex
+	spi      *spiWs281x
+	// Save LED colors so the pixels can be updated individually
+	leds [2]led.Color
+}
+
+// NewKernelHal creates a HAL using the kernel PWM and SPI subsystems instead of /dev/mem
+func NewKernelHal(ctx context.Context, opts ComputeBladeHalOpts, module ComputeModule) (ComputeBladeHal, error) {
+	if opts.Kernel.PwmChip == "" {
+		opts.Kernel.PwmChip = kernelDefaultPwmChip
+	}
+	if opts.Kernel.SpiDevice == "" {
+		opts.Kernel.SpiDevice = kernelDefaultSpiDevice
+	}
+
+	var (
+		gpio            gpioChip
+		smartFanUnitDev string
+		err             error
+	)
+	switch module {
+	case ComputeModuleCm4:
+		gpio, err = openGpioChip("gpiochip0")
+		smartFanUnitDev = bcm2711SmartFanUnitDev
+	case ComputeModuleCm5:
+		gpio, err = findGpioChip(bcm2712Rp1GpioChipLabel)
+		smartFanUnitDev = bcm2712SmartFanUnitDev
+	default:
+		return nil, fmt.Errorf("unsupported compute module")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	spi, err := openSpiWs281x(opts.Kernel.SpiDevice)
+	if err != nil {
+		gpio.Close()
+		return nil, err
+	}
+
+	k := &kernelHal{
+		computeBlade: newComputeBlade(opts, gpio),
+		spi:          spi,
+	}
+
+	computeModule.WithLabelValues(module.String()).Set(1)
+
+	log.FromContext(ctx).Info("starting hal setup", zap.String("hal", "kernel"), zap.String("module", module.String()))
+	if err := k.setup(ctx, smartFanUnitDev, k); err != nil {
+		return nil, errors.Join(err, k.Close())
+	}
+	return k, nil
+}
+
+// Close releases the GPIO lines and the SPI device
+func (k *kernelHal) Close() error {
+	return errors.Join(
+		k.computeBlade.close(),
+		k.spi.close(),
+	)
+}
+
+// enableFanPwm exports and enables the configured PWM channel
+func (k *kernelHal) enableFanPwm() error {
+	var err error
+	periodNs := uint64(time.Second) / uint64(k.opts.fanPwmFrequency())
+	k.fanPwm, err = newSysfsPwm(k.opts.SysfsRoot, k.opts.Kernel.PwmChip, k.opts.Kernel.PwmChannel, periodNs)
+	return err
+}
+
+// setFanSpeedPWM sets the duty cycle of the fan PWM channel
+func (k *kernelHal) setFanSpeedPWM(speed uint8) error {
+	return k.fanPwm.setDutyCyclePercent(speed)
+}
+
+func (k *kernelHal) SetLed(idx uint, color led.Color) error {
+	if idx >= 2 {
+		return fmt.Errorf("invalid led index %d, supported: [0, 1]", idx)
+	}
+
+	// Update the fan unit LED if the index is the same as the fan unit LED index, sent in the background
+	if idx == LedEdge {
+		k.setFanUnitLed(color)
+	}
+
+	k.ledMutex.Lock()
+	defer k.ledMutex.Unlock()
+
+	k.leds[idx] = color
+	ledColorChangeEventCount.Inc()
+	return k.spi.write(k.leds)
+}
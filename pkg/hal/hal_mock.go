@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
 )
 
 // fails if ComputeBladeHalMock does not implement ComputeBladeHal
@@ -45,6 +46,11 @@ func (m *ComputeBladeHalMock) GetPowerStatus() (PowerStatus, error) {
 	return args.Get(0).(PowerStatus), args.Error(1)
 }
 
+func (m *ComputeBladeHalMock) WaitForPowerStatusChange(ctx context.Context, last PowerStatus) (PowerStatus, error) {
+	args := m.Called(ctx, last)
+	return args.Get(0).(PowerStatus), args.Error(1)
+}
+
 func (m *ComputeBladeHalMock) WaitForEdgeButtonPress(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -59,3 +65,43 @@ func (m *ComputeBladeHalMock) GetTemperature() (float64, error) {
 	args := m.Called()
 	return args.Get(0).(float64), args.Error(1)
 }
+
+func (m *ComputeBladeHalMock) GetAirFlowTemperature() (float64, error) {
+	args := m.Called()
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *ComputeBladeHalMock) ThermalSources() *ThermalRegistry {
+	args := m.Called()
+	return args.Get(0).(*ThermalRegistry)
+}
+
+func (m *ComputeBladeHalMock) FanUnitKind() FanUnitKind {
+	args := m.Called()
+	return args.Get(0).(FanUnitKind)
+}
+
+func (m *ComputeBladeHalMock) WaitForFanUnitChange(ctx context.Context) (FanUnitKind, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(FanUnitKind), args.Error(1)
+}
+
+func (m *ComputeBladeHalMock) FanUnitLinkStatus() FanUnitLinkStatus {
+	args := m.Called()
+	return args.Get(0).(FanUnitLinkStatus)
+}
+
+func (m *ComputeBladeHalMock) FanUnitInfo() FanUnitInfo {
+	args := m.Called()
+	return args.Get(0).(FanUnitInfo)
+}
+
+func (m *ComputeBladeHalMock) SetFanUnitCurve(curve smartfanunit.FanCurve) error {
+	args := m.Called(curve)
+	return args.Error(0)
+}
+
+func (m *ComputeBladeHalMock) SetFanUnitLUT(lut smartfanunit.FanLUT) error {
+	args := m.Called(lut)
+	return args.Error(0)
+}
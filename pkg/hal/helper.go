@@ -1,9 +1,9 @@
 //go:build linux
+
 package hal
 
 import (
 	"os"
-	"reflect"
 	"syscall"
 	"unsafe"
 )
@@ -20,9 +20,6 @@ func mmap(file *os.File, base int64, lenght int) ([]uint32, []uint8, error) {
 		return nil, nil, err
 	}
 	// We'll have to work with 32 bit registers, so let's convert it.
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&mem8))
-	header.Len /= (32 / 8)
-	header.Cap /= (32 / 8)
-	mem32 := *(*[]uint32)(unsafe.Pointer(&header))
+	mem32 := unsafe.Slice((*uint32)(unsafe.Pointer(&mem8[0])), len(mem8)/4)
 	return mem32, mem8, nil
 }
@@ -22,11 +22,46 @@ var (
 		Name:      "soc_temperature",
 		Help:      "SoC temperature in °C",
 	})
+	thermalSourceTemperature = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "temperature",
+		Help:      "Temperature of a thermal source in °C",
+	}, []string{"source"})
+	nvmeTemperature = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "nvme_temperature",
+		Help:      "NVMe composite temperature in °C",
+	}, []string{"device"})
+	nvmePercentageUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "nvme_percentage_used",
+		Help:      "NVMe estimate of the used life in percent",
+	}, []string{"device"})
+	nvmeAvailableSpare = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "nvme_available_spare",
+		Help:      "NVMe remaining spare capacity in percent",
+	}, []string{"device"})
+	nvmeCriticalWarning = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "nvme_critical_warning",
+		Help:      "NVMe critical warning bitfield (0 if healthy)",
+	}, []string{"device"})
+	nvmeMediaErrors = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "nvme_media_errors",
+		Help:      "NVMe unrecovered data integrity errors",
+	}, []string{"device"})
 	airFlowTemperature = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: "computeblade",
 		Name:      "airflow_temperature",
 		Help:      "airflow temperature in °C",
 	})
+	fanUnitTemperature = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "fan_unit_temperature",
+		Help:      "Temperature of the fan unit sensors in °C",
+	}, []string{"sensor"})
 	computeModule = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "computeblade",
 		Name:      "compute_modul_present",
@@ -37,6 +72,17 @@ var (
 		Name:      "led_color_change_event_count",
 		Help:      "Led color change event_count",
 	})
+	ledFramesSkippedCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "computeblade",
+		Name:      "led_frames_skipped_count",
+		Help:      "LED updates that were not sent as the colors were unchanged or coalesced into a later frame",
+	})
+	fanPwmGlitchSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "computeblade",
+		Name:      "fan_pwm_glitch_seconds",
+		Help:      "Time the fan PWM signal is interrupted to send LED data",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 10),
+	})
 	powerStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "computeblade",
 		Name:      "power_status",
@@ -57,4 +103,54 @@ var (
 		Name:      "edge_button_event_count",
 		Help:      "Number of edge button presses",
 	})
+	smartFanUnitPacketsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_packets_received_count",
+		Help:      "Number of packets received from the smart fan unit",
+	}, []string{"command"})
+	smartFanUnitPacketsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_packets_sent_count",
+		Help:      "Number of packets sent to the smart fan unit",
+	}, []string{"command"})
+	smartFanUnitCommandTimeoutCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_command_timeout_count",
+		Help:      "Number of commands not acknowledged by the smart fan unit in time",
+	}, []string{"command"})
+	smartFanUnitChecksumMismatchCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_checksum_mismatch_count",
+		Help:      "Number of packets from the smart fan unit dropped due to a checksum mismatch",
+	})
+	smartFanUnitFramingErrorCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_framing_error_count",
+		Help:      "Number of invalid frames received from the smart fan unit",
+	})
+	smartFanUnitTelemetryAge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_telemetry_age_seconds",
+		Help:      "Time since the last telemetry packet has been received from the smart fan unit",
+	})
+	smartFanUnitInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_info",
+		Help:      "Firmware info of the smart fan unit",
+	}, []string{"protocol_version", "firmware_version", "capabilities"})
+	smartFanUnitLinkStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_link_status",
+		Help:      "Link status of the smart fan unit",
+	}, []string{"status"})
+	smartFanUnitBladeAlive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_blade_alive",
+		Help:      "Blades sending heartbeats to the smart fan unit, as reported by the fan unit",
+	}, []string{"side", "self"})
+	smartFanUnitBusRecoveries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "computeblade",
+		Name:      "smart_fan_unit_bus_recoveries",
+		Help:      "Recoveries of the I2C bus to the EMC2101 since the smart fan unit has been started, as reported by the fan unit",
+	}, []string{"result"})
 )
@@ -0,0 +1,252 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// NvmeSmartLogSize is the size of the SMART / health information log page (02h)
+	NvmeSmartLogSize = 512
+
+	nvmeKelvinOffset = 273.15
+)
+
+// NVMe critical warning bits of the SMART / health information log page
+const (
+	NvmeCriticalWarningSpare          = 1 << 0 // available spare below threshold
+	NvmeCriticalWarningTemperature    = 1 << 1 // temperature above/below threshold
+	NvmeCriticalWarningReliability    = 1 << 2 // reliability degraded due to media errors
+	NvmeCriticalWarningReadOnly       = 1 << 3 // media placed in read only mode
+	NvmeCriticalWarningVolatileBackup = 1 << 4 // volatile memory backup failed
+	NvmeCriticalWarningPmrReadOnly    = 1 << 5 // persistent memory region placed in read only mode
+)
+
+// ErrNvmeNotSupported is returned when NVMe health data cannot be read on this platform
+var ErrNvmeNotSupported = errors.New("nvme health monitoring not supported")
+
+// NvmeSmartLog is the parsed SMART / health information log page of an NVMe controller
+type NvmeSmartLog struct {
+	// CriticalWarning is a bitfield of NvmeCriticalWarning* flags
+	CriticalWarning uint8
+	// Temperature is the composite temperature in °C
+	Temperature float64
+	// AvailableSpare is the remaining spare capacity in percent
+	AvailableSpare uint8
+	// AvailableSpareThreshold is the spare capacity in percent below which a critical warning is raised
+	AvailableSpareThreshold uint8
+	// PercentageUsed is the vendor specific estimate of the used life in percent (may exceed 100)
+	PercentageUsed uint8
+	// PowerOnHours is the number of hours the controller has been powered on
+	PowerOnHours uint64
+	// UnsafeShutdowns is the number of unsafe shutdowns
+	UnsafeShutdowns uint64
+	// MediaErrors is the number of unrecovered data integrity errors
+	MediaErrors uint64
+	// SensorTemperatures are the temperatures of the implemented temperature sensors in °C
+	SensorTemperatures []float64
+}
+
+// ParseNvmeSmartLog parses the SMART / health information log page (02h)
+func ParseNvmeSmartLog(data []byte) (NvmeSmartLog, error) {
+	if len(data) < NvmeSmartLogSize {
+		return NvmeSmartLog{}, fmt.Errorf("invalid smart log size %d, expected %d", len(data), NvmeSmartLogSize)
+	}
+
+	smartLog := NvmeSmartLog{
+		CriticalWarning:         data[0],
+		Temperature:             kelvinToCelsius(binary.LittleEndian.Uint16(data[1:3])),
+		AvailableSpare:          data[3],
+		AvailableSpareThreshold: data[4],
+		PercentageUsed:          data[5],
+		PowerOnHours:            uint128ToUint64(data[128:144]),
+		UnsafeShutdowns:         uint128ToUint64(data[144:160]),
+		MediaErrors:             uint128ToUint64(data[160:176]),
+	}
+
+	// Temperature sensors 1-8, a value of 0 indicates the sensor is not implemented
+	for i := 0; i < 8; i++ {
+		raw := binary.LittleEndian.Uint16(data[200+2*i:])
+		if raw != 0 {
+			smartLog.SensorTemperatures = append(smartLog.SensorTemperatures, kelvinToCelsius(raw))
+		}
+	}
+
+	return smartLog, nil
+}
+
+func kelvinToCelsius(kelvin uint16) float64 {
+	return float64(kelvin) - nvmeKelvinOffset
+}
+
+// uint128ToUint64 converts a little endian 128 bit counter, saturating at the maximum uint64
+func uint128ToUint64(data []byte) uint64 {
+	if binary.LittleEndian.Uint64(data[8:16]) != 0 {
+		return math.MaxUint64
+	}
+	return binary.LittleEndian.Uint64(data[0:8])
+}
+
+// DiscoverNvmeDevices returns the names of all NVMe controllers (e.g. nvme0) listed under the given sysfs root (/sys if empty)
+func DiscoverNvmeDevices(sysfsRoot string) ([]string, error) {
+	if sysfsRoot == "" {
+		sysfsRoot = defaultSysfsRoot
+	}
+	paths, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "nvme", "nvme*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(paths, func(i, j int) bool { return naturalLess(paths[i], paths[j]) })
+
+	devices := make([]string, 0, len(paths))
+	for _, path := range paths {
+		devices = append(devices, filepath.Base(path))
+	}
+	return devices, nil
+}
+
+// NvmeMonitorOpts configures the NVMe health monitor
+type NvmeMonitorOpts struct {
+	// Devices are the NVMe controllers to monitor (e.g. nvme0), all controllers are discovered if empty
+	Devices []string
+	// Interval in which the health data is read
+	Interval time.Duration
+	// SysfsRoot is the root of the sysfs used to discover NVMe controllers (default /sys)
+	SysfsRoot string
+	// ReadSmartLog reads the raw SMART / health information log page of a device (default: ioctl on /dev/<device>)
+	ReadSmartLog func(device string) ([]byte, error)
+}
+
+// NvmeMonitor periodically reads the health data of NVMe controllers and exports it as metrics
+// and as thermal sources (<device>/composite)
+type NvmeMonitor struct {
+	opts NvmeMonitorOpts
+
+	mu   sync.RWMutex
+	logs map[string]nvmeSmartLogReading
+}
+
+type nvmeSmartLogReading struct {
+	log       NvmeSmartLog
+	timestamp time.Time
+}
+
+// NewNvmeMonitor creates an NVMe health monitor and registers the composite temperature of each device
+// as a thermal source in the given registry
+func NewNvmeMonitor(opts NvmeMonitorOpts, registry *ThermalRegistry) (*NvmeMonitor, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.ReadSmartLog == nil {
+		opts.ReadSmartLog = readNvmeSmartLog
+	}
+	if len(opts.Devices) == 0 {
+		devices, err := DiscoverNvmeDevices(opts.SysfsRoot)
+		if err != nil {
+			return nil, err
+		}
+		opts.Devices = devices
+	}
+
+	monitor := &NvmeMonitor{
+		opts: opts,
+		logs: make(map[string]nvmeSmartLogReading),
+	}
+	for _, device := range opts.Devices {
+		device := device
+		registry.Register(NewThermalSource(device+"/composite", func(_ context.Context) (float64, error) {
+			smartLog, err := monitor.SmartLog(device)
+			return smartLog.Temperature, err
+		}))
+	}
+	return monitor, nil
+}
+
+// Devices returns the monitored NVMe controllers
+func (m *NvmeMonitor) Devices() []string {
+	return m.opts.Devices
+}
+
+// SmartLog returns the most recent health data of a device.
+// An error is returned if the device has not been read yet or the last reading is outdated.
+func (m *NvmeMonitor) SmartLog(device string) (NvmeSmartLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	reading, ok := m.logs[device]
+	if !ok {
+		return NvmeSmartLog{}, fmt.Errorf("no health data available for %s", device)
+	}
+	if time.Since(reading.timestamp) > 3*m.opts.Interval {
+		return NvmeSmartLog{}, fmt.Errorf("health data of %s is outdated", device)
+	}
+	return reading.log, nil
+}
+
+// Update reads the health data of all devices once
+func (m *NvmeMonitor) Update(ctx context.Context) error {
+	var errs []error
+	for _, device := range m.opts.Devices {
+		data, err := m.opts.ReadSmartLog(device)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", device, err))
+			continue
+		}
+		smartLog, err := ParseNvmeSmartLog(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", device, err))
+			continue
+		}
+
+		m.mu.Lock()
+		m.logs[device] = nvmeSmartLogReading{log: smartLog, timestamp: time.Now()}
+		m.mu.Unlock()
+
+		nvmeTemperature.WithLabelValues(device).Set(smartLog.Temperature)
+		nvmePercentageUsed.WithLabelValues(device).Set(float64(smartLog.PercentageUsed))
+		nvmeAvailableSpare.WithLabelValues(device).Set(float64(smartLog.AvailableSpare))
+		nvmeCriticalWarning.WithLabelValues(device).Set(float64(smartLog.CriticalWarning))
+		nvmeMediaErrors.WithLabelValues(device).Set(float64(smartLog.MediaErrors))
+
+		if smartLog.CriticalWarning != 0 {
+			log.FromContext(ctx).Warn("NVMe critical warning",
+				zap.String("device", device),
+				zap.Uint8("critical_warning", smartLog.CriticalWarning),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run reads the health data periodically until the context is cancelled
+func (m *NvmeMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Update(ctx); err != nil {
+			log.FromContext(ctx).Error("Failed to read NVMe health data", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// nvmeDevicePath returns the character device of an NVMe controller
+func nvmeDevicePath(device string) string {
+	return filepath.Join("/dev", filepath.Base(device))
+}
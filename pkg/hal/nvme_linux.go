@@ -0,0 +1,67 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	nvmeIoctlAdminCmd     = 0xC0484E41 // _IOWR('N', 0x41, struct nvme_admin_cmd)
+	nvmeAdminGetLogPage   = 0x02
+	nvmeLogSmart          = 0x02
+	nvmeNsidAll           = 0xFFFFFFFF
+	nvmeAdminCmdTimeoutMs = 5000
+)
+
+// nvmeAdminCmd mirrors struct nvme_admin_cmd of linux/nvme_ioctl.h
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+// readNvmeSmartLog reads the SMART / health information log page using the NVMe admin ioctl
+func readNvmeSmartLog(device string) ([]byte, error) {
+	f, err := os.Open(nvmeDevicePath(device))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, NvmeSmartLogSize)
+	cmd := nvmeAdminCmd{
+		opcode:  nvmeAdminGetLogPage,
+		nsid:    nvmeNsidAll,
+		addr:    uint64(uintptr(unsafe.Pointer(&data[0]))),
+		dataLen: NvmeSmartLogSize,
+		// number of dwords (0's based) and log page identifier
+		cdw10:     (NvmeSmartLogSize/4-1)<<16 | nvmeLogSmart,
+		timeoutMs: nvmeAdminCmdTimeoutMs,
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	runtime.KeepAlive(data)
+	if errno != 0 {
+		return nil, errno
+	}
+	return data, nil
+}
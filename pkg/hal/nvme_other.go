@@ -0,0 +1,7 @@
+//go:build !linux && !tinygo
+
+package hal
+
+func readNvmeSmartLog(_ string) ([]byte, error) {
+	return nil, ErrNvmeNotSupported
+}
@@ -0,0 +1,94 @@
+//go:build !tinygo
+
+package hal_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal"
+)
+
+// smartLogFixture returns a SMART log page with a composite temperature of 50°C
+func smartLogFixture() []byte {
+	data := make([]byte, hal.NvmeSmartLogSize)
+	data[0] = hal.NvmeCriticalWarningTemperature | hal.NvmeCriticalWarningSpare
+	binary.LittleEndian.PutUint16(data[1:], 323)    // 49.85°C
+	data[3] = 95                                    // available spare
+	data[4] = 10                                    // available spare threshold
+	data[5] = 7                                     // percentage used
+	binary.LittleEndian.PutUint64(data[128:], 1234) // power on hours
+	binary.LittleEndian.PutUint64(data[144:], 3)    // unsafe shutdowns
+	binary.LittleEndian.PutUint64(data[160:], 1)    // media errors
+	data[168] = 1                                   // upper 64 bit of media errors set -> saturate
+	binary.LittleEndian.PutUint16(data[200:], 330)  // temperature sensor 1
+	binary.LittleEndian.PutUint16(data[204:], 340)  // temperature sensor 3 (2 not implemented)
+	return data
+}
+
+func TestParseNvmeSmartLog(t *testing.T) {
+	t.Parallel()
+
+	smartLog, err := hal.ParseNvmeSmartLog(smartLogFixture())
+	require.NoError(t, err)
+
+	assert.Equal(t, uint8(hal.NvmeCriticalWarningTemperature|hal.NvmeCriticalWarningSpare), smartLog.CriticalWarning)
+	assert.InDelta(t, 49.85, smartLog.Temperature, 0.001)
+	assert.Equal(t, uint8(95), smartLog.AvailableSpare)
+	assert.Equal(t, uint8(10), smartLog.AvailableSpareThreshold)
+	assert.Equal(t, uint8(7), smartLog.PercentageUsed)
+	assert.Equal(t, uint64(1234), smartLog.PowerOnHours)
+	assert.Equal(t, uint64(3), smartLog.UnsafeShutdowns)
+	assert.Equal(t, ^uint64(0), smartLog.MediaErrors)
+	require.Len(t, smartLog.SensorTemperatures, 2)
+	assert.InDelta(t, 56.85, smartLog.SensorTemperatures[0], 0.001)
+	assert.InDelta(t, 66.85, smartLog.SensorTemperatures[1], 0.001)
+
+	_, err = hal.ParseNvmeSmartLog(make([]byte, 64))
+	assert.Error(t, err)
+}
+
+func TestNvmeMonitor(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeSysfsFile(t, root, "class/nvme/nvme10/model", "fast ssd")
+	writeSysfsFile(t, root, "class/nvme/nvme2/model", "slow ssd")
+
+	failing := true
+	registry := hal.NewThermalRegistry()
+	monitor, err := hal.NewNvmeMonitor(hal.NvmeMonitorOpts{
+		Interval:  time.Hour,
+		SysfsRoot: root,
+		ReadSmartLog: func(device string) ([]byte, error) {
+			if device == "nvme10" && failing {
+				return nil, errors.New("permission denied")
+			}
+			return smartLogFixture(), nil
+		},
+	}, registry)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nvme2", "nvme10"}, monitor.Devices())
+	assert.Equal(t, []string{"nvme10/composite", "nvme2/composite"}, registry.Names())
+
+	// No data has been read yet
+	_, err = registry.Temperature(context.Background(), "nvme2/composite")
+	assert.Error(t, err)
+
+	// Failing devices are reported but don't affect the others
+	assert.Error(t, monitor.Update(context.Background()))
+	temp, err := registry.Temperature(context.Background(), "nvme2/composite", "nvme10/composite")
+	assert.NoError(t, err)
+	assert.InDelta(t, 49.85, temp, 0.001)
+
+	failing = false
+	assert.NoError(t, monitor.Update(context.Background()))
+	smartLog, err := monitor.SmartLog("nvme10")
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(7), smartLog.PercentageUsed)
+}
@@ -0,0 +1,70 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// sysfsPwmExportTimeout is the time to wait for the kernel (and udev) to set up an exported PWM channel
+const sysfsPwmExportTimeout = time.Second
+
+// sysfsPwm is a PWM channel controlled through the kernel PWM subsystem (/sys/class/pwm)
+type sysfsPwm struct {
+	path     string
+	periodNs uint64
+}
+
+// newSysfsPwm exports (if required) and enables a PWM channel with the given period
+func newSysfsPwm(sysfsRoot string, chip string, channel int, periodNs uint64) (*sysfsPwm, error) {
+	if sysfsRoot == "" {
+		sysfsRoot = defaultSysfsRoot
+	}
+	chipPath := filepath.Join(sysfsRoot, "class", "pwm", chip)
+	path := filepath.Join(chipPath, fmt.Sprintf("pwm%d", channel))
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.WriteFile(filepath.Join(chipPath, "export"), []byte(strconv.Itoa(channel)), 0); err != nil {
+			return nil, fmt.Errorf("failed to export pwm channel %d of %s: %w", channel, chip, err)
+		}
+		deadline := time.Now().Add(sysfsPwmExportTimeout)
+		for {
+			if _, err := os.Stat(filepath.Join(path, "enable")); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("pwm channel %d of %s did not appear after export", channel, chip)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	pwm := &sysfsPwm{path: path, periodNs: periodNs}
+	// The duty cycle must never exceed the period, so reset it before changing the period
+	if err := pwm.write("duty_cycle", 0); err != nil {
+		return nil, err
+	}
+	if err := pwm.write("period", periodNs); err != nil {
+		return nil, err
+	}
+	if err := pwm.write("enable", 1); err != nil {
+		return nil, err
+	}
+	return pwm, nil
+}
+
+// setDutyCyclePercent sets the duty cycle in percent of the period
+func (p *sysfsPwm) setDutyCyclePercent(percent uint8) error {
+	if percent > 100 {
+		percent = 100
+	}
+	return p.write("duty_cycle", p.periodNs*uint64(percent)/100)
+}
+
+func (p *sysfsPwm) write(attribute string, value uint64) error {
+	return os.WriteFile(filepath.Join(p.path, attribute), []byte(strconv.FormatUint(value, 10)), 0)
+}
@@ -0,0 +1,60 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAttribute(t *testing.T, path string) string {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestSysfsPwm(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	chip := filepath.Join(root, "class", "pwm", "pwmchip2")
+	channel := filepath.Join(chip, "pwm1")
+	require.NoError(t, os.MkdirAll(chip, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(chip, "export"), nil, 0o644))
+
+	// Emulate the kernel creating the channel once it has been exported
+	go func() {
+		for {
+			if raw, err := os.ReadFile(filepath.Join(chip, "export")); err == nil && string(raw) == "1" {
+				_ = os.MkdirAll(channel, 0o755)
+				_ = os.WriteFile(filepath.Join(channel, "enable"), []byte("0"), 0o644)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	pwm, err := newSysfsPwm(root, "pwmchip2", 1, 40000)
+	require.NoError(t, err)
+	assert.Equal(t, "40000", readAttribute(t, filepath.Join(channel, "period")))
+	assert.Equal(t, "0", readAttribute(t, filepath.Join(channel, "duty_cycle")))
+	assert.Equal(t, "1", readAttribute(t, filepath.Join(channel, "enable")))
+
+	require.NoError(t, pwm.setDutyCyclePercent(40))
+	assert.Equal(t, "16000", readAttribute(t, filepath.Join(channel, "duty_cycle")))
+	require.NoError(t, pwm.setDutyCyclePercent(200))
+	assert.Equal(t, "40000", readAttribute(t, filepath.Join(channel, "duty_cycle")))
+}
+
+func TestSysfsPwm_MissingChip(t *testing.T) {
+	t.Parallel()
+
+	_, err := newSysfsPwm(t.TempDir(), "pwmchip0", 0, 40000)
+	assert.Error(t, err)
+}
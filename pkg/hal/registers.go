@@ -0,0 +1,42 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"os"
+	"syscall"
+)
+
+// registerBlock is a block of 32 bit peripheral registers addressed by their word index
+type registerBlock interface {
+	read(reg int) uint32
+	write(reg int, value uint32)
+	close() error
+}
+
+// mmapRegisterBlock is a register block mapped from /dev/mem
+type mmapRegisterBlock struct {
+	mem8 []uint8
+	mem  []uint32
+}
+
+// mmapRegisters maps the registers at the given physical address
+func mmapRegisters(devmem *os.File, base int64, length int) (*mmapRegisterBlock, error) {
+	mem, mem8, err := mmap(devmem, base, length)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapRegisterBlock{mem8: mem8, mem: mem}, nil
+}
+
+func (r *mmapRegisterBlock) read(reg int) uint32 {
+	return r.mem[reg]
+}
+
+func (r *mmapRegisterBlock) write(reg int, value uint32) {
+	r.mem[reg] = value
+}
+
+func (r *mmapRegisterBlock) close() error {
+	return syscall.Munmap(r.mem8)
+}
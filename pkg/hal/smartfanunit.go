@@ -5,8 +5,11 @@ package hal
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/eventbus"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
@@ -62,44 +65,154 @@ func NewSmartFanUnit(portName string) (FanUnit, error) {
 		return nil, err
 	}
 
-	return &smartFanUnit{
+	fuc := &smartFanUnit{
 		rwc: rwc,
 		eb:  eventbus.New(),
-	}, nil
+	}
+	// Grace period until the first packet is received
+	fuc.lastTelemetry.Store(time.Now().UnixNano())
+	return fuc, nil
 }
 
-var ErrCommunicationFailed = errors.New("communication failed")
+var (
+	ErrCommunicationFailed = errors.New("communication failed")
+	ErrCommandRejected     = errors.New("command rejected by fan unit")
+)
 
 const (
 	inboundTopic  = "smartfanunit:inbound"
 	outboundTopic = "smartfanunit:outbound"
 )
 
+const (
+	// smartFanUnitLinkDegradedTimeout is the time without telemetry after which the link is degraded,
+	// the smart fan unit sends notifications every 2 seconds
+	smartFanUnitLinkDegradedTimeout = 5 * time.Second
+	// smartFanUnitLinkErrorWindow is the time the link is considered degraded after a protocol error
+	smartFanUnitLinkErrorWindow = time.Minute
+	// smartFanUnitMetricsInterval is the interval in which the link metrics are updated
+	smartFanUnitMetricsInterval = time.Second
+
+	// smartFanUnitAckTimeout is the time to wait for the acknowledgement of a command
+	smartFanUnitAckTimeout = 100 * time.Millisecond
+	// smartFanUnitCommandRetries is the number of retries of unacknowledged commands
+	smartFanUnitCommandRetries = 3
+	// smartFanUnitLegacyThreshold is the number of unacknowledged commands after which the firmware is
+	// considered to not support acknowledgements
+	smartFanUnitLegacyThreshold = 3
+
+	// smartFanUnitHelloTimeout is the time to wait for the response to a hello
+	smartFanUnitHelloTimeout = 500 * time.Millisecond
+	// smartFanUnitHelloAttempts is the number of hellos sent before assuming firmware without version handshake
+	smartFanUnitHelloAttempts = 3
+
+	// smartFanUnitHeartbeatInterval is the interval in which heartbeats are sent, the firmware expires the fan speed
+	// request of blades without heartbeats
+	smartFanUnitHeartbeatInterval = 2 * time.Second
+)
+
+// smartFanUnitAckMode is the support of command acknowledgements by the smart fan unit firmware
+type smartFanUnitAckMode int32
+
+const (
+	// smartFanUnitAckUnknown means no acknowledgement has been received yet
+	smartFanUnitAckUnknown smartFanUnitAckMode = iota
+	// smartFanUnitAckSupported means the firmware acknowledges commands, unacknowledged commands are retried
+	smartFanUnitAckSupported
+	// smartFanUnitAckUnsupported means the firmware doesn't acknowledge commands, they're sent fire-and-forget
+	smartFanUnitAckUnsupported
+)
+
 type smartFanUnit struct {
 	rwc io.ReadWriteCloser
 	mu  sync.Mutex // write mutex
+	// cmdMu serializes commands, so only a single command awaits an acknowledgement
+	cmdMu sync.Mutex
+
+	ackMode atomic.Int32
+	// unackedCommands is the number of consecutive unacknowledged commands while the ack mode is unknown
+	unackedCommands int
+	sequence        atomic.Uint32
 
-	speed   smartfanunit.FanSpeedRPMPacket
-	airflow smartfanunit.AirFlowTemperaturePacket
+	infoMu sync.Mutex
+	info   FanUnitInfo
+	// fanCurve is applied by the firmware if the blades stop sending fan speed requests, pushed after every hello
+	fanCurve *smartfanunit.FanCurve
+	// fanLUT is programmed into the EMC2101 by the firmware, pushed after every hello
+	fanLUT *smartfanunit.FanLUT
+
+	speed smartfanunit.FanSpeedRPMPacket
+
+	// temperaturesMu guards temperatures, the last temperature reported for each sensor
+	temperaturesMu sync.Mutex
+	temperatures   map[string]float32
 
 	eb eventbus.EventBus
+
+	// lastTelemetry is the time the last packet has been received (unix nanoseconds)
+	lastTelemetry atomic.Int64
+	// lastProtocolError is the time the last invalid packet has been received (unix nanoseconds)
+	lastProtocolError atomic.Int64
 }
 
 func (fuc *smartFanUnit) Kind() FanUnitKind {
 	return FanUnitKindSmart
 }
 
+// LastTelemetry returns the time the last packet has been received from the smart fan unit
+func (fuc *smartFanUnit) LastTelemetry() time.Time {
+	return time.Unix(0, fuc.lastTelemetry.Load())
+}
+
+// LinkStatus returns the health of the link to the smart fan unit
+func (fuc *smartFanUnit) LinkStatus() FanUnitLinkStatus {
+	return fuc.linkStatus(time.Now())
+}
+
+func (fuc *smartFanUnit) linkStatus(now time.Time) FanUnitLinkStatus {
+	telemetryAge := now.Sub(fuc.LastTelemetry())
+	switch {
+	case telemetryAge > fanUnitTelemetryTimeout:
+		return FanUnitLinkLost
+	case telemetryAge > smartFanUnitLinkDegradedTimeout:
+		return FanUnitLinkDegraded
+	case now.Sub(time.Unix(0, fuc.lastProtocolError.Load())) < smartFanUnitLinkErrorWindow:
+		return FanUnitLinkDegraded
+	default:
+		return FanUnitLinkOk
+	}
+}
+
+// recordReadError accounts a failed read, returns true for protocol errors the read loop can recover from
+func (fuc *smartFanUnit) recordReadError(err error) bool {
+	switch {
+	case errors.Is(err, proto.ErrChecksumMismatch):
+		smartFanUnitChecksumMismatchCount.Inc()
+	case errors.Is(err, proto.ErrInvalidFramingByte), errors.Is(err, proto.ErrFrameOverflow):
+		smartFanUnitFramingErrorCount.Inc()
+	default:
+		return false
+	}
+	fuc.lastProtocolError.Store(time.Now().UnixNano())
+	return true
+}
+
 // Run the client with event loop
 func (fuc *smartFanUnit) Run(parentCtx context.Context) error {
-	fanUnit.WithLabelValues("smart").Set(1)
-
 	ctx, cancel := context.WithCancelCause(parentCtx)
 	defer cancel(nil)
 
 	wg := errgroup.Group{}
 
+	// Subscribe before the handshake, so the response isn't missed
+	infoSub := fuc.eb.Subscribe(inboundTopic, 2, func(pktAny any) bool {
+		return smartfanunit.MatchCmd(smartfanunit.NotifyHello)(pktAny) ||
+			smartfanunit.MatchCmd(smartfanunit.NotifyFirmwareVersion)(pktAny)
+	})
+
 	// Start read loop
 	wg.Go(func() error {
+		dec := proto.NewDecoder(fuc.rwc)
 		for {
 			select {
 			case <-ctx.Done():
@@ -107,15 +220,120 @@ func (fuc *smartFanUnit) Run(parentCtx context.Context) error {
 			default:
 			}
 
-			pkt, err := proto.ReadPacket(ctx, fuc.rwc)
+			pkt, err := dec.ReadPacket(ctx)
 			if err != nil {
-				log.FromContext(ctx).Error("Failed to read packet from serial port", zap.Error(err))
+				if fuc.recordReadError(err) {
+					log.FromContext(ctx).Debug("Received invalid packet from smart fan unit", zap.Error(err))
+				} else {
+					log.FromContext(ctx).Error("Failed to read packet from serial port", zap.Error(err))
+				}
 				continue
 			}
+			fuc.lastTelemetry.Store(time.Now().UnixNano())
+			if pkt.Command == smartfanunit.NotifyAck || pkt.Command == smartfanunit.NotifyNack {
+				// Firmware might have been updated since falling back to fire-and-forget
+				fuc.ackMode.Store(int32(smartFanUnitAckSupported))
+			}
+			smartFanUnitPacketsReceived.WithLabelValues(smartfanunit.CommandName(pkt.Command)).Inc()
 			fuc.eb.Publish(inboundTopic, pkt)
 		}
 	})
 
+	// Discover the firmware version and capabilities
+	wg.Go(func() error {
+		fuc.handshake(ctx)
+		return nil
+	})
+
+	// Handle hello and firmware version notifications, also sent unsolicited when the fan unit starts
+	wg.Go(func() error {
+		defer infoSub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case pktAny := <-infoSub.C():
+				pkt := pktAny.(proto.Packet)
+				fuc.handleInfoPacket(ctx, pkt)
+				if pkt.Command == smartfanunit.NotifyHello {
+					// The fan unit might have been restarted and lost the fan curve and lookup table
+					if err := fuc.pushFanCurve(ctx); err != nil {
+						log.FromContext(ctx).Warn("Failed to push fan curve to smart fan unit", zap.Error(err))
+					}
+					if err := fuc.pushFanLUT(ctx); err != nil {
+						log.FromContext(ctx).Warn("Failed to push fan lookup table to smart fan unit", zap.Error(err))
+					}
+				}
+			}
+		}
+	})
+
+	// Send heartbeats, so the fan unit keeps the fan speed request of this blade
+	wg.Go(func() error {
+		ticker := time.NewTicker(smartFanUnitHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			if !fuc.Info().Capabilities.Has(smartfanunit.CapabilityHeartbeat) {
+				continue
+			}
+			if err := fuc.write(ctx, &smartfanunit.HeartbeatPacket{}); err != nil {
+				log.FromContext(ctx).Warn("Failed to send heartbeat to smart fan unit", zap.Error(err))
+			}
+		}
+	})
+
+	// Track the blades reported alive by the fan unit
+	wg.Go(func() error {
+		sub := fuc.eb.Subscribe(inboundTopic, 1, smartfanunit.MatchCmd(smartfanunit.NotifyBladeStatus))
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case pktAny := <-sub.C():
+				var status smartfanunit.BladeStatusPacket
+				_ = status.FromPacket(pktAny.(proto.Packet))
+				fuc.setBladeStatus(ctx, status.Status)
+			}
+		}
+	})
+
+	// Track the I2C bus recoveries reported by the fan unit
+	wg.Go(func() error {
+		sub := fuc.eb.Subscribe(inboundTopic, 1, smartfanunit.MatchCmd(smartfanunit.NotifyBusRecovery))
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case pktAny := <-sub.C():
+				var recovery smartfanunit.BusRecoveryPacket
+				_ = recovery.FromPacket(pktAny.(proto.Packet))
+				fuc.setBusRecovery(ctx, recovery.Stats)
+			}
+		}
+	})
+
+	// Update link metrics
+	wg.Go(func() error {
+		ticker := time.NewTicker(smartFanUnitMetricsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				smartFanUnitTelemetryAge.Set(time.Since(fuc.LastTelemetry()).Seconds())
+				setFanUnitLinkMetric(fuc.LinkStatus())
+			}
+		}
+	})
+
 	// Subscribe to fan speed updates
 	wg.Go(func() error {
 		sub := fuc.eb.Subscribe(inboundTopic, 1, smartfanunit.MatchCmd(smartfanunit.NotifyFanSpeedRPM))
@@ -134,9 +352,13 @@ func (fuc *smartFanUnit) Run(parentCtx context.Context) error {
 		}
 	})
 
-	// Subscribe to air flow temperature updates
+	// Subscribe to temperature updates
 	wg.Go(func() error {
-		sub := fuc.eb.Subscribe(inboundTopic, 1, smartfanunit.MatchCmd(smartfanunit.NotifyAirFlowTemperature))
+		sub := fuc.eb.Subscribe(inboundTopic, 4, func(pktAny any) bool {
+			return smartfanunit.MatchCmd(smartfanunit.NotifyInternalTemperature)(pktAny) ||
+				smartfanunit.MatchCmd(smartfanunit.NotifyExternalTemperature)(pktAny) ||
+				smartfanunit.MatchCmd(smartfanunit.NotifyAirFlowTemperature)(pktAny)
+		})
 		defer sub.Unsubscribe()
 		for {
 			select {
@@ -144,10 +366,19 @@ func (fuc *smartFanUnit) Run(parentCtx context.Context) error {
 				return nil
 			case pktAny := <-sub.C():
 				rawPkt := pktAny.(proto.Packet)
-				if err := fuc.airflow.FromPacket(rawPkt); err != nil && err != proto.ErrChecksumMismatch {
+				if rawPkt.Command == smartfanunit.NotifyAirFlowTemperature {
+					var airflow smartfanunit.AirFlowTemperaturePacket
+					if err := airflow.FromPacket(rawPkt); err != nil && err != proto.ErrChecksumMismatch {
+						return err
+					}
+					fuc.setAirFlowTemperature(airflow.Temperature)
+					continue
+				}
+				var temperature smartfanunit.SensorTemperaturePacket
+				if err := temperature.FromPacket(rawPkt); err != nil && err != proto.ErrChecksumMismatch {
 					return err
 				}
-				airFlowTemperature.Set(float64(fuc.airflow.Temperature))
+				fuc.setSensorTemperature(temperature.Sensor.String(), temperature.Temperature)
 			}
 		}
 	})
@@ -158,17 +389,248 @@ func (fuc *smartFanUnit) Run(parentCtx context.Context) error {
 func (fuc *smartFanUnit) write(ctx context.Context, pktGen smartfanunit.PacketGenerator) error {
 	fuc.mu.Lock()
 	defer fuc.mu.Unlock()
-	return proto.WritePacket(ctx, fuc.rwc, pktGen.Packet())
+	pkt := pktGen.Packet()
+	write := proto.WritePacket
+	if pkt.Payload != nil {
+		write = proto.WritePacketV2
+	}
+	if err := write(ctx, fuc.rwc, pkt); err != nil {
+		return err
+	}
+	smartFanUnitPacketsSent.WithLabelValues(smartfanunit.CommandName(pkt.Command)).Inc()
+	return nil
+}
+
+// handshake sends hellos until the smart fan unit responds.
+// Firmware without version handshake never responds, its capabilities are discovered on use instead.
+func (fuc *smartFanUnit) handshake(ctx context.Context) {
+	sub := fuc.eb.Subscribe(inboundTopic, 1, smartfanunit.MatchCmd(smartfanunit.NotifyHello))
+	defer sub.Unsubscribe()
+
+	for attempt := 0; attempt < smartFanUnitHelloAttempts; attempt++ {
+		if err := fuc.write(ctx, &smartfanunit.HelloPacket{ProtocolVersion: smartfanunit.ProtocolVersion}); err != nil {
+			log.FromContext(ctx).Warn("Failed to send hello to smart fan unit", zap.Error(err))
+			return
+		}
+
+		timer := time.NewTimer(smartFanUnitHelloTimeout)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-sub.C():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	log.FromContext(ctx).Info("Smart fan unit doesn't support the version handshake, assuming protocol version 0")
+}
+
+// handleInfoPacket updates the firmware info from hello and firmware version notifications
+func (fuc *smartFanUnit) handleInfoPacket(ctx context.Context, pkt proto.Packet) {
+	fuc.infoMu.Lock()
+	switch pkt.Command {
+	case smartfanunit.NotifyHello:
+		var hello smartfanunit.HelloNotifyPacket
+		_ = hello.FromPacket(pkt)
+		fuc.info.ProtocolVersion = hello.ProtocolVersion
+		fuc.info.Capabilities = hello.Capabilities
+
+		// Firmware announcing its capabilities doesn't need to be probed for acknowledgements
+		mode := smartFanUnitAckUnsupported
+		if hello.Capabilities.Has(smartfanunit.CapabilityAck) {
+			mode = smartFanUnitAckSupported
+		}
+		fuc.ackMode.Store(int32(mode))
+	case smartfanunit.NotifyFirmwareVersion:
+		var version smartfanunit.FirmwareVersionPacket
+		_ = version.FromPacket(pkt)
+		fuc.info.FirmwareVersion = version.Version.String()
+	}
+	info := fuc.info
+	fuc.infoMu.Unlock()
+
+	setFanUnitInfoMetric(info)
+	log.FromContext(ctx).Debug("Updated smart fan unit info",
+		zap.Uint8("protocolVersion", info.ProtocolVersion),
+		zap.String("firmwareVersion", info.FirmwareVersion),
+		zap.Strings("capabilities", info.Capabilities.Names()),
+	)
+}
+
+// setBladeStatus updates the status of the blades reported by the fan unit
+func (fuc *smartFanUnit) setBladeStatus(ctx context.Context, status smartfanunit.BladeStatus) {
+	fuc.infoMu.Lock()
+	previous := fuc.info.BladeStatus
+	fuc.info.BladeStatus = &status
+	fuc.infoMu.Unlock()
+
+	setFanUnitBladeAliveMetric(status)
+	if previous == nil || *previous != status {
+		log.FromContext(ctx).Info("Smart fan unit reported blade status",
+			zap.Stringer("side", status.Side),
+			zap.Bool("leftAlive", status.LeftAlive),
+			zap.Bool("rightAlive", status.RightAlive),
+		)
+	}
+}
+
+// setBusRecovery updates the I2C bus recoveries reported by the fan unit.
+// Failed attempts are reported without a recovery if the fan unit is about to reset.
+func (fuc *smartFanUnit) setBusRecovery(ctx context.Context, stats smartfanunit.BusRecoveryStats) {
+	fuc.infoMu.Lock()
+	prev := fuc.info.BusRecovery
+	fuc.info.BusRecovery = stats
+	fuc.infoMu.Unlock()
+
+	setFanUnitBusRecoveryMetric(stats)
+	fields := []zap.Field{zap.Uint16("recoveries", stats.Recoveries), zap.Uint8("failedAttempts", stats.FailedAttempts)}
+	switch {
+	case stats.Recoveries != prev.Recoveries:
+		log.FromContext(ctx).Warn("Smart fan unit recovered its I2C bus", fields...)
+	case stats.FailedAttempts != prev.FailedAttempts:
+		log.FromContext(ctx).Error("Smart fan unit failed to recover its I2C bus", fields...)
+	}
+}
+
+// Info returns the protocol version, firmware version and capabilities of the smart fan unit
+func (fuc *smartFanUnit) Info() FanUnitInfo {
+	fuc.infoMu.Lock()
+	defer fuc.infoMu.Unlock()
+	return fuc.info
+}
+
+// command sends a command and waits for its acknowledgement, retrying if it isn't acknowledged in time.
+// Firmware without acknowledgements is detected after a few unacknowledged commands, commands are sent once afterward.
+func (fuc *smartFanUnit) command(ctx context.Context, pktGen smartfanunit.PacketGenerator) error {
+	fuc.cmdMu.Lock()
+	defer fuc.cmdMu.Unlock()
+
+	mode := smartFanUnitAckMode(fuc.ackMode.Load())
+	if mode == smartFanUnitAckUnsupported {
+		return fuc.write(ctx, pktGen)
+	}
+
+	pkt := pktGen.Packet()
+	sequence := smartfanunit.Sequence(pkt)
+	sub := fuc.eb.Subscribe(inboundTopic, 1, func(pktAny any) bool {
+		reply, ok := pktAny.(proto.Packet)
+		if !ok || (reply.Command != smartfanunit.NotifyAck && reply.Command != smartfanunit.NotifyNack) {
+			return false
+		}
+		return proto.Command(reply.Data[0]) == pkt.Command && reply.Data[1] == sequence
+	})
+	defer sub.Unsubscribe()
+
+	attempts := 1
+	if mode == smartFanUnitAckSupported {
+		attempts += smartFanUnitCommandRetries
+	}
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err := fuc.write(ctx, pktGen); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(smartFanUnitAckTimeout)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			smartFanUnitCommandTimeoutCount.WithLabelValues(smartfanunit.CommandName(pkt.Command)).Inc()
+			continue
+		case replyAny := <-sub.C():
+			timer.Stop()
+			fuc.unackedCommands = 0
+			reply := replyAny.(proto.Packet)
+			if reply.Command == smartfanunit.NotifyNack {
+				var nack smartfanunit.NackPacket
+				_ = nack.FromPacket(reply)
+				return fmt.Errorf("%w: %s", ErrCommandRejected, nack.Reason)
+			}
+			return nil
+		}
+	}
+
+	if mode == smartFanUnitAckUnknown {
+		// Firmware without acknowledgements, the command has been sent anyway
+		fuc.unackedCommands++
+		if fuc.unackedCommands >= smartFanUnitLegacyThreshold {
+			log.FromContext(ctx).Info("Smart fan unit doesn't acknowledge commands, disabling retries")
+			fuc.ackMode.CompareAndSwap(int32(smartFanUnitAckUnknown), int32(smartFanUnitAckUnsupported))
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s not acknowledged", ErrCommunicationFailed, smartfanunit.CommandName(pkt.Command))
+}
+
+// nextSequence returns the sequence number of the next command
+func (fuc *smartFanUnit) nextSequence() uint8 {
+	return uint8(fuc.sequence.Add(1))
 }
 
 // SetFanSpeedPercent sets the fan speed in percent.
 func (fuc *smartFanUnit) SetFanSpeedPercent(ctx context.Context, percent uint8) error {
-	return fuc.write(ctx, &smartfanunit.SetFanSpeedPercentPacket{Percent: percent})
+	return fuc.command(ctx, &smartfanunit.SetFanSpeedPercentPacket{Percent: percent, Sequence: fuc.nextSequence()})
 }
 
 // SetLed sets the LED color.
+// The color is sent with a sequence number if supported, so repeated colors aren't mistaken for each other's acknowledgements.
 func (fuc *smartFanUnit) SetLed(ctx context.Context, color led.Color) error {
-	return fuc.write(ctx, &smartfanunit.SetLEDPacket{Color: color})
+	pkt := &smartfanunit.SetLEDPacket{Color: color}
+	if fuc.Info().Capabilities.Has(smartfanunit.CapabilityLEDSequence | smartfanunit.CapabilityFrameV2) {
+		pkt.Sequence, pkt.HasSequence = fuc.nextSequence(), true
+	}
+	return fuc.command(ctx, pkt)
+}
+
+// SetFanCurve configures the fan curve applied by the firmware once the blades stop sending fan speed requests.
+// The curve is kept and pushed as soon as the fan unit announces support for fan curves.
+func (fuc *smartFanUnit) SetFanCurve(ctx context.Context, curve smartfanunit.FanCurve) error {
+	if err := curve.Validate(); err != nil {
+		return err
+	}
+	fuc.infoMu.Lock()
+	fuc.fanCurve = &curve
+	fuc.infoMu.Unlock()
+	return fuc.pushFanCurve(ctx)
+}
+
+// pushFanCurve sends the fan curve if one is set and the firmware supports it
+func (fuc *smartFanUnit) pushFanCurve(ctx context.Context) error {
+	fuc.infoMu.Lock()
+	curve, capabilities := fuc.fanCurve, fuc.info.Capabilities
+	fuc.infoMu.Unlock()
+
+	if curve == nil || !capabilities.Has(smartfanunit.CapabilityFanCurve) || !capabilities.Has(smartfanunit.CapabilityFrameV2) {
+		return nil
+	}
+	return fuc.command(ctx, &smartfanunit.SetFanCurvePacket{Curve: *curve})
+}
+
+// SetFanLUT configures the lookup table of the EMC2101, which drives the fan instead of the fan speed requests.
+// The lookup table is kept and pushed as soon as the fan unit announces support for lookup tables.
+func (fuc *smartFanUnit) SetFanLUT(ctx context.Context, lut smartfanunit.FanLUT) error {
+	if err := lut.Validate(); err != nil {
+		return err
+	}
+	fuc.infoMu.Lock()
+	fuc.fanLUT = &lut
+	fuc.infoMu.Unlock()
+	return fuc.pushFanLUT(ctx)
+}
+
+// pushFanLUT sends the lookup table if one is set and the firmware supports it
+func (fuc *smartFanUnit) pushFanLUT(ctx context.Context) error {
+	fuc.infoMu.Lock()
+	lut, capabilities := fuc.fanLUT, fuc.info.Capabilities
+	fuc.infoMu.Unlock()
+
+	if lut == nil || !capabilities.Has(smartfanunit.CapabilityFanLUT) || !capabilities.Has(smartfanunit.CapabilityFrameV2) {
+		return nil
+	}
+	return fuc.command(ctx, &smartfanunit.SetFanLUTPacket{LUT: *lut})
 }
 
 // FanSpeedRPM returns the current fan speed in rotations per minute.
@@ -194,9 +656,60 @@ func (fuc *smartFanUnit) WaitForButtonPress(ctx context.Context) error {
 	return nil
 }
 
-// AirFlowTemperature returns the temperature of the air flow.
-func (fuc *smartFanUnit) AirFlowTemperature(_ context.Context) (float32, error) {
-	return fuc.airflow.Temperature, nil
+// setSensorTemperature updates the temperature of a named sensor, replacing the air flow temperature
+func (fuc *smartFanUnit) setSensorTemperature(sensor string, temperature float32) {
+	fuc.temperaturesMu.Lock()
+	defer fuc.temperaturesMu.Unlock()
+	if fuc.temperatures == nil {
+		fuc.temperatures = make(map[string]float32)
+	}
+	delete(fuc.temperatures, FanUnitSensorAirFlow)
+	fuc.temperatures[sensor] = temperature
+	fanUnitTemperature.DeleteLabelValues(FanUnitSensorAirFlow)
+	fanUnitTemperature.WithLabelValues(sensor).Set(float64(temperature))
+	airFlowTemperature.Set(float64(maxTemperature(fuc.temperatures)))
+}
+
+// setAirFlowTemperature updates the air flow temperature, it's ignored once the firmware reports named sensors
+func (fuc *smartFanUnit) setAirFlowTemperature(temperature float32) {
+	fuc.temperaturesMu.Lock()
+	defer fuc.temperaturesMu.Unlock()
+	_, hasInternal := fuc.temperatures[FanUnitSensorInternal]
+	_, hasExternal := fuc.temperatures[FanUnitSensorExternal]
+	if hasInternal || hasExternal {
+		return
+	}
+	fuc.temperatures = map[string]float32{FanUnitSensorAirFlow: temperature}
+	fanUnitTemperature.WithLabelValues(FanUnitSensorAirFlow).Set(float64(temperature))
+	airFlowTemperature.Set(float64(temperature))
+}
+
+// Temperatures returns the last temperatures reported by the fan unit, ErrSensorNotAvailable until the first report.
+// Firmware reporting a single air flow temperature reports it as FanUnitSensorAirFlow.
+func (fuc *smartFanUnit) Temperatures(_ context.Context) (map[string]float32, error) {
+	fuc.temperaturesMu.Lock()
+	defer fuc.temperaturesMu.Unlock()
+	if len(fuc.temperatures) == 0 {
+		return nil, ErrSensorNotAvailable
+	}
+	temperatures := make(map[string]float32, len(fuc.temperatures))
+	for sensor, temperature := range fuc.temperatures {
+		temperatures[sensor] = temperature
+	}
+	return temperatures, nil
+}
+
+// maxTemperature returns the highest of the given temperatures
+func maxTemperature(temperatures map[string]float32) float32 {
+	first := true
+	var highest float32
+	for _, temperature := range temperatures {
+		if first || temperature > highest {
+			highest = temperature
+		}
+		first = false
+	}
+	return highest
 }
 
 func (fuc *smartFanUnit) Close() error {
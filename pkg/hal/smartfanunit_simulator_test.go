@@ -0,0 +1,95 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/eventbus"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/firmware"
+)
+
+func TestSmartFanUnit_Simulator(t *testing.T) {
+	t.Parallel()
+
+	sim := firmware.NewSimulator(smartfanunit.FirmwareVersion{Major: 1, Minor: 2, Patch: 3})
+	left := &smartFanUnit{rwc: sim.Left, eb: eventbus.New()}
+	right := &smartFanUnit{rwc: sim.Right, eb: eventbus.New()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sim.Run(ctx) //nolint:errcheck
+	for _, fuc := range []*smartFanUnit{left, right} {
+		fuc.lastTelemetry.Store(time.Now().UnixNano())
+		go fuc.Run(ctx) //nolint:errcheck
+		defer fuc.Close()
+	}
+
+	// Both blades complete the version handshake
+	for _, fuc := range []*smartFanUnit{left, right} {
+		assert.Eventually(t, func() bool { return fuc.Info().FirmwareVersion == "1.2.3" }, 2*time.Second, time.Millisecond)
+		assert.True(t, fuc.Info().Capabilities.Has(smartfanunit.CapabilityAck|smartfanunit.CapabilityHeartbeat))
+	}
+
+	// The fan runs at the max speed requested by both blades, commands are acknowledged
+	require.NoError(t, left.SetFanSpeedPercent(ctx, 30))
+	require.NoError(t, right.SetFanSpeedPercent(ctx, 60))
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 60 }, time.Second, time.Millisecond)
+	require.NoError(t, right.SetFanSpeedPercent(ctx, 20))
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 30 }, time.Second, time.Millisecond)
+
+	require.NoError(t, left.SetLed(ctx, led.Color{Green: 0xff}))
+	assert.Eventually(t, func() bool {
+		leftColor, _ := sim.LEDs.Colors()
+		return leftColor == led.Color{Green: 0xff}
+	}, time.Second, time.Millisecond)
+
+	// A double press is forwarded to the right blade only
+	pressed := make(chan error, 1)
+	waitCtx, cancelWait := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelWait()
+	go func() { pressed <- right.WaitForButtonPress(waitCtx) }()
+	time.Sleep(10 * time.Millisecond)
+	sim.Button.Press()
+	sim.Button.Press()
+	assert.NoError(t, <-pressed)
+
+	// Both blades send heartbeats and are reported alive
+	assert.Eventually(t, func() bool {
+		status := right.Info().BladeStatus
+		return status != nil && *status == smartfanunit.BladeStatus{Side: smartfanunit.BladeSideRight, LeftAlive: true, RightAlive: true}
+	}, 2*smartFanUnitHeartbeatInterval, 10*time.Millisecond)
+
+	// A lookup table hands the fan over to the EMC2101, no steps hand it back to the requests
+	sim.FanController.SetTemperatures(25, 50)
+	lut := smartfanunit.FanLUT{Hysteresis: 2, Steps: []smartfanunit.FanCurveStep{{Temperature: 0, Percent: 40}, {Temperature: 45, Percent: 80}}}
+	require.NoError(t, left.SetFanLUT(ctx, lut))
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 80 }, time.Second, time.Millisecond)
+	require.NoError(t, left.SetFanLUT(ctx, smartfanunit.FanLUT{}))
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 30 }, time.Second, time.Millisecond)
+
+	// Both blades receive the temperatures of both sensors
+	sim.FanController.SetTemperatures(25, 35)
+	for _, fuc := range []*smartFanUnit{left, right} {
+		assert.Eventually(t, func() bool {
+			temps, err := fuc.Temperatures(ctx)
+			return err == nil && reflect.DeepEqual(temps, map[string]float32{FanUnitSensorInternal: 25, FanUnitSensorExternal: 35})
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// I2C bus recoveries are reported to both blades
+	sim.FanController.LockBus()
+	for _, fuc := range []*smartFanUnit{left, right} {
+		assert.Eventually(t, func() bool {
+			return fuc.Info().BusRecovery == smartfanunit.BusRecoveryStats{Recoveries: 1}
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	assert.Zero(t, sim.Resets())
+}
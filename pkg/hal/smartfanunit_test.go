@@ -0,0 +1,408 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/eventbus"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/log"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSmartFanUnit_LinkStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	fuc := &smartFanUnit{}
+	fuc.lastTelemetry.Store(now.UnixNano())
+	assert.Equal(t, FanUnitLinkOk, fuc.linkStatus(now))
+
+	// Missing notifications degrade the link before it's lost
+	assert.Equal(t, FanUnitLinkDegraded, fuc.linkStatus(now.Add(smartFanUnitLinkDegradedTimeout+time.Second)))
+	assert.Equal(t, FanUnitLinkLost, fuc.linkStatus(now.Add(fanUnitTelemetryTimeout+time.Second)))
+
+	// Protocol errors degrade the link for a while, even with fresh telemetry
+	assert.True(t, fuc.recordReadError(proto.ErrChecksumMismatch))
+	fuc.lastTelemetry.Store(time.Now().UnixNano())
+	assert.Equal(t, FanUnitLinkDegraded, fuc.linkStatus(time.Now()))
+	fuc.lastTelemetry.Store(time.Now().Add(smartFanUnitLinkErrorWindow).UnixNano())
+	assert.Equal(t, FanUnitLinkOk, fuc.linkStatus(time.Now().Add(smartFanUnitLinkErrorWindow+time.Second)))
+}
+
+func TestSmartFanUnit_RecordReadError(t *testing.T) {
+	t.Parallel()
+
+	fuc := &smartFanUnit{}
+	assert.True(t, fuc.recordReadError(proto.ErrChecksumMismatch))
+	assert.True(t, fuc.recordReadError(proto.ErrInvalidFramingByte))
+	assert.True(t, fuc.recordReadError(proto.ErrFrameOverflow))
+	assert.False(t, fuc.recordReadError(errors.New("port closed")))
+}
+
+func TestSmartFanUnit_SetBusRecovery(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := log.IntoContext(context.Background(), zap.New(core))
+	fuc := &smartFanUnit{}
+
+	// Failed attempts without a recovery are logged as failure
+	fuc.setBusRecovery(ctx, smartfanunit.BusRecoveryStats{FailedAttempts: 3})
+	fuc.setBusRecovery(ctx, smartfanunit.BusRecoveryStats{Recoveries: 1, FailedAttempts: 4})
+	entries := logs.TakeAll()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+		assert.Contains(t, entries[0].Message, "failed")
+		assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+		assert.Contains(t, entries[1].Message, "recovered")
+	}
+	assert.Equal(t, smartfanunit.BusRecoveryStats{Recoveries: 1, FailedAttempts: 4}, fuc.Info().BusRecovery)
+}
+
+// newPipeSmartFanUnit runs a smart fan unit connected to a fake firmware replying to every packet with respond.
+// Unless handled by respond, the firmware doesn't support the version handshake.
+func newPipeSmartFanUnit(t *testing.T, respond func(pkt proto.Packet) []proto.Packet) *smartFanUnit {
+	t.Helper()
+
+	agentConn, firmwareConn := net.Pipe()
+	fuc := &smartFanUnit{rwc: agentConn, eb: eventbus.New()}
+	fuc.lastTelemetry.Store(time.Now().UnixNano())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		agentConn.Close()
+		firmwareConn.Close()
+	})
+
+	go fuc.Run(ctx) //nolint:errcheck
+	go func() {
+		for {
+			pkt, err := proto.ReadPacket(ctx, firmwareConn)
+			if err != nil {
+				return
+			}
+			for _, reply := range respond(pkt) {
+				if err := proto.WritePacket(ctx, firmwareConn, reply); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return fuc
+}
+
+// commandsOnly ignores hellos, like firmware without version handshake
+func commandsOnly(respond func(pkt proto.Packet) []proto.Packet) func(pkt proto.Packet) []proto.Packet {
+	return func(pkt proto.Packet) []proto.Packet {
+		if pkt.Command == smartfanunit.CmdHello {
+			return nil
+		}
+		return respond(pkt)
+	}
+}
+
+func ackAll(pkt proto.Packet) []proto.Packet {
+	ack := smartfanunit.AckPacket{Command: pkt.Command, Sequence: smartfanunit.Sequence(pkt)}
+	return []proto.Packet{ack.Packet()}
+}
+
+func TestSmartFanUnit_CommandAck(t *testing.T) {
+	t.Parallel()
+
+	var received atomic.Int32
+	fuc := newPipeSmartFanUnit(t, commandsOnly(func(pkt proto.Packet) []proto.Packet {
+		received.Add(1)
+		return ackAll(pkt)
+	}))
+
+	ctx := context.Background()
+	assert.NoError(t, fuc.SetFanSpeedPercent(ctx, 50))
+	assert.NoError(t, fuc.SetLed(ctx, led.Color{Red: 0xff}))
+	assert.Equal(t, int32(2), received.Load())
+	assert.Equal(t, smartFanUnitAckSupported, smartFanUnitAckMode(fuc.ackMode.Load()))
+}
+
+func TestSmartFanUnit_CommandRetry(t *testing.T) {
+	t.Parallel()
+
+	// The second packet is lost
+	var received atomic.Int32
+	fuc := newPipeSmartFanUnit(t, commandsOnly(func(pkt proto.Packet) []proto.Packet {
+		if received.Add(1) == 2 {
+			return nil
+		}
+		return ackAll(pkt)
+	}))
+
+	ctx := context.Background()
+	assert.NoError(t, fuc.SetFanSpeedPercent(ctx, 50))
+	assert.NoError(t, fuc.SetFanSpeedPercent(ctx, 60))
+	assert.Equal(t, int32(3), received.Load())
+}
+
+func TestSmartFanUnit_CommandNack(t *testing.T) {
+	t.Parallel()
+
+	fuc := newPipeSmartFanUnit(t, commandsOnly(func(pkt proto.Packet) []proto.Packet {
+		nack := smartfanunit.NackPacket{
+			Command:  pkt.Command,
+			Sequence: smartfanunit.Sequence(pkt),
+			Reason:   smartfanunit.NackReasonInvalidValue,
+		}
+		return []proto.Packet{nack.Packet()}
+	}))
+
+	err := fuc.SetFanSpeedPercent(context.Background(), 150)
+	assert.ErrorIs(t, err, ErrCommandRejected)
+	assert.ErrorContains(t, err, "invalid value")
+}
+
+func TestSmartFanUnit_CommandTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Firmware acknowledges the first command only
+	var received atomic.Int32
+	fuc := newPipeSmartFanUnit(t, commandsOnly(func(pkt proto.Packet) []proto.Packet {
+		if received.Add(1) > 1 {
+			return nil
+		}
+		return ackAll(pkt)
+	}))
+
+	ctx := context.Background()
+	assert.NoError(t, fuc.SetFanSpeedPercent(ctx, 50))
+	assert.ErrorIs(t, fuc.SetFanSpeedPercent(ctx, 60), ErrCommunicationFailed)
+	assert.Equal(t, int32(2+smartFanUnitCommandRetries), received.Load())
+}
+
+func TestSmartFanUnit_CommandLegacyFirmware(t *testing.T) {
+	t.Parallel()
+
+	// Firmware without acknowledgements
+	var received atomic.Int32
+	fuc := newPipeSmartFanUnit(t, commandsOnly(func(proto.Packet) []proto.Packet {
+		received.Add(1)
+		return nil
+	}))
+
+	ctx := context.Background()
+	for i := 0; i < smartFanUnitLegacyThreshold; i++ {
+		assert.NoError(t, fuc.SetFanSpeedPercent(ctx, 50))
+	}
+	assert.Equal(t, smartFanUnitAckUnsupported, smartFanUnitAckMode(fuc.ackMode.Load()))
+
+	// Commands are sent once without waiting for acknowledgements
+	start := time.Now()
+	assert.NoError(t, fuc.SetLed(ctx, led.Color{Green: 0xff}))
+	assert.Less(t, time.Since(start), smartFanUnitAckTimeout)
+	assert.Eventually(t, func() bool { return received.Load() == smartFanUnitLegacyThreshold+1 }, time.Second, time.Millisecond)
+}
+
+func TestSmartFanUnit_LEDSequence(t *testing.T) {
+	t.Parallel()
+
+	sequences := make(chan uint8, 2)
+	fuc := newPipeSmartFanUnit(t, func(pkt proto.Packet) []proto.Packet {
+		switch pkt.Command {
+		case smartfanunit.CmdHello:
+			hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: 1, Capabilities: smartfanunit.CapabilityAck |
+				smartfanunit.CapabilityFrameV2 | smartfanunit.CapabilityLEDSequence}
+			return []proto.Packet{hello.Packet()}
+		case smartfanunit.CmdSetLED:
+			var setLed smartfanunit.SetLEDPacket
+			assert.NoError(t, setLed.FromPacket(pkt))
+			assert.True(t, setLed.HasSequence)
+			sequences <- setLed.Sequence
+		}
+		return ackAll(pkt)
+	})
+	assert.Eventually(t, func() bool { return fuc.Info().Capabilities.Has(smartfanunit.CapabilityLEDSequence) }, time.Second, time.Millisecond)
+
+	// Repeated colors are sent with distinct sequence numbers
+	ctx := context.Background()
+	assert.NoError(t, fuc.SetLed(ctx, led.Color{Red: 0xff}))
+	assert.NoError(t, fuc.SetLed(ctx, led.Color{Red: 0xff}))
+	assert.NotEqual(t, <-sequences, <-sequences)
+}
+
+func TestSmartFanUnit_Handshake(t *testing.T) {
+	t.Parallel()
+
+	fuc := newPipeSmartFanUnit(t, func(pkt proto.Packet) []proto.Packet {
+		if pkt.Command != smartfanunit.CmdHello {
+			return ackAll(pkt)
+		}
+		hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: 1, Capabilities: smartfanunit.CapabilityAck}
+		version := smartfanunit.FirmwareVersionPacket{Version: smartfanunit.FirmwareVersion{Major: 1, Minor: 2, Patch: 3}}
+		return []proto.Packet{hello.Packet(), version.Packet()}
+	})
+
+	assert.Eventually(t, func() bool {
+		return fuc.Info() == FanUnitInfo{ProtocolVersion: 1, FirmwareVersion: "1.2.3", Capabilities: smartfanunit.CapabilityAck}
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, smartFanUnitAckSupported, smartFanUnitAckMode(fuc.ackMode.Load()))
+}
+
+func TestSmartFanUnit_HandshakeWithoutAck(t *testing.T) {
+	t.Parallel()
+
+	var received atomic.Int32
+	fuc := newPipeSmartFanUnit(t, func(pkt proto.Packet) []proto.Packet {
+		if pkt.Command != smartfanunit.CmdHello {
+			received.Add(1)
+			return nil
+		}
+		hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: 1}
+		return []proto.Packet{hello.Packet()}
+	})
+
+	// Commands are sent once without waiting for acknowledgements
+	assert.Eventually(t, func() bool {
+		return smartFanUnitAckMode(fuc.ackMode.Load()) == smartFanUnitAckUnsupported
+	}, time.Second, time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, fuc.SetFanSpeedPercent(context.Background(), 50))
+	assert.Less(t, time.Since(start), smartFanUnitAckTimeout)
+	assert.Empty(t, fuc.Info().FirmwareVersion)
+}
+
+func TestSmartFanUnit_FanCurve(t *testing.T) {
+	t.Parallel()
+
+	curve := smartfanunit.FanCurve{
+		Sensor:          smartfanunit.FanCurveSensorExternal,
+		WatchdogTimeout: 10 * time.Second,
+		Steps:           []smartfanunit.FanCurveStep{{Temperature: 30, Percent: 20}, {Temperature: 50, Percent: 100}},
+	}
+
+	for _, tc := range []struct {
+		name         string
+		capabilities smartfanunit.Capabilities
+		pushed       bool
+	}{
+		{"supported", smartfanunit.CapabilityAck | smartfanunit.CapabilityFrameV2 | smartfanunit.CapabilityFanCurve, true},
+		{"unsupported", smartfanunit.CapabilityAck | smartfanunit.CapabilityFrameV2, false},
+	} {
+		capabilities, pushed := tc.capabilities, tc.pushed
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			received := make(chan smartfanunit.FanCurve, 2)
+			fuc := newPipeSmartFanUnit(t, func(pkt proto.Packet) []proto.Packet {
+				switch pkt.Command {
+				case smartfanunit.CmdHello:
+					hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: 1, Capabilities: capabilities}
+					return []proto.Packet{hello.Packet()}
+				case smartfanunit.CmdSetFanCurve:
+					var setFanCurve smartfanunit.SetFanCurvePacket
+					assert.NoError(t, setFanCurve.FromPacket(pkt))
+					received <- setFanCurve.Curve
+				}
+				return ackAll(pkt)
+			})
+
+			// The curve is set before the handshake, it's pushed once the capabilities are known
+			assert.NoError(t, fuc.SetFanCurve(context.Background(), curve))
+			if !pushed {
+				assert.Eventually(t, func() bool { return fuc.Info().Capabilities == capabilities }, time.Second, time.Millisecond)
+				select {
+				case <-received:
+					t.Fatal("fan curve pushed to fan unit without support")
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+
+			select {
+			case got := <-received:
+				assert.Equal(t, curve, got)
+			case <-time.After(time.Second):
+				t.Fatal("fan curve not pushed")
+			}
+			assert.NoError(t, fuc.SetFanCurve(context.Background(), curve))
+
+			invalid := curve
+			invalid.Steps = nil
+			assert.ErrorIs(t, fuc.SetFanCurve(context.Background(), invalid), smartfanunit.ErrInvalidFanCurve)
+		})
+	}
+}
+
+func TestSmartFanUnit_Heartbeat(t *testing.T) {
+	t.Parallel()
+
+	var heartbeats atomic.Int32
+	fuc := newPipeSmartFanUnit(t, func(pkt proto.Packet) []proto.Packet {
+		switch pkt.Command {
+		case smartfanunit.CmdHello:
+			hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: 1, Capabilities: smartfanunit.CapabilityAck | smartfanunit.CapabilityHeartbeat}
+			return []proto.Packet{hello.Packet()}
+		case smartfanunit.CmdHeartbeat:
+			heartbeats.Add(1)
+			status := smartfanunit.BladeStatusPacket{Status: smartfanunit.BladeStatus{Side: smartfanunit.BladeSideRight, RightAlive: true}}
+			return []proto.Packet{status.Packet()}
+		}
+		return ackAll(pkt)
+	})
+
+	assert.Eventually(t, func() bool { return fuc.Info().BladeStatus != nil }, 2*smartFanUnitHeartbeatInterval, 10*time.Millisecond)
+	assert.Equal(t, smartfanunit.BladeStatus{Side: smartfanunit.BladeSideRight, RightAlive: true}, *fuc.Info().BladeStatus)
+	assert.Positive(t, heartbeats.Load())
+}
+
+func TestSmartFanUnit_HeartbeatUnsupported(t *testing.T) {
+	t.Parallel()
+
+	var heartbeats atomic.Int32
+	fuc := newPipeSmartFanUnit(t, func(pkt proto.Packet) []proto.Packet {
+		switch pkt.Command {
+		case smartfanunit.CmdHello:
+			hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: 1, Capabilities: smartfanunit.CapabilityAck}
+			return []proto.Packet{hello.Packet()}
+		case smartfanunit.CmdHeartbeat:
+			heartbeats.Add(1)
+		}
+		return ackAll(pkt)
+	})
+
+	// Firmware without heartbeats would reject them
+	time.Sleep(smartFanUnitHeartbeatInterval + 100*time.Millisecond)
+	assert.Zero(t, heartbeats.Load())
+	assert.Nil(t, fuc.Info().BladeStatus)
+}
+
+func TestSmartFanUnit_Temperatures(t *testing.T) {
+	t.Parallel()
+
+	fuc := &smartFanUnit{}
+	_, err := fuc.Temperatures(context.Background())
+	assert.ErrorIs(t, err, ErrSensorNotAvailable)
+
+	// Firmware without named sensors reports a single air flow temperature
+	fuc.setAirFlowTemperature(30)
+	temps, err := fuc.Temperatures(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float32{FanUnitSensorAirFlow: 30}, temps)
+
+	// Named sensors replace the air flow temperature, which is ignored afterward
+	fuc.setSensorTemperature(FanUnitSensorInternal, 25)
+	fuc.setSensorTemperature(FanUnitSensorExternal, 35)
+	fuc.setAirFlowTemperature(30)
+	temps, err = fuc.Temperatures(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float32{FanUnitSensorInternal: 25, FanUnitSensorExternal: 35}, temps)
+	assert.Equal(t, float32(35), maxTemperature(temps))
+}
@@ -0,0 +1,108 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFanPulsesPerRevolution is the number of tach pulses per revolution of most PC fans
+	DefaultFanPulsesPerRevolution = 2
+
+	// tachWindow is the sliding window over which tach edges are counted
+	tachWindow = time.Second
+	// tachTimeout is the time without tach edges after which the fan is considered stopped
+	tachTimeout = 2 * time.Second
+	// tachGlitchInterval is the minimum interval between two tach edges, closer edges are considered glitches.
+	// This corresponds to 30000 RPM with 2 pulses per revolution, way above any fan used with the blade.
+	tachGlitchInterval = time.Millisecond
+	// tachMinSpinningEdges is the minimum number of edges within the window for a spinning fan
+	tachMinSpinningEdges = 4
+	// tachMaxIntervalRatio is the maximum ratio between the longest and the shortest interval of a spinning fan
+	tachMaxIntervalRatio = 4
+)
+
+// tachometer measures the fan speed by counting the edges of a tach signal over a sliding time window.
+// The zero value is ready to use with DefaultFanPulsesPerRevolution.
+type tachometer struct {
+	// pulsesPerRevolution of the fan, DefaultFanPulsesPerRevolution if 0
+	pulsesPerRevolution uint
+
+	mu sync.Mutex
+	// edges are the timestamps of all edges within the window, oldest first
+	edges []time.Duration
+	// lastEdge is the local time the last edge has been received, used to detect stopped fans
+	lastEdge time.Time
+}
+
+// addEdge records an edge with the given timestamp (e.g. the kernel timestamp of a gpiod.LineEvent).
+// received is the local time the edge has been received.
+func (t *tachometer) addEdge(timestamp time.Duration, received time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n := len(t.edges); n > 0 {
+		delta := timestamp - t.edges[n-1]
+		if delta < 0 {
+			// Timestamps are not monotonic (e.g. the line has been re-requested), start over
+			t.edges = t.edges[:0]
+		} else if delta < tachGlitchInterval {
+			return
+		}
+	}
+	t.edges = append(t.edges, timestamp)
+	t.lastEdge = received
+
+	// Drop all edges outside the window, keeping the one right before it to measure the first interval
+	drop := 0
+	for drop < len(t.edges)-2 && timestamp-t.edges[drop+1] >= tachWindow {
+		drop++
+	}
+	t.edges = append(t.edges[:0], t.edges[drop:]...)
+}
+
+// rpm returns the fan speed averaged over the window, 0 if no edges have been received within the timeout
+func (t *tachometer) rpm(now time.Time) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.edges) < 2 || now.Sub(t.lastEdge) > tachTimeout {
+		return 0
+	}
+
+	pulsesPerRevolution := t.pulsesPerRevolution
+	if pulsesPerRevolution == 0 {
+		pulsesPerRevolution = DefaultFanPulsesPerRevolution
+	}
+
+	intervals := len(t.edges) - 1
+	elapsed := t.edges[len(t.edges)-1] - t.edges[0]
+	pulsesPerSecond := float64(intervals) / elapsed.Seconds()
+	return pulsesPerSecond * 60 / float64(pulsesPerRevolution)
+}
+
+// spinning returns true if the edges within the window look like the tach signal of a spinning fan:
+// enough edges spread over the window with regular intervals. The UART of the smart fan unit shares the tach pin,
+// its bursts of a few milliseconds every 2 seconds pass the glitch filter but are not considered a spinning fan.
+func (t *tachometer) spinning(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.edges) < tachMinSpinningEdges || now.Sub(t.lastEdge) > tachTimeout {
+		return false
+	}
+	if t.edges[len(t.edges)-1]-t.edges[0] < tachWindow/2 {
+		return false
+	}
+
+	minInterval := t.edges[1] - t.edges[0]
+	maxInterval := minInterval
+	for i := 2; i < len(t.edges); i++ {
+		interval := t.edges[i] - t.edges[i-1]
+		minInterval = min(minInterval, interval)
+		maxInterval = max(maxInterval, interval)
+	}
+	return maxInterval <= tachMaxIntervalRatio*minInterval
+}
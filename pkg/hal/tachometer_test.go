@@ -0,0 +1,153 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTachometer(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var tach tachometer
+
+	// No edges, no speed
+	assert.Zero(t, tach.rpm(now))
+
+	// A single edge is not enough to measure the speed
+	tach.addEdge(0, now)
+	assert.Zero(t, tach.rpm(now))
+
+	// 100 pulses/s with 2 pulses per revolution -> 3000 RPM
+	for i := 1; i <= 200; i++ {
+		tach.addEdge(time.Duration(i)*10*time.Millisecond, now)
+	}
+	assert.InDelta(t, 3000, tach.rpm(now), 0.01)
+	// Only the edges of the window (and the one right before it) are kept
+	assert.Len(t, tach.edges, 101)
+}
+
+func TestTachometer_PulsesPerRevolution(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tach := tachometer{pulsesPerRevolution: 4}
+	for i := 0; i <= 100; i++ {
+		tach.addEdge(time.Duration(i)*10*time.Millisecond, now)
+	}
+	assert.InDelta(t, 1500, tach.rpm(now), 0.01)
+}
+
+func TestTachometer_SpeedChange(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var tach tachometer
+	ts := time.Duration(0)
+	for i := 0; i < 100; i++ {
+		ts += 10 * time.Millisecond
+		tach.addEdge(ts, now)
+	}
+	// Once the window has passed, the old speed doesn't have any influence anymore
+	for i := 0; i < 50; i++ {
+		ts += 20 * time.Millisecond
+		tach.addEdge(ts, now)
+	}
+	assert.InDelta(t, 1500, tach.rpm(now), 0.01)
+}
+
+func TestTachometer_GlitchFilter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var tach tachometer
+	for i := 0; i <= 100; i++ {
+		ts := time.Duration(i) * 10 * time.Millisecond
+		tach.addEdge(ts, now)
+		// Ringing on the tach line
+		tach.addEdge(ts+100*time.Microsecond, now)
+		tach.addEdge(ts+500*time.Microsecond, now)
+	}
+	assert.InDelta(t, 3000, tach.rpm(now), 0.01)
+}
+
+func TestTachometer_Timeout(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var tach tachometer
+	for i := 0; i <= 100; i++ {
+		tach.addEdge(time.Duration(i)*10*time.Millisecond, now)
+	}
+	assert.NotZero(t, tach.rpm(now.Add(tachTimeout)))
+	// The fan stopped, no further edges arrive
+	assert.Zero(t, tach.rpm(now.Add(tachTimeout+time.Millisecond)))
+}
+
+func TestTachometer_NonMonotonicTimestamps(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var tach tachometer
+	for i := 0; i <= 100; i++ {
+		tach.addEdge(time.Hour+time.Duration(i)*10*time.Millisecond, now)
+	}
+	// Timestamps restart, the old edges must not be used
+	tach.addEdge(0, now)
+	assert.Zero(t, tach.rpm(now))
+	tach.addEdge(20*time.Millisecond, now)
+	assert.InDelta(t, 1500, tach.rpm(now), 0.01)
+}
+
+// addUartBurst adds the falling edges of a smart fan unit packet of the given length sent at 115200 baud
+func addUartBurst(tach *tachometer, start time.Duration, bytes int, received time.Time) {
+	const bitTime = time.Second / 115200
+	for i := 0; i < bytes; i++ {
+		// Start bit and a falling edge within the data bits
+		tach.addEdge(start+time.Duration(10*i)*bitTime, received)
+		tach.addEdge(start+time.Duration(10*i+4)*bitTime, received)
+	}
+}
+
+func TestTachometer_Spinning(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	t.Run("fan", func(t *testing.T) {
+		var tach tachometer
+		for i := 0; i <= 100; i++ {
+			tach.addEdge(time.Duration(i)*10*time.Millisecond, now)
+		}
+		assert.True(t, tach.spinning(now))
+		// The fan stopped, no further edges arrive
+		assert.False(t, tach.spinning(now.Add(tachTimeout+time.Millisecond)))
+	})
+	t.Run("slow fan", func(t *testing.T) {
+		// 300 RPM with 2 pulses per revolution
+		var tach tachometer
+		for i := 0; i <= 20; i++ {
+			tach.addEdge(time.Duration(i)*100*time.Millisecond, now)
+		}
+		assert.True(t, tach.spinning(now))
+	})
+	t.Run("uart bursts", func(t *testing.T) {
+		// Packets every 2 seconds pass the glitch filter, but are not a fan
+		var tach tachometer
+		for i := 0; i < 5; i++ {
+			addUartBurst(&tach, time.Duration(i)*2*time.Second, 16, now)
+		}
+		assert.NotZero(t, tach.rpm(now))
+		assert.False(t, tach.spinning(now))
+	})
+	t.Run("long uart burst", func(t *testing.T) {
+		// Even a long packet with regular edges doesn't cover the window
+		var tach tachometer
+		addUartBurst(&tach, 0, 256, now)
+		assert.NotZero(t, tach.rpm(now))
+		assert.False(t, tach.spinning(now))
+	})
+}
@@ -0,0 +1,263 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ThermalSourceSoc is the name of the SoC thermal source (thermal_zone0)
+	ThermalSourceSoc = "soc"
+
+	defaultSysfsRoot = "/sys"
+)
+
+// ErrThermalSourceNotFound is returned when none of the requested thermal sources is registered
+var ErrThermalSourceNotFound = errors.New("thermal source not found")
+
+// ThermalSource is a temperature sensor of the blade
+type ThermalSource interface {
+	// Name returns the unique name of the source, e.g. soc or nvme/composite
+	Name() string
+	// Temperature returns the current temperature in °C
+	Temperature(ctx context.Context) (float64, error)
+}
+
+type thermalSourceFunc struct {
+	name string
+	fn   func(ctx context.Context) (float64, error)
+}
+
+// NewThermalSource creates a thermal source reading its temperature from the given function
+func NewThermalSource(name string, fn func(ctx context.Context) (float64, error)) ThermalSource {
+	return &thermalSourceFunc{name: name, fn: fn}
+}
+
+func (s *thermalSourceFunc) Name() string {
+	return s.name
+}
+
+func (s *thermalSourceFunc) Temperature(ctx context.Context) (float64, error) {
+	return s.fn(ctx)
+}
+
+// sysfsThermalSource reads a temperature in millidegree celsius from a sysfs file
+type sysfsThermalSource struct {
+	name string
+	path string
+}
+
+func (s *sysfsThermalSource) Name() string {
+	return s.name
+}
+
+func (s *sysfsThermalSource) Temperature(_ context.Context) (float64, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return 0, err
+	}
+	milliDegrees, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0, err
+	}
+	return float64(milliDegrees) / 1000.0, nil
+}
+
+// ThermalRegistry keeps track of all thermal sources of the blade
+type ThermalRegistry struct {
+	mu      sync.RWMutex
+	sources map[string]ThermalSource
+}
+
+// NewThermalRegistry creates an empty thermal registry
+func NewThermalRegistry() *ThermalRegistry {
+	return &ThermalRegistry{
+		sources: make(map[string]ThermalSource),
+	}
+}
+
+// Register adds a thermal source to the registry, replacing any source with the same name
+func (r *ThermalRegistry) Register(sources ...ThermalSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, source := range sources {
+		r.sources[source.Name()] = source
+	}
+}
+
+// Unregister removes a thermal source from the registry
+func (r *ThermalRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sources, name)
+	thermalSourceTemperature.DeleteLabelValues(name)
+}
+
+// Source returns the thermal source with the given name
+func (r *ThermalRegistry) Source(name string) (ThermalSource, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	source, ok := r.sources[name]
+	return source, ok
+}
+
+// Names returns the names of all registered sources, sorted alphabetically
+func (r *ThermalRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.sources))
+	for name := range r.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Temperature returns the maximum temperature over the given sources (soc if none are given).
+// Unknown or unreadable sources are skipped, an error is only returned if no source could be read.
+func (r *ThermalRegistry) Temperature(ctx context.Context, names ...string) (float64, error) {
+	if len(names) == 0 {
+		names = []string{ThermalSourceSoc}
+	}
+
+	var (
+		result float64
+		found  bool
+		errs   []error
+	)
+	for _, name := range names {
+		source, ok := r.Source(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrThermalSourceNotFound, name))
+			continue
+		}
+		temp, err := source.Temperature(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("thermal source %s: %w", name, err))
+			continue
+		}
+		thermalSourceTemperature.WithLabelValues(name).Set(temp)
+		if !found || temp > result {
+			result = temp
+			found = true
+		}
+	}
+	if !found {
+		return 0, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// UpdateMetrics reads all registered sources to keep their metrics current
+func (r *ThermalRegistry) UpdateMetrics(ctx context.Context) {
+	_, _ = r.Temperature(ctx, r.Names()...)
+}
+
+// DiscoverThermalSources discovers thermal zones and hwmon temperature sensors under the given sysfs root (/sys if empty).
+//
+// Thermal zones are named after their type (e.g. cpu-thermal), thermal_zone0 is additionally registered as soc.
+// hwmon sensors are named <name>/<label> (e.g. nvme/composite), falling back to <name>/tempN without a label.
+// Duplicate names are suffixed with the sysfs device name.
+func DiscoverThermalSources(sysfsRoot string) ([]ThermalSource, error) {
+	if sysfsRoot == "" {
+		sysfsRoot = defaultSysfsRoot
+	}
+
+	var sources []ThermalSource
+	names := make(map[string]bool)
+	add := func(name, device, path string) {
+		if names[name] {
+			name = name + "@" + device
+		}
+		names[name] = true
+		sources = append(sources, &sysfsThermalSource{name: name, path: path})
+	}
+
+	// Thermal zones
+	zones, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "thermal", "thermal_zone*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(zones, func(i, j int) bool { return naturalLess(zones[i], zones[j]) })
+	for _, zone := range zones {
+		device := filepath.Base(zone)
+		path := filepath.Join(zone, "temp")
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if device == "thermal_zone0" {
+			add(ThermalSourceSoc, device, path)
+		}
+		add(sanitizeThermalName(readSysfsString(filepath.Join(zone, "type"), device)), device, path)
+	}
+
+	// hwmon sensors
+	hwmons, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "hwmon", "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(hwmons, func(i, j int) bool { return naturalLess(hwmons[i], hwmons[j]) })
+	for _, hwmon := range hwmons {
+		device := filepath.Base(hwmon)
+		chip := sanitizeThermalName(readSysfsString(filepath.Join(hwmon, "name"), device))
+		inputs, err := filepath.Glob(filepath.Join(hwmon, "temp*_input"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(inputs, func(i, j int) bool { return naturalLess(inputs[i], inputs[j]) })
+		for _, input := range inputs {
+			sensor := strings.TrimSuffix(filepath.Base(input), "_input")
+			label := sanitizeThermalName(readSysfsString(filepath.Join(hwmon, sensor+"_label"), sensor))
+			add(chip+"/"+label, device, input)
+		}
+	}
+
+	return sources, nil
+}
+
+// readSysfsString reads a trimmed sysfs attribute, returning fallback if it cannot be read or is empty
+func readSysfsString(path string, fallback string) string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fallback
+	}
+	value := strings.TrimSpace(string(raw))
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// sanitizeThermalName converts a sysfs label into a metric friendly name, e.g. "Sensor 1" -> "sensor_1"
+func sanitizeThermalName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), " ", "_")
+}
+
+// naturalLess compares paths ending with a number numerically (thermal_zone2 < thermal_zone10)
+func naturalLess(a, b string) bool {
+	trim := func(s string) (string, int) {
+		idx := strings.LastIndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) + 1
+		num, err := strconv.Atoi(s[idx:])
+		if err != nil {
+			return s, -1
+		}
+		return s[:idx], num
+	}
+	// temp1_input -> temp1
+	a, b = strings.TrimSuffix(a, "_input"), strings.TrimSuffix(b, "_input")
+	prefixA, numA := trim(a)
+	prefixB, numB := trim(b)
+	if prefixA != prefixB || numA < 0 || numB < 0 {
+		return a < b
+	}
+	return numA < numB
+}
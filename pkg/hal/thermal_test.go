@@ -0,0 +1,99 @@
+//go:build !tinygo
+
+package hal_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal"
+)
+
+func writeSysfsFile(t *testing.T, root string, path string, content string) {
+	t.Helper()
+	path = filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content+"\n"), 0o644))
+}
+
+func TestDiscoverThermalSources(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeSysfsFile(t, root, "class/thermal/thermal_zone0/type", "cpu-thermal")
+	writeSysfsFile(t, root, "class/thermal/thermal_zone0/temp", "48312")
+	writeSysfsFile(t, root, "class/thermal/thermal_zone1/type", "cpu-thermal")
+	writeSysfsFile(t, root, "class/thermal/thermal_zone1/temp", "40000")
+	writeSysfsFile(t, root, "class/hwmon/hwmon0/name", "nvme")
+	writeSysfsFile(t, root, "class/hwmon/hwmon0/temp1_input", "51850")
+	writeSysfsFile(t, root, "class/hwmon/hwmon0/temp1_label", "Composite")
+	writeSysfsFile(t, root, "class/hwmon/hwmon0/temp2_input", "60850")
+	writeSysfsFile(t, root, "class/hwmon/hwmon0/temp2_label", "Sensor 1")
+	writeSysfsFile(t, root, "class/hwmon/hwmon1/name", "rpi_volt")
+	writeSysfsFile(t, root, "class/hwmon/hwmon1/in0_lcrit_alarm", "0")
+
+	sources, err := hal.DiscoverThermalSources(root)
+	require.NoError(t, err)
+
+	registry := hal.NewThermalRegistry()
+	registry.Register(sources...)
+	assert.Equal(t, []string{
+		"cpu-thermal",
+		"cpu-thermal@thermal_zone1",
+		"nvme/composite",
+		"nvme/sensor_1",
+		"soc",
+	}, registry.Names())
+
+	soc, ok := registry.Source(hal.ThermalSourceSoc)
+	require.True(t, ok)
+	temp, err := soc.Temperature(context.Background())
+	require.NoError(t, err)
+	assert.InDelta(t, 48.312, temp, 0.0001)
+
+	// Sensor values are read on each call
+	writeSysfsFile(t, root, "class/hwmon/hwmon0/temp1_input", "55000")
+	temp, err = registry.Temperature(context.Background(), "nvme/composite")
+	require.NoError(t, err)
+	assert.InDelta(t, 55.0, temp, 0.0001)
+}
+
+func TestThermalRegistry_Temperature(t *testing.T) {
+	t.Parallel()
+
+	static := func(name string, temp float64, err error) hal.ThermalSource {
+		return hal.NewThermalSource(name, func(_ context.Context) (float64, error) {
+			return temp, err
+		})
+	}
+
+	registry := hal.NewThermalRegistry()
+	registry.Register(
+		static(hal.ThermalSourceSoc, 45, nil),
+		static("nvme/composite", 62, nil),
+		static("broken", 0, errors.New("read failed")),
+	)
+
+	// Defaults to the SoC
+	temp, err := registry.Temperature(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 45.0, temp)
+
+	// Maximum over the set, unknown and unreadable sources are skipped
+	temp, err = registry.Temperature(context.Background(), hal.ThermalSourceSoc, "nvme/composite", "broken", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, 62.0, temp)
+
+	// Error if no source could be read
+	_, err = registry.Temperature(context.Background(), "broken", "missing")
+	assert.ErrorIs(t, err, hal.ErrThermalSourceNotFound)
+
+	registry.Unregister("nvme/composite")
+	_, ok := registry.Source("nvme/composite")
+	assert.False(t, ok)
+}
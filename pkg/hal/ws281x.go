@@ -0,0 +1,58 @@
+//go:build !tinygo
+
+package hal
+
+import "github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+
+// ws281xSpiSpeedHz is the SPI clock used to encode WS281x data, 3 SPI bits per data bit at 800khz
+const ws281xSpiSpeedHz = 3 * 800000
+
+// ws281xSpiResetBytes is the number of zero bytes sent before and after the pixel data (~67us at 2.4Mhz)
+const ws281xSpiResetBytes = 20
+
+// ws281xSilenceFrames is the number of empty frames sent before the pixel data.
+// Sufficient padding to clear 50us of silence with ~412.5ns per bit -> at least 121 bits -> let's be safe and send 6*24=144 bits of silence
+const ws281xSilenceFrames = 6
+
+// serializePwmDataFrame converts a byte to a 24 bit PWM data frame for WS281x LEDs
+func serializePwmDataFrame(data uint8) uint32 {
+	var result uint32 = 0
+	for i := 7; i >= 0; i-- {
+		if i != 7 {
+			result <<= 3
+		}
+		if (uint32(data)&(1<<i))>>i == 0 {
+			result |= 0b100 // -__
+		} else {
+			result |= 0b110 // --_
+		}
+	}
+	return result
+}
+
+// ws281xFrames returns the 24 bit frames (MSB aligned) to be serialized for the top and edge LED,
+// padded with silence before and after the pixel data
+func ws281xFrames(leds [2]led.Color) []uint32 {
+	frames := make([]uint32, ws281xSilenceFrames, ws281xSilenceFrames+len(leds)*3+1)
+	for _, color := range leds {
+		frames = append(frames,
+			serializePwmDataFrame(color.Red)<<8,
+			serializePwmDataFrame(color.Green)<<8,
+			serializePwmDataFrame(color.Blue)<<8,
+		)
+	}
+	// make sure there's >50us of silence
+	return append(frames, 0)
+}
+
+// encodeWs281xSpi encodes the colors of the top and edge LED as SPI data, padded with the reset time before and after the pixel data
+func encodeWs281xSpi(leds [2]led.Color) []byte {
+	data := make([]byte, ws281xSpiResetBytes, 2*ws281xSpiResetBytes+len(leds)*9)
+	for _, color := range leds {
+		for _, channel := range []uint8{color.Red, color.Green, color.Blue} {
+			frame := serializePwmDataFrame(channel)
+			data = append(data, byte(frame>>16), byte(frame>>8), byte(frame))
+		}
+	}
+	return append(data, make([]byte, ws281xSpiResetBytes)...)
+}
@@ -0,0 +1,59 @@
+//go:build linux && !tinygo
+
+package hal
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+)
+
+const (
+	spiIocWrMode        = 0x40016B01 // _IOW('k', 1, __u8)
+	spiIocWrBitsPerWord = 0x40016B03 // _IOW('k', 3, __u8)
+	spiIocWrMaxSpeedHz  = 0x40046B04 // _IOW('k', 4, __u32)
+)
+
+// spiWs281x drives WS281x LEDs through the MOSI line of a spidev device
+type spiWs281x struct {
+	dev *os.File
+}
+
+// openSpiWs281x opens the spidev device and configures it for WS281x encoding (mode 0, 8 bits per word, 3*800khz)
+func openSpiWs281x(device string) (*spiWs281x, error) {
+	dev, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := uint8(0)
+	bitsPerWord := uint8(8)
+	speed := uint32(ws281xSpiSpeedHz)
+	for _, ioctl := range []struct {
+		req uintptr
+		arg unsafe.Pointer
+	}{
+		{spiIocWrMode, unsafe.Pointer(&mode)},
+		{spiIocWrBitsPerWord, unsafe.Pointer(&bitsPerWord)},
+		{spiIocWrMaxSpeedHz, unsafe.Pointer(&speed)},
+	} {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dev.Fd(), ioctl.req, uintptr(ioctl.arg)); errno != 0 {
+			dev.Close()
+			return nil, errno
+		}
+	}
+
+	return &spiWs281x{dev: dev}, nil
+}
+
+// write sends the colors of the top and edge LED
+func (s *spiWs281x) write(leds [2]led.Color) error {
+	_, err := s.dev.Write(encodeWs281xSpi(leds))
+	return err
+}
+
+func (s *spiWs281x) close() error {
+	return s.dev.Close()
+}
@@ -0,0 +1,44 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+)
+
+func TestWs281xFrames(t *testing.T) {
+	t.Parallel()
+
+	frames := ws281xFrames([2]led.Color{{Red: 0xff}, {Blue: 0x01}})
+	assert.Len(t, frames, ws281xSilenceFrames+6+1)
+	for _, frame := range frames[:ws281xSilenceFrames] {
+		assert.Zero(t, frame)
+	}
+
+	one := uint32(0b110110110110110110110110) << 8
+	zero := uint32(0b100100100100100100100100) << 8
+	assert.Equal(t, []uint32{
+		one, zero, zero, // top: red
+		zero, zero, (zero &^ (0b111 << 8)) | (0b110 << 8), // edge: blue=1
+		0,
+	}, frames[ws281xSilenceFrames:])
+}
+
+func TestEncodeWs281xSpi(t *testing.T) {
+	t.Parallel()
+
+	data := encodeWs281xSpi([2]led.Color{{Red: 0xff}, {Blue: 0x01}})
+	assert.Len(t, data, 2*ws281xSpiResetBytes+2*9)
+	assert.Equal(t, make([]byte, ws281xSpiResetBytes), data[:ws281xSpiResetBytes])
+	assert.Equal(t, make([]byte, ws281xSpiResetBytes), data[len(data)-ws281xSpiResetBytes:])
+
+	one := []byte{0b11011011, 0b01101101, 0b10110110}
+	zero := []byte{0b10010010, 0b01001001, 0b00100100}
+	pixels := data[ws281xSpiResetBytes : len(data)-ws281xSpiResetBytes]
+	assert.Equal(t, one, pixels[0:3])                                          // top: red
+	assert.Equal(t, zero, pixels[3:6])                                         // top: green
+	assert.Equal(t, []byte{0b10010010, 0b01001001, 0b00100110}, pixels[15:18]) // edge: blue=1
+}
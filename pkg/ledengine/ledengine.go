@@ -14,17 +14,20 @@ import (
 type LedEngine interface {
 	// SetPattern sets the blink pattern
 	SetPattern(pattern BlinkPattern) error
+	// SetBrightness scales the brightness of all pattern colors (0-1)
+	SetBrightness(brightness float64)
 	// Run runs the LED Engine
 	Run(ctx context.Context) error
 }
 
 // ledEngineImpl is the implementation of the LedEngine interface
 type ledEngineImpl struct {
-	ledIdx  uint
-	restart chan struct{}
-	pattern BlinkPattern
-	hal     hal.ComputeBladeHal
-	clock   util.Clock
+	ledIdx     uint
+	restart    chan struct{}
+	pattern    BlinkPattern
+	brightness float64
+	hal        hal.ComputeBladeHal
+	clock      util.Clock
 }
 
 type BlinkPattern struct {
@@ -56,6 +59,18 @@ func LedColorRed(brightness float64) led.Color {
 	}
 }
 
+// dimColor scales a color by the given brightness (0-1)
+func dimColor(color led.Color, brightness float64) led.Color {
+	if brightness >= 1 {
+		return color
+	}
+	return led.Color{
+		Red:   uint8(float64(color.Red) * brightness),
+		Green: uint8(float64(color.Green) * brightness),
+		Blue:  uint8(float64(color.Blue) * brightness),
+	}
+}
+
 func LedColorGreen(brightness float64) led.Color {
 	return led.Color{
 		Red:   0,
@@ -117,11 +132,12 @@ func NewLedEngine(opts LedEngineOpts) *ledEngineImpl {
 		clock = util.RealClock{}
 	}
 	return &ledEngineImpl{
-		ledIdx:  opts.LedIdx,
-		hal:     opts.Hal,
-		restart: make(chan struct{}),           // restart channel controls cancelation of any pattern
-		pattern: NewStaticPattern(led.Color{}), // Turn off LEDs by default
-		clock:   clock,
+		ledIdx:     opts.LedIdx,
+		hal:        opts.Hal,
+		restart:    make(chan struct{}),           // restart channel controls cancelation of any pattern
+		pattern:    NewStaticPattern(led.Color{}), // Turn off LEDs by default
+		brightness: 1,
+		clock:      clock,
 	}
 }
 
@@ -137,12 +153,19 @@ func (b *ledEngineImpl) SetPattern(pattern BlinkPattern) error {
 	return nil
 }
 
+func (b *ledEngineImpl) SetBrightness(brightness float64) {
+	b.brightness = max(0, min(1, brightness))
+	// Restart the pattern so the brightness is applied immediately
+	close(b.restart)
+	b.restart = make(chan struct{})
+}
+
 // Run runs the blink engine
 func (b *ledEngineImpl) Run(ctx context.Context) error {
 	// Iterate forever unless context is done
 	for {
 		// Set the base color
-		if err := b.hal.SetLed(b.ledIdx, b.pattern.BaseColor); err != nil {
+		if err := b.hal.SetLed(b.ledIdx, dimColor(b.pattern.BaseColor, b.brightness)); err != nil {
 			return err
 		}
 		//  Iterate through pattern delays
@@ -161,7 +184,7 @@ func (b *ledEngineImpl) Run(ctx context.Context) error {
 				if idx%2 == 0 {
 					color = b.pattern.ActiveColor
 				}
-				if err := b.hal.SetLed(b.ledIdx, color); err != nil {
+				if err := b.hal.SetLed(b.ledIdx, dimColor(color, b.brightness)); err != nil {
 					return err
 				}
 			}
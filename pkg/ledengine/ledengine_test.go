@@ -278,3 +278,34 @@ func Test_LedEngine_SetPattern_SetLedFailureInPattern(t *testing.T) {
 	clk.AssertExpectations(t)
 	cbMock.AssertExpectations(t)
 }
+
+func Test_LedEngine_SetBrightness(t *testing.T) {
+	t.Parallel()
+
+	clk := util.MockClock{}
+	clkAfterChan := make(chan time.Time)
+	clk.On("After", time.Hour).Once().Return(clkAfterChan)
+
+	cbMock := hal.ComputeBladeHalMock{}
+	cbMock.On("SetLed", uint(0), led.Color{Green: 0, Blue: 10, Red: 100}).Once().Return(nil)
+
+	opts := ledengine.LedEngineOpts{
+		Hal:    &cbMock,
+		Clock:  &clk,
+		LedIdx: 0,
+	}
+
+	engine := ledengine.NewLedEngine(opts)
+	err := engine.SetPattern(ledengine.NewStaticPattern(led.Color{Red: 200, Blue: 20}))
+	assert.NoError(t, err)
+	engine.SetBrightness(0.5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = engine.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	clk.AssertExpectations(t)
+	cbMock.AssertExpectations(t)
+}
@@ -0,0 +1,36 @@
+package smartfanunit
+
+import (
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+// BusRecoveryStats are the recoveries of the I2C bus to the EMC2101 since the fan unit has been started
+type BusRecoveryStats struct {
+	// Recoveries is the number of recoveries that made the EMC2101 reachable again
+	Recoveries uint16
+	// FailedAttempts is the number of recovery attempts after which the EMC2101 was still unreachable
+	FailedAttempts uint8
+}
+
+// BusRecoveryPacket is sent from the fan unit to both blades after recovering the I2C bus.
+type BusRecoveryPacket struct {
+	Stats BusRecoveryStats
+}
+
+func (p *BusRecoveryPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: NotifyBusRecovery,
+		Data:    proto.Data{uint8(p.Stats.Recoveries >> 8), uint8(p.Stats.Recoveries), p.Stats.FailedAttempts},
+	}
+}
+
+func (p *BusRecoveryPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != NotifyBusRecovery {
+		return ErrInvalidCommand
+	}
+	p.Stats = BusRecoveryStats{
+		Recoveries:     uint16(packet.Data[0])<<8 | uint16(packet.Data[1]),
+		FailedAttempts: packet.Data[2],
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build !tinygo
+
+package smartfanunit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+func TestBusRecoveryPacket(t *testing.T) {
+	t.Parallel()
+
+	for _, stats := range []BusRecoveryStats{
+		{},
+		{Recoveries: 3, FailedAttempts: 1},
+		{Recoveries: 0x1234, FailedAttempts: 0xff},
+	} {
+		pkt := BusRecoveryPacket{Stats: stats}
+		var parsed BusRecoveryPacket
+		assert.NoError(t, parsed.FromPacket(pkt.Packet()))
+		assert.Equal(t, stats, parsed.Stats)
+	}
+
+	var parsed BusRecoveryPacket
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: NotifyBladeStatus}), ErrInvalidCommand)
+}
@@ -2,6 +2,7 @@ package smartfanunit
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
 	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
@@ -11,28 +12,184 @@ const (
 	// Blade -> FanUnit
 	CmdSetFanSpeedPercent proto.Command = 0x01
 	CmdSetLED             proto.Command = 0x02
+	CmdHello              proto.Command = 0x03
+	CmdSetFanCurve        proto.Command = 0x04
+	CmdHeartbeat          proto.Command = 0x05
+	CmdSetFanLUT          proto.Command = 0x06
 
 	// FanUnit -> Blade, sent in regular intervals
 	NotifyButtonPress        proto.Command = 0xa1
 	NotifyAirFlowTemperature proto.Command = 0xa2
 	NotifyFanSpeedRPM        proto.Command = 0xa3
+
+	// FanUnit -> Blade, sent in response to commands
+	NotifyAck  proto.Command = 0xa4
+	NotifyNack proto.Command = 0xa5
+
+	// FanUnit -> Blade, sent in response to CmdHello and on startup
+	NotifyHello           proto.Command = 0xa6
+	NotifyFirmwareVersion proto.Command = 0xa7
+
+	// FanUnit -> Blade, sent in response to CmdHeartbeat
+	NotifyBladeStatus proto.Command = 0xa8
+
+	// FanUnit -> Blade, sent after recovering the I2C bus to the EMC2101
+	NotifyBusRecovery proto.Command = 0xa9
+
+	// FanUnit -> Blade, sent in regular intervals to both blades
+	NotifyInternalTemperature proto.Command = 0xaa
+	NotifyExternalTemperature proto.Command = 0xab
+)
+
+// ProtocolVersion is the version of the protocol implemented by this package.
+// Firmware not answering CmdHello implements version 0.
+const ProtocolVersion = 1
+
+// Capabilities are the optional features supported by the fan unit firmware
+type Capabilities uint16
+
+const (
+	// CapabilityAck is set if commands are acknowledged with NotifyAck/NotifyNack
+	CapabilityAck Capabilities = 1 << iota
+	// CapabilityFrameV2 is set if v2 frames with variable-length payload are read
+	CapabilityFrameV2
+	// CapabilityFanCurve is set if the fan unit applies a fan curve sent with CmdSetFanCurve
+	CapabilityFanCurve
+	// CapabilityHeartbeat is set if the fan unit expires requests of blades without heartbeats and answers
+	// CmdHeartbeat with NotifyBladeStatus
+	CapabilityHeartbeat
+	// CapabilityFanLUT is set if the fan unit programs the EMC2101 lookup table sent with CmdSetFanLUT
+	CapabilityFanLUT
+	// CapabilityLEDSequence is set if the fan unit acknowledges CmdSetLED with the sequence number of v2 frames
+	CapabilityLEDSequence
+)
+
+// Has returns true if all given capabilities are supported
+func (c Capabilities) Has(capabilities Capabilities) bool {
+	return c&capabilities == capabilities
+}
+
+// Names returns the names of all supported capabilities
+func (c Capabilities) Names() []string {
+	names := []string{}
+	if c.Has(CapabilityAck) {
+		names = append(names, "ack")
+	}
+	if c.Has(CapabilityFrameV2) {
+		names = append(names, "frame_v2")
+	}
+	if c.Has(CapabilityFanCurve) {
+		names = append(names, "fan_curve")
+	}
+	if c.Has(CapabilityHeartbeat) {
+		names = append(names, "heartbeat")
+	}
+	if c.Has(CapabilityFanLUT) {
+		names = append(names, "fan_lut")
+	}
+	if c.Has(CapabilityLEDSequence) {
+		names = append(names, "led_sequence")
+	}
+	return names
+}
+
+// NackReason is the reason a command has been rejected by the fan unit
+type NackReason uint8
+
+const (
+	NackReasonUnknown NackReason = iota
+	// NackReasonInvalidCommand is sent for commands not supported by the fan unit
+	NackReasonInvalidCommand
+	// NackReasonInvalidValue is sent for commands with out of range data
+	NackReasonInvalidValue
 )
 
+func (r NackReason) String() string {
+	switch r {
+	case NackReasonInvalidCommand:
+		return "invalid command"
+	case NackReasonInvalidValue:
+		return "invalid value"
+	default:
+		return "unknown"
+	}
+}
+
 var ErrInvalidCommand = errors.New("invalid command")
 
+// CommandName returns a human-readable name of the command, e.g. for metric labels
+func CommandName(cmd proto.Command) string {
+	switch cmd {
+	case CmdSetFanSpeedPercent:
+		return "set_fan_speed_percent"
+	case CmdSetLED:
+		return "set_led"
+	case NotifyButtonPress:
+		return "notify_button_press"
+	case NotifyAirFlowTemperature:
+		return "notify_airflow_temperature"
+	case NotifyFanSpeedRPM:
+		return "notify_fan_speed_rpm"
+	case NotifyAck:
+		return "notify_ack"
+	case NotifyNack:
+		return "notify_nack"
+	case CmdHello:
+		return "hello"
+	case CmdSetFanCurve:
+		return "set_fan_curve"
+	case CmdHeartbeat:
+		return "heartbeat"
+	case CmdSetFanLUT:
+		return "set_fan_lut"
+	case NotifyHello:
+		return "notify_hello"
+	case NotifyFirmwareVersion:
+		return "notify_firmware_version"
+	case NotifyBladeStatus:
+		return "notify_blade_status"
+	case NotifyBusRecovery:
+		return "notify_bus_recovery"
+	case NotifyInternalTemperature:
+		return "notify_internal_temperature"
+	case NotifyExternalTemperature:
+		return "notify_external_temperature"
+	default:
+		return "unknown"
+	}
+}
+
 type PacketGenerator interface {
 	Packet() proto.Packet
 }
 
+// Sequence returns the sequence byte used to acknowledge the packet.
+// Commands with a spare data byte carry a sequence number, the checksum is used for all others.
+func Sequence(packet proto.Packet) uint8 {
+	switch {
+	case packet.Command == CmdSetFanSpeedPercent:
+		return packet.Data[1]
+	case packet.Command == CmdSetLED && len(packet.Payload) == setLEDSequencedSize:
+		return packet.Payload[setLEDSequencedSize-1]
+	case packet.Payload != nil:
+		// Data only holds the first bytes of variable-length payloads
+		return uint8(proto.CRC16(packet.Payload))
+	default:
+		return packet.Checksum()
+	}
+}
+
 // SetFanSpeedPercentPacket is sent from the blade to the fan unit to set the fan speed in percent.
 type SetFanSpeedPercentPacket struct {
 	Percent uint8
+	// Sequence is echoed in the acknowledgement, ignored by firmware without acknowledgements
+	Sequence uint8
 }
 
 func (p *SetFanSpeedPercentPacket) Packet() proto.Packet {
 	return proto.Packet{
 		Command: CmdSetFanSpeedPercent,
-		Data:    proto.Data{p.Percent, 0, 0},
+		Data:    proto.Data{p.Percent, p.Sequence, 0},
 	}
 }
 
@@ -41,19 +198,31 @@ func (p *SetFanSpeedPercentPacket) FromPacket(packet proto.Packet) error {
 		return ErrInvalidCommand
 	}
 	p.Percent = packet.Data[0]
+	p.Sequence = packet.Data[1]
 	return nil
 }
 
+// setLEDSequencedSize is the payload size of SetLEDPacket with sequence number: blue, green, red, sequence
+const setLEDSequencedSize = 4
+
 // SetLEDPacket is sent from the blade to the fan unit to set the LED color.
 type SetLEDPacket struct {
 	Color led.Color
+	// Sequence is echoed in the acknowledgement if HasSequence is set. It doesn't fit into a v1 frame, so the packet
+	// is sent as v2 frame, which requires CapabilityLEDSequence. Otherwise the checksum is echoed.
+	Sequence    uint8
+	HasSequence bool
 }
 
 func (p *SetLEDPacket) Packet() proto.Packet {
-	return proto.Packet{
+	pkt := proto.Packet{
 		Command: CmdSetLED,
 		Data:    proto.Data{p.Color.Blue, p.Color.Green, p.Color.Red},
 	}
+	if p.HasSequence {
+		pkt.Payload = []uint8{p.Color.Blue, p.Color.Green, p.Color.Red, p.Sequence}
+	}
+	return pkt
 }
 
 func (p *SetLEDPacket) FromPacket(packet proto.Packet) error {
@@ -65,6 +234,11 @@ func (p *SetLEDPacket) FromPacket(packet proto.Packet) error {
 		Green: packet.Data[1],
 		Red:   packet.Data[2],
 	}
+	p.HasSequence = len(packet.Payload) == setLEDSequencedSize
+	p.Sequence = 0
+	if p.HasSequence {
+		p.Sequence = packet.Payload[setLEDSequencedSize-1]
+	}
 	return nil
 }
 
@@ -86,6 +260,7 @@ func (p *ButtonPressPacket) FromPacket(packet proto.Packet) error {
 }
 
 // AirFlowTemperaturePacket is sent from the fan unit to the blade to report the current air flow temperature.
+// The left blade receives the internal, the right blade the external temperature, see SensorTemperaturePacket.
 type AirFlowTemperaturePacket struct {
 	Temperature float32
 }
@@ -105,6 +280,58 @@ func (p *AirFlowTemperaturePacket) FromPacket(packet proto.Packet) error {
 	return nil
 }
 
+// TemperatureSensor is a temperature sensor of the fan unit
+type TemperatureSensor uint8
+
+const (
+	// TemperatureSensorInternal is the internal temperature sensor of the EMC2101, measuring the inlet air
+	TemperatureSensorInternal TemperatureSensor = iota
+	// TemperatureSensorExternal is the external temperature diode of the EMC2101, measuring the outlet air
+	TemperatureSensorExternal
+)
+
+func (s TemperatureSensor) String() string {
+	switch s {
+	case TemperatureSensorInternal:
+		return "internal"
+	case TemperatureSensorExternal:
+		return "external"
+	default:
+		return "unknown"
+	}
+}
+
+// SensorTemperaturePacket is sent from the fan unit to both blades to report the temperature of a sensor.
+// Each sensor has its own command, NotifyInternalTemperature or NotifyExternalTemperature.
+type SensorTemperaturePacket struct {
+	Sensor      TemperatureSensor
+	Temperature float32
+}
+
+func (p *SensorTemperaturePacket) Packet() proto.Packet {
+	cmd := NotifyInternalTemperature
+	if p.Sensor == TemperatureSensorExternal {
+		cmd = NotifyExternalTemperature
+	}
+	return proto.Packet{
+		Command: cmd,
+		Data:    float32To24Bit(p.Temperature),
+	}
+}
+
+func (p *SensorTemperaturePacket) FromPacket(packet proto.Packet) error {
+	switch packet.Command {
+	case NotifyInternalTemperature:
+		p.Sensor = TemperatureSensorInternal
+	case NotifyExternalTemperature:
+		p.Sensor = TemperatureSensorExternal
+	default:
+		return ErrInvalidCommand
+	}
+	p.Temperature = float32From24Bit(packet.Data)
+	return nil
+}
+
 // FanSpeedRPMPacket is sent from the fan unit to the blade to report the current fan speed in RPM.
 type FanSpeedRPMPacket struct {
 	RPM float32
@@ -123,3 +350,122 @@ func (p *FanSpeedRPMPacket) FromPacket(packet proto.Packet) error {
 	p.RPM = float32From24Bit(packet.Data)
 	return nil
 }
+
+// AckPacket is sent from the fan unit to the blade once a command has been applied.
+type AckPacket struct {
+	Command  proto.Command
+	Sequence uint8
+}
+
+func (p *AckPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: NotifyAck,
+		Data:    proto.Data{uint8(p.Command), p.Sequence, 0},
+	}
+}
+
+func (p *AckPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != NotifyAck {
+		return ErrInvalidCommand
+	}
+	p.Command = proto.Command(packet.Data[0])
+	p.Sequence = packet.Data[1]
+	return nil
+}
+
+// NackPacket is sent from the fan unit to the blade if a command has been rejected.
+type NackPacket struct {
+	Command  proto.Command
+	Sequence uint8
+	Reason   NackReason
+}
+
+func (p *NackPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: NotifyNack,
+		Data:    proto.Data{uint8(p.Command), p.Sequence, uint8(p.Reason)},
+	}
+}
+
+func (p *NackPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != NotifyNack {
+		return ErrInvalidCommand
+	}
+	p.Command = proto.Command(packet.Data[0])
+	p.Sequence = packet.Data[1]
+	p.Reason = NackReason(packet.Data[2])
+	return nil
+}
+
+// HelloPacket is sent from the blade to the fan unit to request the protocol version and capabilities.
+type HelloPacket struct {
+	ProtocolVersion uint8
+}
+
+func (p *HelloPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: CmdHello,
+		Data:    proto.Data{p.ProtocolVersion, 0, 0},
+	}
+}
+
+func (p *HelloPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != CmdHello {
+		return ErrInvalidCommand
+	}
+	p.ProtocolVersion = packet.Data[0]
+	return nil
+}
+
+// HelloNotifyPacket is sent from the fan unit to the blade with the protocol version and capabilities of the firmware.
+type HelloNotifyPacket struct {
+	ProtocolVersion uint8
+	Capabilities    Capabilities
+}
+
+func (p *HelloNotifyPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: NotifyHello,
+		Data:    proto.Data{p.ProtocolVersion, uint8(p.Capabilities >> 8), uint8(p.Capabilities)},
+	}
+}
+
+func (p *HelloNotifyPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != NotifyHello {
+		return ErrInvalidCommand
+	}
+	p.ProtocolVersion = packet.Data[0]
+	p.Capabilities = Capabilities(packet.Data[1])<<8 | Capabilities(packet.Data[2])
+	return nil
+}
+
+// FirmwareVersion is the semantic version of the fan unit firmware
+type FirmwareVersion struct {
+	Major uint8
+	Minor uint8
+	Patch uint8
+}
+
+func (v FirmwareVersion) String() string {
+	return strconv.Itoa(int(v.Major)) + "." + strconv.Itoa(int(v.Minor)) + "." + strconv.Itoa(int(v.Patch))
+}
+
+// FirmwareVersionPacket is sent from the fan unit to the blade with the firmware version.
+type FirmwareVersionPacket struct {
+	Version FirmwareVersion
+}
+
+func (p *FirmwareVersionPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: NotifyFirmwareVersion,
+		Data:    proto.Data{p.Version.Major, p.Version.Minor, p.Version.Patch},
+	}
+}
+
+func (p *FirmwareVersionPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != NotifyFirmwareVersion {
+		return ErrInvalidCommand
+	}
+	p.Version = FirmwareVersion{Major: packet.Data[0], Minor: packet.Data[1], Patch: packet.Data[2]}
+	return nil
+}
@@ -0,0 +1,72 @@
+//go:build !tinygo
+
+package smartfanunit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+func TestSequence(t *testing.T) {
+	t.Parallel()
+
+	// Fan speed commands carry a sequence number
+	setFanSpeed := SetFanSpeedPercentPacket{Percent: 40, Sequence: 7}
+	assert.Equal(t, uint8(7), Sequence(setFanSpeed.Packet()))
+
+	// LED commands don't have a spare byte, the checksum is used instead
+	setLed := SetLEDPacket{Color: led.Color{Red: 1, Green: 2, Blue: 3}}
+	pkt := setLed.Packet()
+	assert.Equal(t, pkt.Checksum(), Sequence(pkt))
+
+	// Unless sent with a sequence number as v2 frame
+	setLed.Sequence, setLed.HasSequence = 9, true
+	pkt = setLed.Packet()
+	assert.Equal(t, uint8(9), Sequence(pkt))
+	var parsed SetLEDPacket
+	assert.NoError(t, parsed.FromPacket(pkt))
+	assert.Equal(t, setLed, parsed)
+}
+
+func TestAckNackPacket(t *testing.T) {
+	t.Parallel()
+
+	ack := AckPacket{Command: CmdSetLED, Sequence: 0x42}
+	var parsedAck AckPacket
+	assert.NoError(t, parsedAck.FromPacket(ack.Packet()))
+	assert.Equal(t, ack, parsedAck)
+
+	nack := NackPacket{Command: CmdSetFanSpeedPercent, Sequence: 0x13, Reason: NackReasonInvalidValue}
+	var parsedNack NackPacket
+	assert.NoError(t, parsedNack.FromPacket(nack.Packet()))
+	assert.Equal(t, nack, parsedNack)
+
+	assert.ErrorIs(t, parsedAck.FromPacket(proto.Packet{Command: NotifyNack}), ErrInvalidCommand)
+	assert.ErrorIs(t, parsedNack.FromPacket(proto.Packet{Command: NotifyAck}), ErrInvalidCommand)
+}
+
+func TestSensorTemperaturePacket(t *testing.T) {
+	t.Parallel()
+
+	for sensor, cmd := range map[TemperatureSensor]proto.Command{
+		TemperatureSensorInternal: NotifyInternalTemperature,
+		TemperatureSensorExternal: NotifyExternalTemperature,
+	} {
+		temperature := SensorTemperaturePacket{Sensor: sensor, Temperature: 31.5}
+		pkt := temperature.Packet()
+		assert.Equal(t, cmd, pkt.Command)
+
+		var parsed SensorTemperaturePacket
+		assert.NoError(t, parsed.FromPacket(pkt))
+		assert.Equal(t, sensor, parsed.Sensor)
+		assert.InDelta(t, 31.5, parsed.Temperature, 0.01)
+	}
+
+	var parsed SensorTemperaturePacket
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: NotifyAirFlowTemperature}), ErrInvalidCommand)
+	assert.Equal(t, "internal", TemperatureSensorInternal.String())
+	assert.Equal(t, "external", TemperatureSensorExternal.String())
+}
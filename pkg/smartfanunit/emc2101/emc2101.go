@@ -3,6 +3,8 @@
 package emc2101
 
 import (
+	"errors"
+
 	"tinygo.org/x/drivers"
 )
 
@@ -21,23 +23,115 @@ type EMC2101 interface {
 	ExternalTemperature() (float32, error)
 	// SetFanPercent sets the fan speed as a percentage of max
 	SetFanPercent(percent uint8) error
-	// FanRPM returns the current fan speed in RPM
+	// FanRPM returns the current fan speed in RPM, 0 if the fan is stopped
 	FanRPM() (float32, error)
+	// SetLUTEnabled switches between automatic fan control by the lookup table and the fan setting of SetFanPercent
+	SetLUTEnabled(enabled bool) error
+	// Status reads the status register, alert bits are cleared by reading
+	Status() (Status, error)
+	// SetLUT programs the lookup table mapping the external diode temperature to a fan speed
+	SetLUT(steps []LUTStep) error
+	// SetLUTHysteresis sets the hysteresis in °C of the lookup table
+	SetLUTHysteresis(hysteresis uint8) error
+	// ConfigureExternalDiode configures the external temperature diode
+	ConfigureExternalDiode(diode ExternalDiode) error
 }
 
 const (
 	// Address is the default I2C address for the EMC2101
 	Address               = 0x4C
+	InternalTempReg       = 0x00
+	ExternalTempReg       = 0x01 // high byte, reading it latches the low byte
+	StatusReg             = 0x02
 	ConfigReg             = 0x03
+	ExternalTempLowReg    = 0x10
+	IdealityFactorReg     = 0x17
+	BetaConfigReg         = 0x18
+	FanTachReadingLowReg  = 0x46 // reading it latches the high byte
+	FanTachReadingHighReg = 0x47
 	FanConfigReg          = 0x4a
 	FanSpinUpReg          = 0x4b
 	FanSettingReg         = 0x4c
-	FanTachReadingLowReg  = 0x46
-	FanTachReadingHighReg = 0x47
-	ExternalTempReg       = 0x01
-	InternalTempReg       = 0x00
+	LUTHysteresisReg      = 0x4f
+	LUTStartReg           = 0x50 // temperature and fan setting of each step
+)
+
+const (
+	// fanConfigProg disables the lookup table, the fan is driven by the fan setting register
+	fanConfigProg = 1 << 5
+	// maxFanSetting is the fan setting at 100%
+	maxFanSetting = 0x3f
+	// tachStopped is the tach reading of a stopped fan
+	tachStopped = 0xffff
+	// tachNumerator is divided by the tach reading to calculate the fan speed in RPM
+	tachNumerator = 5400000
+	// externalTempResolution is the resolution of the external temperature in °C
+	externalTempResolution = 0.125
+	// maxLUTTemperature is the highest temperature of a lookup table step in °C
+	maxLUTTemperature = 0x7f
+	// maxLUTHysteresis is the highest hysteresis of the lookup table in °C
+	maxLUTHysteresis = 0x1f
+	// maxIdealityFactor is the highest ideality factor code
+	maxIdealityFactor = 0x3f
+	// betaConfigAuto enables automatic beta compensation, betaConfigDisabled disables it
+	betaConfigAuto     = 0x08
+	betaConfigDisabled = 0x07
+)
+
+// MaxLUTSteps is the number of steps of the lookup table
+const MaxLUTSteps = 8
+
+var (
+	ErrInvalidLUT           = errors.New("invalid lookup table")
+	ErrInvalidExternalDiode = errors.New("invalid external diode configuration")
 )
 
+// LUTStep is a step of the lookup table, the fan speed applies from the temperature up to the next step
+type LUTStep struct {
+	// Temperature in °C, up to 127°C
+	Temperature uint8
+	// Percent is the fan speed at the temperature
+	Percent uint8
+}
+
+// ExternalDiode configures the external temperature diode
+type ExternalDiode struct {
+	// IdealityFactor is the ideality factor code of the diode, 0x12 (1.008) after power up
+	IdealityFactor uint8
+	// BetaCompensation enables automatic beta compensation, required for transistors of processors
+	BetaCompensation bool
+}
+
+// Status is the content of the status register
+type Status uint8
+
+const (
+	// StatusTach is set if the tach reading exceeds the tach limit, e.g. the fan is stalled
+	StatusTach Status = 1 << iota
+	// StatusTCrit is set if the external temperature reached the TCRIT limit
+	StatusTCrit
+	// StatusDiodeFault is set if the external diode is open or shorted
+	StatusDiodeFault
+	// StatusExternalLow is set if the external temperature is below the low limit
+	StatusExternalLow
+	// StatusExternalHigh is set if the external temperature is above the high limit
+	StatusExternalHigh
+	// StatusEEPROMError is set if the configuration couldn't be loaded on power up
+	StatusEEPROMError
+	// StatusInternalHigh is set if the internal temperature is above the high limit
+	StatusInternalHigh
+	// StatusBusy is set while a temperature conversion is in progress
+	StatusBusy
+)
+
+// Has returns true if all given status bits are set
+func (s Status) Has(status Status) bool {
+	return s&status == status
+}
+
+// ErrDiodeFault is returned when reading the external temperature with a faulty external diode
+var ErrDiodeFault = errors.New("external diode fault")
+
 func New(bus drivers.I2C) EMC2101 {
 	return &emc2101{bus: bus, Address: Address}
 }
@@ -57,7 +151,6 @@ func (e *emc2101) updateReg(regAddr, setMask, clearMask uint8) error {
 		return nil
 	}
 
-
 	return e.bus.Tx(e.Address, []byte{regAddr, toWrite}, nil)
 }
 
@@ -74,10 +167,10 @@ func (e *emc2101) Init() error {
 	}
 
 	/*
-	0x3 0b100
-	0x4b 0b11111
-	0x4a 0b100000
-	0x4a 0b100000
+		0x3 0b100
+		0x4b 0b11111
+		0x4a 0b100000
+		0x4a 0b100000
 	*/
 
 	// Configure fan spin up to ignore tach input
@@ -89,44 +182,162 @@ func (e *emc2101) Init() error {
 	return nil
 }
 
+// InternalTemperature returns the internal temperature in °C, with a resolution of 1°C
 func (e *emc2101) InternalTemperature() (float32, error) {
 	buf := make([]byte, 1)
 	if err := e.bus.Tx(e.Address, []byte{InternalTempReg}, buf); err != nil {
 		return 0, err
 	}
-	return float32(buf[0]), nil
+	return float32(int8(buf[0])), nil
 }
 
+// ExternalTemperature returns the temperature of the external diode in °C, with a resolution of 0.125°C
 func (e *emc2101) ExternalTemperature() (float32, error) {
-	buf := make([]byte, 1)
-	if err := e.bus.Tx(e.Address, []byte{ExternalTempReg}, buf); err != nil {
+	high := make([]byte, 1)
+	low := make([]byte, 1)
+	if err := e.bus.Tx(e.Address, []byte{ExternalTempReg}, high); err != nil {
 		return 0, err
 	}
-	return float32(buf[0]), nil
+	if err := e.bus.Tx(e.Address, []byte{ExternalTempLowReg}, low); err != nil {
+		return 0, err
+	}
+
+	// 11 bit two's complement, the 3 fractional bits are the upper bits of the low byte
+	raw := int16(uint16(high[0])<<8|uint16(low[0])) >> 5
+	if raw == 0x3ff {
+		// Reading of an open or shorted diode
+		status, err := e.Status()
+		if err == nil && status.Has(StatusDiodeFault) {
+			return 0, ErrDiodeFault
+		}
+	}
+	return float32(raw) * externalTempResolution, nil
 }
 
+// SetFanPercent sets the fan setting, it only drives the fan while the lookup table is disabled
 func (e *emc2101) SetFanPercent(percent uint8) error {
 	if percent > 100 {
 		percent = 100
 	}
-	val := uint8(uint32(percent) * 63 / 100)
-	return e.bus.Tx(e.Address, []byte{FanSettingReg, val}, nil)
+
+	return e.bus.Tx(e.Address, []byte{FanSettingReg, fanSetting(percent)}, nil)
+}
+
+// fanSetting converts a fan speed in percent to a fan setting
+func fanSetting(percent uint8) uint8 {
+	return uint8(uint32(percent) * maxFanSetting / 100)
 }
 
 func (e *emc2101) FanRPM() (float32, error) {
 	high := make([]byte, 1)
 	low := make([]byte, 1)
 
-	err := e.bus.Tx(e.Address, []byte{FanTachReadingHighReg}, high)
+	// The low byte latches the high byte, so it's read first
+	err := e.bus.Tx(e.Address, []byte{FanTachReadingLowReg}, low)
 	if err != nil {
 		return 0, err
 	}
-	err = e.bus.Tx(e.Address, []byte{FanTachReadingLowReg}, low)
+	err = e.bus.Tx(e.Address, []byte{FanTachReadingHighReg}, high)
 	if err != nil {
 		return 0, err
 	}
 
-	var tachCount int = int(high[0])<<8 | int(low[0])
+	tachCount := uint16(high[0])<<8 | uint16(low[0])
+	if tachCount == 0 || tachCount == tachStopped {
+		// No tach pulses, the fan is stopped
+		return 0, nil
+	}
+	return float32(tachNumerator) / float32(tachCount), nil
+}
 
-	return float32(5400000) / float32(tachCount), nil
+func (e *emc2101) SetLUTEnabled(enabled bool) error {
+	if enabled {
+		return e.updateReg(FanConfigReg, 0, fanConfigProg)
+	}
+	return e.updateReg(FanConfigReg, fanConfigProg, 0)
+}
+
+func (e *emc2101) Status() (Status, error) {
+	buf := make([]byte, 1)
+	if err := e.bus.Tx(e.Address, []byte{StatusReg}, buf); err != nil {
+		return 0, err
+	}
+	return Status(buf[0]), nil
+}
+
+// SetLUT programs all steps of the lookup table, unused steps repeat the last step.
+// The lookup table is read-only while it drives the fan, so it's disabled while programming.
+func (e *emc2101) SetLUT(steps []LUTStep) error {
+	if len(steps) == 0 || len(steps) > MaxLUTSteps {
+		return ErrInvalidLUT
+	}
+	for i, step := range steps {
+		if step.Temperature > maxLUTTemperature || step.Percent > 100 {
+			return ErrInvalidLUT
+		}
+		if i > 0 && step.Temperature <= steps[i-1].Temperature {
+			return ErrInvalidLUT
+		}
+	}
+
+	// The EMC2101 only supports SMBus byte writes, so every register is written in a transaction of its own
+	buf := make([]byte, 0, 2*MaxLUTSteps)
+	for i := 0; i < MaxLUTSteps; i++ {
+		step := steps[min(i, len(steps)-1)]
+		if i >= len(steps) {
+			step.Temperature = maxLUTTemperature
+		}
+		buf = append(buf, step.Temperature, fanSetting(step.Percent))
+	}
+	return e.unlockLUT(func() error {
+		for i, value := range buf {
+			if err := e.bus.Tx(e.Address, []byte{LUTStartReg + uint8(i), value}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (e *emc2101) SetLUTHysteresis(hysteresis uint8) error {
+	if hysteresis > maxLUTHysteresis {
+		return ErrInvalidLUT
+	}
+	return e.unlockLUT(func() error {
+		return e.bus.Tx(e.Address, []byte{LUTHysteresisReg, hysteresis}, nil)
+	})
+}
+
+// unlockLUT disables the lookup table while program writes it, the lookup table is re-enabled afterward if it was enabled
+func (e *emc2101) unlockLUT(program func() error) error {
+	buf := make([]byte, 1)
+	if err := e.bus.Tx(e.Address, []byte{FanConfigReg}, buf); err != nil {
+		return err
+	}
+	if buf[0]&fanConfigProg != 0 {
+		return program()
+	}
+
+	if err := e.bus.Tx(e.Address, []byte{FanConfigReg, buf[0] | fanConfigProg}, nil); err != nil {
+		return err
+	}
+	if err := program(); err != nil {
+		return err
+	}
+	return e.bus.Tx(e.Address, []byte{FanConfigReg, buf[0]}, nil)
+}
+
+func (e *emc2101) ConfigureExternalDiode(diode ExternalDiode) error {
+	if diode.IdealityFactor > maxIdealityFactor {
+		return ErrInvalidExternalDiode
+	}
+	if err := e.bus.Tx(e.Address, []byte{IdealityFactorReg, diode.IdealityFactor}, nil); err != nil {
+		return err
+	}
+
+	beta := uint8(betaConfigDisabled)
+	if diode.BetaCompensation {
+		beta = betaConfigAuto
+	}
+	return e.bus.Tx(e.Address, []byte{BetaConfigReg, beta}, nil)
 }
@@ -0,0 +1,222 @@
+//go:build !tinygo
+
+package emc2101
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEMC2101_Init(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	bus.preset(ConfigReg, 1<<4)
+	dev := New(bus)
+
+	require.NoError(t, dev.Init())
+	assert.Equal(t, uint8(1<<2), bus.reg(ConfigReg), "PWM mode with tach input")
+	assert.Equal(t, uint8(fanConfigProg), bus.reg(FanConfigReg)&fanConfigProg, "lookup table disabled")
+	assert.Zero(t, bus.reg(FanSpinUpReg)&(1<<5), "spin up ignores tach")
+}
+
+func TestEMC2101_Temperatures(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+
+	for _, temp := range []int8{0, 42, 127, -1, -40, -128} {
+		bus.setInternalTemperature(temp)
+		got, err := dev.InternalTemperature()
+		require.NoError(t, err)
+		assert.Equal(t, float32(temp), got)
+	}
+
+	for _, temp := range []float32{0, 0.125, 25.5, 85.875, 127.75, -0.125, -12.625, -64} {
+		bus.setExternalTemperature(temp)
+		got, err := dev.ExternalTemperature()
+		require.NoError(t, err)
+		assert.Equal(t, temp, got)
+	}
+}
+
+func TestEMC2101_DiodeFault(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+
+	bus.preset(ExternalTempReg, 0x7f)
+	bus.preset(ExternalTempLowReg, 0xe0)
+	bus.setStatus(StatusDiodeFault)
+	_, err := dev.ExternalTemperature()
+	assert.ErrorIs(t, err, ErrDiodeFault)
+
+	// Same reading without a fault is a valid temperature
+	temp, err := dev.ExternalTemperature()
+	require.NoError(t, err)
+	assert.Equal(t, float32(127.875), temp)
+}
+
+func TestEMC2101_FanRPM(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+
+	// Power-on default, no tach pulses
+	rpm, err := dev.FanRPM()
+	require.NoError(t, err)
+	assert.Zero(t, rpm)
+
+	bus.setTachCount(0)
+	rpm, err = dev.FanRPM()
+	require.NoError(t, err)
+	assert.Zero(t, rpm)
+
+	bus.setTachCount(2700)
+	rpm, err = dev.FanRPM()
+	require.NoError(t, err)
+	assert.Equal(t, float32(2000), rpm)
+
+	bus.setTachCount(0x0100)
+	rpm, err = dev.FanRPM()
+	require.NoError(t, err)
+	assert.InDelta(t, 21093.75, rpm, 0.01)
+}
+
+func TestEMC2101_SetFanPercent(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+	require.NoError(t, dev.Init())
+
+	for percent, setting := range map[uint8]uint8{0: 0, 50: 31, 100: maxFanSetting, 255: maxFanSetting} {
+		require.NoError(t, dev.SetFanPercent(percent))
+		assert.Equal(t, setting, bus.reg(FanSettingReg), "percent %d", percent)
+	}
+}
+
+func TestEMC2101_SetLUTEnabled(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+	require.NoError(t, dev.Init())
+	require.NoError(t, dev.SetFanPercent(100))
+
+	require.NoError(t, dev.SetLUTEnabled(true))
+	assert.Zero(t, bus.reg(FanConfigReg)&fanConfigProg)
+
+	// The fan setting is read-only while the lookup table drives the fan
+	require.NoError(t, dev.SetFanPercent(0))
+	assert.Equal(t, uint8(maxFanSetting), bus.reg(FanSettingReg))
+
+	require.NoError(t, dev.SetLUTEnabled(false))
+	assert.Equal(t, uint8(fanConfigProg), bus.reg(FanConfigReg)&fanConfigProg)
+	require.NoError(t, dev.SetFanPercent(0))
+	assert.Zero(t, bus.reg(FanSettingReg))
+}
+
+func TestEMC2101_SetLUT(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+	require.NoError(t, dev.Init())
+	require.NoError(t, dev.SetLUTEnabled(true))
+
+	steps := []LUTStep{{Temperature: 30, Percent: 40}, {Temperature: 45, Percent: 50}, {Temperature: 60, Percent: 100}}
+	require.NoError(t, dev.SetLUT(steps))
+	lut := bus.lut()
+	assert.Equal(t, steps, lut[:len(steps)])
+	for _, step := range lut[len(steps):] {
+		assert.Equal(t, LUTStep{Temperature: maxLUTTemperature, Percent: 100}, step, "unused steps repeat the last step")
+	}
+	assert.Zero(t, bus.reg(FanConfigReg)&fanConfigProg, "lookup table re-enabled")
+
+	require.NoError(t, dev.SetLUTHysteresis(2))
+	assert.Equal(t, uint8(2), bus.reg(LUTHysteresisReg))
+	assert.Zero(t, bus.reg(FanConfigReg)&fanConfigProg, "lookup table re-enabled")
+
+	// Programming doesn't enable a disabled lookup table
+	require.NoError(t, dev.SetLUTEnabled(false))
+	require.NoError(t, dev.SetLUT(steps[:1]))
+	assert.Equal(t, uint8(fanConfigProg), bus.reg(FanConfigReg)&fanConfigProg)
+	assert.Equal(t, steps[0], bus.lut()[0])
+
+	for _, invalid := range [][]LUTStep{
+		nil,
+		make([]LUTStep, MaxLUTSteps+1),
+		{{Temperature: 30, Percent: 40}, {Temperature: 30, Percent: 50}},
+		{{Temperature: 128, Percent: 40}},
+		{{Temperature: 30, Percent: 101}},
+	} {
+		assert.ErrorIs(t, dev.SetLUT(invalid), ErrInvalidLUT)
+	}
+	assert.ErrorIs(t, dev.SetLUTHysteresis(maxLUTHysteresis+1), ErrInvalidLUT)
+}
+
+func TestEMC2101_ConfigureExternalDiode(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+
+	require.NoError(t, dev.ConfigureExternalDiode(ExternalDiode{IdealityFactor: 0x10}))
+	assert.Equal(t, uint8(0x10), bus.reg(IdealityFactorReg))
+	assert.Equal(t, uint8(betaConfigDisabled), bus.reg(BetaConfigReg))
+
+	require.NoError(t, dev.ConfigureExternalDiode(ExternalDiode{IdealityFactor: 0x12, BetaCompensation: true}))
+	assert.Equal(t, uint8(0x12), bus.reg(IdealityFactorReg))
+	assert.Equal(t, uint8(betaConfigAuto), bus.reg(BetaConfigReg))
+
+	assert.ErrorIs(t, dev.ConfigureExternalDiode(ExternalDiode{IdealityFactor: maxIdealityFactor + 1}), ErrInvalidExternalDiode)
+}
+
+func TestEMC2101_Status(t *testing.T) {
+	t.Parallel()
+
+	bus := newFakeEMC2101()
+	dev := New(bus)
+
+	bus.setStatus(StatusTach | StatusExternalHigh | StatusBusy)
+	status, err := dev.Status()
+	require.NoError(t, err)
+	assert.True(t, status.Has(StatusTach|StatusExternalHigh))
+	assert.False(t, status.Has(StatusDiodeFault))
+
+	// Alert bits are cleared by reading
+	status, err = dev.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StatusBusy, status)
+}
+
+func TestEMC2101_BusErrors(t *testing.T) {
+	t.Parallel()
+
+	busErr := errors.New("bus locked up")
+	bus := newFakeEMC2101()
+	bus.setError(busErr)
+	dev := New(bus)
+
+	assert.ErrorIs(t, dev.Init(), busErr)
+	_, err := dev.InternalTemperature()
+	assert.ErrorIs(t, err, busErr)
+	_, err = dev.ExternalTemperature()
+	assert.ErrorIs(t, err, busErr)
+	_, err = dev.FanRPM()
+	assert.ErrorIs(t, err, busErr)
+	_, err = dev.Status()
+	assert.ErrorIs(t, err, busErr)
+	assert.ErrorIs(t, dev.SetFanPercent(50), busErr)
+	assert.ErrorIs(t, dev.SetLUTEnabled(true), busErr)
+	assert.ErrorIs(t, dev.SetLUT([]LUTStep{{Temperature: 30, Percent: 40}}), busErr)
+	assert.ErrorIs(t, dev.SetLUTHysteresis(4), busErr)
+	assert.ErrorIs(t, dev.ConfigureExternalDiode(ExternalDiode{IdealityFactor: 0x12}), busErr)
+}
@@ -0,0 +1,164 @@
+//go:build !tinygo
+
+package emc2101
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"tinygo.org/x/drivers"
+)
+
+const (
+	productIDReg      = 0xfd
+	manufacturerIDReg = 0xfe
+)
+
+// fakeEMC2101 is an in-memory EMC2101 on an I2C bus, modelling the register map at register level
+type fakeEMC2101 struct {
+	mu   sync.Mutex
+	regs [256]uint8
+	// latchedTempLow and latchedTachHigh are latched when reading the other byte of a 16 bit reading
+	latchedTempLow  uint8
+	latchedTachHigh uint8
+	// err fails all transactions, e.g. to simulate a locked up bus
+	err error
+}
+
+// fails if fakeEMC2101 does not implement drivers.I2C
+var _ drivers.I2C = &fakeEMC2101{}
+
+// newFakeEMC2101 returns a fake with the power-on defaults of the datasheet
+func newFakeEMC2101() *fakeEMC2101 {
+	f := &fakeEMC2101{}
+	f.regs[IdealityFactorReg] = 0x12
+	f.regs[BetaConfigReg] = betaConfigAuto
+	f.regs[FanConfigReg] = 0x20
+	f.regs[FanSpinUpReg] = 0x3f
+	f.regs[FanTachReadingLowReg] = 0xff
+	f.regs[FanTachReadingHighReg] = 0xff
+	f.regs[productIDReg] = 0x16
+	f.regs[manufacturerIDReg] = 0x5d
+	return f
+}
+
+func (f *fakeEMC2101) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	if addr != Address {
+		return fmt.Errorf("no device at address 0x%02x", addr)
+	}
+	if len(w) == 0 {
+		return fmt.Errorf("missing register address")
+	}
+	if len(w) > 2 {
+		return fmt.Errorf("block write of %d registers not supported", len(w)-1)
+	}
+
+	reg := w[0]
+	if len(w) == 2 {
+		f.write(reg, w[1])
+	}
+	for i := range r {
+		r[i] = f.read(reg + uint8(i))
+	}
+	return nil
+}
+
+func (f *fakeEMC2101) write(reg, value uint8) {
+	switch {
+	case reg == InternalTempReg, reg == ExternalTempReg, reg == StatusReg, reg == ExternalTempLowReg,
+		reg == FanTachReadingLowReg, reg == FanTachReadingHighReg, reg >= productIDReg:
+		// read-only
+	case (reg == FanSettingReg || reg >= LUTHysteresisReg && reg < LUTStartReg+2*MaxLUTSteps) &&
+		f.regs[FanConfigReg]&fanConfigProg == 0:
+		// read-only while the lookup table drives the fan
+	default:
+		f.regs[reg] = value
+	}
+}
+
+func (f *fakeEMC2101) read(reg uint8) uint8 {
+	switch reg {
+	case ExternalTempReg:
+		f.latchedTempLow = f.regs[ExternalTempLowReg]
+		return f.regs[reg]
+	case ExternalTempLowReg:
+		return f.latchedTempLow
+	case FanTachReadingLowReg:
+		f.latchedTachHigh = f.regs[FanTachReadingHighReg]
+		return f.regs[reg]
+	case FanTachReadingHighReg:
+		return f.latchedTachHigh
+	case StatusReg:
+		// Alert bits are cleared by reading, busy reflects the ADC
+		status := f.regs[reg]
+		f.regs[reg] &= uint8(StatusBusy)
+		return status
+	default:
+		return f.regs[reg]
+	}
+}
+
+// reg returns the value of a register without side effects
+func (f *fakeEMC2101) reg(reg uint8) uint8 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.regs[reg]
+}
+
+// lut returns the programmed steps of the lookup table, fan settings are converted to percent
+func (f *fakeEMC2101) lut() []LUTStep {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	steps := make([]LUTStep, MaxLUTSteps)
+	for i := range steps {
+		steps[i] = LUTStep{
+			Temperature: f.regs[LUTStartReg+2*i],
+			Percent:     uint8(math.Ceil(float64(f.regs[LUTStartReg+2*i+1]) * 100 / maxFanSetting)),
+		}
+	}
+	return steps
+}
+
+// preset sets a register bypassing the access rules, e.g. to simulate measurements
+func (f *fakeEMC2101) preset(reg, value uint8) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[reg] = value
+}
+
+func (f *fakeEMC2101) setInternalTemperature(temp int8) {
+	f.preset(InternalTempReg, uint8(temp))
+}
+
+func (f *fakeEMC2101) setExternalTemperature(temp float32) {
+	raw := uint16(int16(math.Round(float64(temp/externalTempResolution)))) << 5
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[ExternalTempReg] = uint8(raw >> 8)
+	f.regs[ExternalTempLowReg] = uint8(raw)
+}
+
+func (f *fakeEMC2101) setTachCount(count uint16) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[FanTachReadingHighReg] = uint8(count >> 8)
+	f.regs[FanTachReadingLowReg] = uint8(count)
+}
+
+func (f *fakeEMC2101) setStatus(status Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[StatusReg] |= uint8(status)
+}
+
+func (f *fakeEMC2101) setError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
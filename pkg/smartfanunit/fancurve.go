@@ -0,0 +1,127 @@
+package smartfanunit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+// MaxFanCurveSteps is the maximum number of steps of a fan curve
+const MaxFanCurveSteps = 8
+
+// MinFanCurveWatchdogTimeout is the minimum watchdog timeout of a fan curve. It clearly exceeds the interval the
+// agent sends fan speed requests in (5s), so the curve doesn't take over between two requests.
+const MinFanCurveWatchdogTimeout = 10 * time.Second
+
+var ErrInvalidFanCurve = errors.New("invalid fan curve")
+
+// FanCurveSensor is the temperature sensor of the fan unit a fan curve is based on
+type FanCurveSensor uint8
+
+const (
+	// FanCurveSensorInternal is the internal temperature sensor of the EMC2101
+	FanCurveSensorInternal FanCurveSensor = iota
+	// FanCurveSensorExternal is the external temperature diode of the EMC2101
+	FanCurveSensorExternal
+	// FanCurveSensorMax is the maximum of the internal and the external temperature
+	FanCurveSensorMax
+)
+
+// FanCurveStep is a point of a fan curve
+type FanCurveStep struct {
+	// Temperature in °C
+	Temperature uint8
+	// Percent is the fan speed at the temperature
+	Percent uint8
+}
+
+// FanCurve maps the temperature of the fan unit to a fan speed.
+// The fan speed is linearly interpolated between the steps.
+type FanCurve struct {
+	Sensor FanCurveSensor
+	// WatchdogTimeout is the time without fan speed requests of a blade after which the curve is applied.
+	// It's transmitted in seconds, MinFanCurveWatchdogTimeout to 255s.
+	WatchdogTimeout time.Duration
+	// Steps in ascending temperature order
+	Steps []FanCurveStep
+}
+
+// Validate checks if the fan curve can be applied by the fan unit
+func (c *FanCurve) Validate() error {
+	if c.Sensor > FanCurveSensorMax {
+		return ErrInvalidFanCurve
+	}
+	if c.WatchdogTimeout < MinFanCurveWatchdogTimeout || c.WatchdogTimeout > 255*time.Second {
+		return ErrInvalidFanCurve
+	}
+	if len(c.Steps) < 2 || len(c.Steps) > MaxFanCurveSteps {
+		return ErrInvalidFanCurve
+	}
+	for i, step := range c.Steps {
+		if step.Percent > 100 {
+			return ErrInvalidFanCurve
+		}
+		if i > 0 && step.Temperature <= c.Steps[i-1].Temperature {
+			return ErrInvalidFanCurve
+		}
+	}
+	return nil
+}
+
+// Percent returns the fan speed at the given temperature
+func (c *FanCurve) Percent(temperature float32) uint8 {
+	if len(c.Steps) == 0 {
+		return 100
+	}
+	if temperature <= float32(c.Steps[0].Temperature) {
+		return c.Steps[0].Percent
+	}
+	for i := 1; i < len(c.Steps); i++ {
+		lower, upper := c.Steps[i-1], c.Steps[i]
+		if temperature >= float32(upper.Temperature) {
+			continue
+		}
+		slope := (float32(upper.Percent) - float32(lower.Percent)) / float32(upper.Temperature-lower.Temperature)
+		return uint8(float32(lower.Percent) + slope*(temperature-float32(lower.Temperature)))
+	}
+	return c.Steps[len(c.Steps)-1].Percent
+}
+
+// SetFanCurvePacket is sent from the blade to the fan unit to configure the fan curve.
+// It's sent as v2 frame with the payload: sensor, watchdog timeout in seconds, temperature/percent of each step.
+type SetFanCurvePacket struct {
+	Curve FanCurve
+}
+
+func (p *SetFanCurvePacket) Packet() proto.Packet {
+	payload := make([]uint8, 0, 2+2*len(p.Curve.Steps))
+	payload = append(payload, uint8(p.Curve.Sensor), uint8(p.Curve.WatchdogTimeout/time.Second))
+	for _, step := range p.Curve.Steps {
+		payload = append(payload, step.Temperature, step.Percent)
+	}
+	pkt := proto.Packet{
+		Command: CmdSetFanCurve,
+		Payload: payload,
+	}
+	copy(pkt.Data[:], payload)
+	return pkt
+}
+
+func (p *SetFanCurvePacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != CmdSetFanCurve {
+		return ErrInvalidCommand
+	}
+	if len(packet.Payload) < 2 || len(packet.Payload)%2 != 0 {
+		return ErrInvalidFanCurve
+	}
+	p.Curve = FanCurve{
+		Sensor:          FanCurveSensor(packet.Payload[0]),
+		WatchdogTimeout: time.Duration(packet.Payload[1]) * time.Second,
+		Steps:           make([]FanCurveStep, 0, len(packet.Payload)/2-1),
+	}
+	for i := 2; i < len(packet.Payload); i += 2 {
+		p.Curve.Steps = append(p.Curve.Steps, FanCurveStep{Temperature: packet.Payload[i], Percent: packet.Payload[i+1]})
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+//go:build !tinygo
+
+package smartfanunit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+func testFanCurve() FanCurve {
+	return FanCurve{
+		Sensor:          FanCurveSensorMax,
+		WatchdogTimeout: 30 * time.Second,
+		Steps: []FanCurveStep{
+			{Temperature: 30, Percent: 20},
+			{Temperature: 40, Percent: 60},
+			{Temperature: 50, Percent: 100},
+		},
+	}
+}
+
+func TestFanCurve_Percent(t *testing.T) {
+	t.Parallel()
+
+	curve := testFanCurve()
+	testCases := []struct {
+		temperature float32
+		expected    uint8
+	}{
+		{20, 20},  // Below the first step
+		{35, 40},  // Between the first and second step
+		{40, 60},  // At a step
+		{45, 80},  // Between the second and third step
+		{70, 100}, // Above the last step
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, curve.Percent(tc.temperature), "temperature %v", tc.temperature)
+	}
+}
+
+func TestFanCurve_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := testFanCurve()
+	assert.NoError(t, valid.Validate())
+
+	invalid := map[string]func(c *FanCurve){
+		"unknown sensor":     func(c *FanCurve) { c.Sensor = FanCurveSensorMax + 1 },
+		"no watchdog":        func(c *FanCurve) { c.WatchdogTimeout = 0 },
+		"watchdog too short": func(c *FanCurve) { c.WatchdogTimeout = 5 * time.Second },
+		"watchdog too long":  func(c *FanCurve) { c.WatchdogTimeout = 5 * time.Minute },
+		"single step":        func(c *FanCurve) { c.Steps = c.Steps[:1] },
+		"too many steps":     func(c *FanCurve) { c.Steps = make([]FanCurveStep, MaxFanCurveSteps+1) },
+		"descending steps":   func(c *FanCurve) { c.Steps[1].Temperature = 20 },
+		"speed exceeds 100%": func(c *FanCurve) { c.Steps[2].Percent = 101 },
+	}
+	for name, modify := range invalid {
+		curve := testFanCurve()
+		modify(&curve)
+		assert.ErrorIs(t, curve.Validate(), ErrInvalidFanCurve, name)
+	}
+}
+
+func TestSetFanCurvePacket(t *testing.T) {
+	t.Parallel()
+
+	setFanCurve := SetFanCurvePacket{Curve: testFanCurve()}
+	pkt := setFanCurve.Packet()
+	assert.Equal(t, []uint8{uint8(FanCurveSensorMax), 30, 30, 20, 40, 60, 50, 100}, pkt.Payload)
+	assert.Equal(t, uint8(proto.CRC16(pkt.Payload)), Sequence(pkt))
+
+	// The curve exceeds the data of v1 frames and is sent as v2 frame
+	var buffer bytes.Buffer
+	assert.NoError(t, proto.WritePacketV2(context.TODO(), &buffer, pkt))
+	read, err := proto.ReadPacket(context.TODO(), &buffer)
+	assert.NoError(t, err)
+
+	var parsed SetFanCurvePacket
+	assert.NoError(t, parsed.FromPacket(read))
+	assert.Equal(t, setFanCurve, parsed)
+
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: CmdSetFanCurve, Data: proto.Data{1, 2, 3}}), ErrInvalidFanCurve)
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: CmdSetLED}), ErrInvalidCommand)
+}
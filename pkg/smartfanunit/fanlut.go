@@ -0,0 +1,88 @@
+package smartfanunit
+
+import (
+	"errors"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+const (
+	// MaxFanLUTSteps is the maximum number of steps of a lookup table
+	MaxFanLUTSteps = 8
+	// MaxFanLUTTemperature is the highest temperature of a lookup table step in °C
+	MaxFanLUTTemperature = 127
+	// MaxFanLUTHysteresis is the highest hysteresis of a lookup table in °C
+	MaxFanLUTHysteresis = 31
+)
+
+var ErrInvalidFanLUT = errors.New("invalid fan lookup table")
+
+// FanLUT is the lookup table of the EMC2101, mapping the temperature of the external diode to a fan speed.
+// Unlike a fan curve, the lookup table is evaluated by the EMC2101 itself, so it keeps working if the firmware hangs.
+// The speed of the highest step not above the temperature applies, without interpolation.
+// While a lookup table is set, it drives the fan instead of the fan speed requests of the blades, except for requests
+// of 100% overriding it.
+type FanLUT struct {
+	// Hysteresis in °C the temperature has to drop below a step before the speed of the step below applies
+	Hysteresis uint8
+	// Steps in ascending temperature order, no steps release the lookup table of the blade
+	Steps []FanCurveStep
+}
+
+// Validate checks if the lookup table can be programmed by the fan unit
+func (l *FanLUT) Validate() error {
+	if l.Hysteresis > MaxFanLUTHysteresis || len(l.Steps) > MaxFanLUTSteps {
+		return ErrInvalidFanLUT
+	}
+	for i, step := range l.Steps {
+		if step.Temperature > MaxFanLUTTemperature || step.Percent > 100 {
+			return ErrInvalidFanLUT
+		}
+		if i > 0 && step.Temperature <= l.Steps[i-1].Temperature {
+			return ErrInvalidFanLUT
+		}
+	}
+	return nil
+}
+
+// Enabled returns true if the lookup table drives the fan
+func (l *FanLUT) Enabled() bool {
+	return len(l.Steps) > 0
+}
+
+// SetFanLUTPacket is sent from the blade to the fan unit to program the lookup table of the EMC2101.
+// It's sent as v2 frame with the payload: hysteresis, temperature/percent of each step.
+type SetFanLUTPacket struct {
+	LUT FanLUT
+}
+
+func (p *SetFanLUTPacket) Packet() proto.Packet {
+	payload := make([]uint8, 0, 1+2*len(p.LUT.Steps))
+	payload = append(payload, p.LUT.Hysteresis)
+	for _, step := range p.LUT.Steps {
+		payload = append(payload, step.Temperature, step.Percent)
+	}
+	pkt := proto.Packet{
+		Command: CmdSetFanLUT,
+		Payload: payload,
+	}
+	copy(pkt.Data[:], payload)
+	return pkt
+}
+
+func (p *SetFanLUTPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != CmdSetFanLUT {
+		return ErrInvalidCommand
+	}
+	if len(packet.Payload)%2 != 1 {
+		return ErrInvalidFanLUT
+	}
+	p.LUT = FanLUT{
+		Hysteresis: packet.Payload[0],
+		Steps:      make([]FanCurveStep, 0, len(packet.Payload)/2),
+	}
+	for i := 1; i < len(packet.Payload); i += 2 {
+		p.LUT.Steps = append(p.LUT.Steps, FanCurveStep{Temperature: packet.Payload[i], Percent: packet.Payload[i+1]})
+	}
+	return nil
+}
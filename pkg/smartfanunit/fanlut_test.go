@@ -0,0 +1,76 @@
+//go:build !tinygo
+
+package smartfanunit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+func testFanLUT() FanLUT {
+	return FanLUT{
+		Hysteresis: 4,
+		Steps: []FanCurveStep{
+			{Temperature: 30, Percent: 40},
+			{Temperature: 45, Percent: 60},
+			{Temperature: 60, Percent: 100},
+		},
+	}
+}
+
+func TestFanLUT_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := testFanLUT()
+	assert.NoError(t, valid.Validate())
+	assert.True(t, valid.Enabled())
+
+	disabled := FanLUT{}
+	assert.NoError(t, disabled.Validate())
+	assert.False(t, disabled.Enabled())
+
+	invalid := map[string]func(l *FanLUT){
+		"hysteresis too high": func(l *FanLUT) { l.Hysteresis = MaxFanLUTHysteresis + 1 },
+		"too many steps":      func(l *FanLUT) { l.Steps = make([]FanCurveStep, MaxFanLUTSteps+1) },
+		"descending steps":    func(l *FanLUT) { l.Steps[1].Temperature = 20 },
+		"temperature too high": func(l *FanLUT) {
+			l.Steps[2].Temperature = MaxFanLUTTemperature + 1
+		},
+		"speed exceeds 100%": func(l *FanLUT) { l.Steps[2].Percent = 101 },
+	}
+	for name, modify := range invalid {
+		lut := testFanLUT()
+		modify(&lut)
+		assert.ErrorIs(t, lut.Validate(), ErrInvalidFanLUT, name)
+	}
+}
+
+func TestSetFanLUTPacket(t *testing.T) {
+	t.Parallel()
+
+	setFanLUT := SetFanLUTPacket{LUT: testFanLUT()}
+	pkt := setFanLUT.Packet()
+	assert.Equal(t, []uint8{4, 30, 40, 45, 60, 60, 100}, pkt.Payload)
+	assert.Equal(t, uint8(proto.CRC16(pkt.Payload)), Sequence(pkt))
+
+	var buffer bytes.Buffer
+	assert.NoError(t, proto.WritePacketV2(context.TODO(), &buffer, pkt))
+	read, err := proto.ReadPacket(context.TODO(), &buffer)
+	assert.NoError(t, err)
+
+	var parsed SetFanLUTPacket
+	assert.NoError(t, parsed.FromPacket(read))
+	assert.Equal(t, setFanLUT, parsed)
+
+	// A lookup table without steps disables it
+	disable := SetFanLUTPacket{}
+	assert.NoError(t, parsed.FromPacket(disable.Packet()))
+	assert.False(t, parsed.LUT.Enabled())
+
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: CmdSetFanLUT, Payload: []uint8{4, 30}}), ErrInvalidFanLUT)
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: CmdSetLED}), ErrInvalidCommand)
+}
@@ -0,0 +1,743 @@
+// Package firmware implements the smart fan unit firmware.
+// The hardware is accessed through interfaces, so the controller runs on the RP2040 (see cmd/fanunit) as well as on
+// the host against simulated hardware (see Simulator).
+package firmware
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/eventbus"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/emc2101"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	leftBladeTopicIn   = "left:in"
+	leftBladeTopicOut  = "left:out"
+	rightBladeTopicIn  = "right:in"
+	rightBladeTopicOut = "right:out"
+	// fanControllerTopic is notified once the EMC2101 has been reinitialised
+	fanControllerTopic = "fan:reinit"
+)
+
+// capabilities are the optional protocol features supported by the firmware
+const capabilities = smartfanunit.CapabilityAck | smartfanunit.CapabilityFrameV2 | smartfanunit.CapabilityFanCurve |
+	smartfanunit.CapabilityHeartbeat | smartfanunit.CapabilityFanLUT | smartfanunit.CapabilityLEDSequence
+
+const (
+	// fanUpdateInterval is the interval in which expired requests and the fan curve are evaluated
+	fanUpdateInterval = time.Second
+	// bladeAliveTimeout is the time without packets from a blade after which its fan speed request expires,
+	// blades send heartbeats every 2 seconds
+	bladeAliveTimeout = 10 * time.Second
+	// maxBusRecoveryAttempts is the number of I2C bus recoveries after EMC2101 errors before the CPU is reset
+	maxBusRecoveryAttempts = 3
+	// busRecoveryBackoff is the delay after a failed recovery, multiplied by the attempt
+	busRecoveryBackoff = 100 * time.Millisecond
+)
+
+// LEDs is the chain of WS2812 LEDs of both blades, e.g. a ws2812.Device
+type LEDs interface {
+	// Write writes the colors of the chain, 3 bytes per LED
+	Write(buf []byte) (int, error)
+}
+
+// Button is the button of the fan unit
+type Button interface {
+	// SetInterrupt registers the callback called on every button press
+	SetInterrupt(callback func()) error
+}
+
+// Controller routes packets between the blades and the fan unit hardware
+type Controller struct {
+	FirmwareVersion smartfanunit.FirmwareVersion
+	DefaultFanSpeed uint8
+	LEDs            LEDs
+	FanController   emc2101.EMC2101
+	// ExternalDiode configures the external temperature diode on startup, the power-on defaults are kept if nil
+	ExternalDiode *emc2101.ExternalDiode
+	Button        Button
+	// Reset resets the CPU, e.g. machine.CPUReset. It's the last resort if the EMC2101 can't be recovered.
+	Reset func()
+	// RecoverBus frees a locked up I2C bus, e.g. by clocking out a stuck SDA, and reinitialises the I2C peripheral.
+	// The EMC2101 is reinitialised afterward. The CPU is reset on EMC2101 errors if nil.
+	RecoverBus func() error
+
+	// LeftUART and RightUART are the serial links to the blades, reads are polled if they implement drivers.UART
+	LeftUART  io.ReadWriter
+	RightUART io.ReadWriter
+
+	// BladeAliveTimeout is the time without packets from a blade after which its fan speed request expires,
+	// defaults to 10 seconds
+	BladeAliveTimeout time.Duration
+
+	eb               eventbus.EventBus
+	leftLed          led.Color
+	rightLed         led.Color
+	leftReqFanSpeed  uint8
+	rightReqFanSpeed uint8
+	// leftLastReq and rightLastReq are the times of the last fan speed requests, zero if none has been received
+	leftLastReq  time.Time
+	rightLastReq time.Time
+	// fanCurves and fanLUTs are the fan curves and lookup tables configured by the blades, indexed by their side.
+	// Those of a blade are released once it sends a hello, its agent pushes them again if still enabled.
+	fanCurves [2]*smartfanunit.FanCurve
+	fanLUTs   [2]*smartfanunit.FanLUT
+	// fanCurveOwner and fanLUTOwner are the blades whose fan curve and lookup table apply, the last to configure one
+	fanCurveOwner smartfanunit.BladeSide
+	fanLUTOwner   smartfanunit.BladeSide
+	// fanCurve is applied for blades not sending fan speed requests, nil until configured by a blade
+	fanCurve *smartfanunit.FanCurve
+	// fanLUT is the lookup table driving the fan, nil while the fan speed requests apply
+	fanLUT *smartfanunit.FanLUT
+
+	// mu guards the state shared between the blade listeners, the fan update loop and the metric reporter
+	mu sync.Mutex
+	// leftLastSeen and rightLastSeen are the times of the last packets, zero if none has been received
+	leftLastSeen  time.Time
+	rightLastSeen time.Time
+	// internalTemp and externalTemp are the last temperatures read from the EMC2101
+	internalTemp float32
+	externalTemp float32
+
+	buttonPressed atomic.Int32
+
+	// busRecovery counts the I2C bus recoveries, only accessed by the metric reporter
+	busRecovery smartfanunit.BusRecoveryStats
+}
+
+func (c *Controller) Run(parentCtx context.Context) error {
+	c.eb = eventbus.New()
+	if c.BladeAliveTimeout == 0 {
+		c.BladeAliveTimeout = bladeAliveTimeout
+	}
+
+	if err := c.initFanController(); err != nil {
+		// e.g. the bus locked up by a reset during a transfer, the CPU is reset if it can't be recovered
+		println("[!] failed to initialize fan controller:", err.Error())
+		c.recoverFanController(parentCtx)
+	}
+	c.FanController.SetFanPercent(c.DefaultFanSpeed)
+	c.LEDs.Write([]byte{0, 0, 0, 0, 0, 0})
+
+	group, ctx := errgroup.WithContext(parentCtx)
+
+	// LED Update events
+	println("[+] Starting LED update loop")
+	group.Go(func() error {
+		return c.updateLEDs(ctx)
+	})
+
+	// Fan speed update events
+	println("[+] Starting fan update loop")
+	group.Go(func() error {
+		return c.updateFanSpeed(ctx)
+	})
+
+	// Metric reporting events
+	println("[+] Starting metric reporting loop")
+	group.Go(func() error {
+		return c.metricReporter(ctx)
+	})
+
+	// Left blade events
+	println("[+] Starting event listener (left)")
+	group.Go(func() error {
+		return c.listenEvents(ctx, c.LeftUART, smartfanunit.BladeSideLeft, leftBladeTopicIn, leftBladeTopicOut)
+	})
+	println("[+] Starting event dispatcher (left)")
+	group.Go(func() error {
+		return c.dispatchEvents(ctx, c.LeftUART, leftBladeTopicOut)
+	})
+
+	// right blade events
+	println("[+] Starting event listener (righ)")
+	group.Go(func() error {
+		return c.listenEvents(ctx, c.RightUART, smartfanunit.BladeSideRight, rightBladeTopicIn, rightBladeTopicOut)
+	})
+	println("[+] Starting event dispatcher (right)")
+	group.Go(func() error {
+		return c.dispatchEvents(ctx, c.RightUART, rightBladeTopicOut)
+	})
+
+	// Button Press events
+	println("[+] Starting button interrupt handler")
+	if err := c.Button.SetInterrupt(func() { c.buttonPressed.Add(1) }); err != nil {
+		return err
+	}
+
+	group.Go(func() error {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				btnPressed := smartfanunit.ButtonPressPacket{}
+				if c.buttonPressed.Load() > 0 {
+					// Allow up to 600ms for a 2nc button press
+					time.Sleep(600 * time.Millisecond)
+				}
+
+				switch c.buttonPressed.Swap(0) {
+				case 1:
+					println("[ ] Button pressed once")
+					c.eb.Publish(leftBladeTopicOut, btnPressed.Packet())
+				case 2:
+					println("[ ] Button pressed twice")
+					c.eb.Publish(rightBladeTopicOut, btnPressed.Packet())
+				}
+			}
+		}
+	})
+	return group.Wait()
+}
+
+// listenEvents reads events from the UART interface and dispatches them to the eventbus.
+// Invalid commands are rejected to the blade through replyTopic, valid ones are acknowledged once applied.
+// Heartbeats are answered with the blade status.
+func (c *Controller) listenEvents(ctx context.Context, uart io.Reader, side smartfanunit.BladeSide, targetTopic string, replyTopic string) error {
+	dec := proto.NewDecoder(uart)
+	for {
+		// Read packet from UART; blocks until packet is received
+		pkt, err := dec.ReadPacket(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			println("[!] failed to read packet, continuing..", err.Error())
+			continue
+		}
+		c.mu.Lock()
+		*c.lastSeen(side) = time.Now()
+		c.mu.Unlock()
+
+		if pkt.Command == smartfanunit.CmdHeartbeat {
+			status := smartfanunit.BladeStatusPacket{Status: c.bladeStatus(side)}
+			c.eb.Publish(replyTopic, status.Packet())
+			continue
+		}
+
+		if pkt.Command == smartfanunit.CmdHello {
+			println("[ ] received hello from UART")
+			// The agent of the blade (re)connected, its fan curve and lookup table are released until pushed again
+			c.eb.Publish(targetTopic, pkt)
+			c.publishHello(replyTopic)
+			continue
+		}
+
+		if reason, ok := validateCommand(pkt); !ok {
+			println("[!] rejecting packet from UART:", reason.String())
+			nack := smartfanunit.NackPacket{Command: pkt.Command, Sequence: smartfanunit.Sequence(pkt), Reason: reason}
+			c.eb.Publish(replyTopic, nack.Packet())
+			continue
+		}
+
+		// Commands dropped by a busy subscriber aren't acknowledged, so the blade retries them
+		println("[ ] received packet from UART publishing to topic", targetTopic)
+		c.eb.Publish(targetTopic, pkt)
+	}
+}
+
+// ack acknowledges an applied command to the blade
+func (c *Controller) ack(topic string, pkt proto.Packet) {
+	ack := smartfanunit.AckPacket{Command: pkt.Command, Sequence: smartfanunit.Sequence(pkt)}
+	c.eb.Publish(topic, ack.Packet())
+}
+
+// publishHello announces the protocol version, capabilities and firmware version to a blade
+func (c *Controller) publishHello(topic string) {
+	hello := smartfanunit.HelloNotifyPacket{ProtocolVersion: smartfanunit.ProtocolVersion, Capabilities: capabilities}
+	version := smartfanunit.FirmwareVersionPacket{Version: c.FirmwareVersion}
+	c.eb.Publish(topic, hello.Packet())
+	c.eb.Publish(topic, version.Packet())
+}
+
+// lastSeen returns the time of the last packet from the blade on the given side, c.mu must be held
+func (c *Controller) lastSeen(side smartfanunit.BladeSide) *time.Time {
+	if side == smartfanunit.BladeSideLeft {
+		return &c.leftLastSeen
+	}
+	return &c.rightLastSeen
+}
+
+// isAlive returns true if a packet has been received from a blade within the alive timeout, c.mu must be held
+func (c *Controller) isAlive(lastSeen time.Time) bool {
+	return !lastSeen.IsZero() && time.Since(lastSeen) <= c.BladeAliveTimeout
+}
+
+// expired returns true if a blade has been seen but not within the alive timeout
+func (c *Controller) expired(side smartfanunit.BladeSide) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lastSeen := *c.lastSeen(side)
+	return !lastSeen.IsZero() && !c.isAlive(lastSeen)
+}
+
+// bladeStatus returns the status of both blades as seen by the blade on the given side
+func (c *Controller) bladeStatus(side smartfanunit.BladeSide) smartfanunit.BladeStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return smartfanunit.BladeStatus{
+		Side:       side,
+		LeftAlive:  c.isAlive(c.leftLastSeen),
+		RightAlive: c.isAlive(c.rightLastSeen),
+	}
+}
+
+// validateCommand checks if a command sent by a blade can be applied
+func validateCommand(pkt proto.Packet) (smartfanunit.NackReason, bool) {
+	switch pkt.Command {
+	case smartfanunit.CmdSetFanSpeedPercent:
+		var setFanSpeed smartfanunit.SetFanSpeedPercentPacket
+		if err := setFanSpeed.FromPacket(pkt); err != nil || setFanSpeed.Percent > 100 {
+			return smartfanunit.NackReasonInvalidValue, false
+		}
+		return 0, true
+	case smartfanunit.CmdSetLED:
+		return 0, true
+	case smartfanunit.CmdSetFanCurve:
+		var setFanCurve smartfanunit.SetFanCurvePacket
+		if err := setFanCurve.FromPacket(pkt); err != nil || setFanCurve.Curve.Validate() != nil {
+			return smartfanunit.NackReasonInvalidValue, false
+		}
+		return 0, true
+	case smartfanunit.CmdSetFanLUT:
+		var setFanLUT smartfanunit.SetFanLUTPacket
+		if err := setFanLUT.FromPacket(pkt); err != nil || setFanLUT.LUT.Validate() != nil {
+			return smartfanunit.NackReasonInvalidValue, false
+		}
+		return 0, true
+	default:
+		return smartfanunit.NackReasonInvalidCommand, false
+	}
+}
+
+// dispatchEvents reads events from the eventbus and writes them to the UART interface
+func (c *Controller) dispatchEvents(ctx context.Context, uart io.Writer, sourceTopic string) error {
+	sub := c.eb.Subscribe(sourceTopic, 8, eventbus.MatchAll)
+	defer sub.Unsubscribe()
+
+	// Announce the firmware on startup, so blades notice the fan unit has been (re)started
+	c.publishHello(sourceTopic)
+	for {
+		select {
+		case msg := <-sub.C():
+			println("[ ] dispatching event to UART from topic", sourceTopic)
+			pkt := msg.(proto.Packet)
+			err := proto.WritePacket(ctx, uart, pkt)
+			if err != nil {
+				println(err.Error())
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// initFanController initializes the EMC2101 and configures the external diode
+func (c *Controller) initFanController() error {
+	if err := c.FanController.Init(); err != nil {
+		return err
+	}
+	if c.ExternalDiode != nil {
+		return c.FanController.ConfigureExternalDiode(*c.ExternalDiode)
+	}
+	return nil
+}
+
+// recoverFanController recovers the I2C bus and reinitialises the EMC2101 after an error, with bounded retries.
+// The recovery counts are reported to both blades, the CPU is reset if the EMC2101 remains unreachable.
+func (c *Controller) recoverFanController(ctx context.Context) {
+	if c.RecoverBus == nil {
+		c.reset()
+		return
+	}
+
+	recovered := false
+	for attempt := 1; attempt <= maxBusRecoveryAttempts && !recovered; attempt++ {
+		println("[!] recovering I2C bus, attempt", attempt)
+		err := c.RecoverBus()
+		if err == nil {
+			err = c.initFanController()
+		}
+		if err == nil {
+			// Verify the EMC2101 responds again
+			_, err = c.FanController.FanRPM()
+		}
+		if err != nil {
+			println("[!] failed to recover I2C bus:", err.Error())
+			if c.busRecovery.FailedAttempts < 0xff {
+				c.busRecovery.FailedAttempts++
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(attempt) * busRecoveryBackoff):
+			}
+			continue
+		}
+		recovered = true
+		c.busRecovery.Recoveries++
+	}
+
+	recovery := smartfanunit.BusRecoveryPacket{Stats: c.busRecovery}
+	c.eb.Publish(leftBladeTopicOut, recovery.Packet())
+	c.eb.Publish(rightBladeTopicOut, recovery.Packet())
+	if !recovered {
+		c.reset()
+		return
+	}
+	println("[+] I2C bus recovered")
+	// Init drives the fan by the fan setting, the fan speed and lookup table have to be restored
+	c.eb.Publish(fanControllerTopic, struct{}{})
+}
+
+// reset resets the CPU after giving the dispatchers some time to send pending packets
+func (c *Controller) reset() {
+	println("[!] resetting CPU")
+	time.Sleep(100 * time.Millisecond)
+	c.Reset()
+}
+
+func (c *Controller) metricReporter(ctx context.Context) error {
+	var err error
+
+	ticker := time.NewTicker(2 * time.Second)
+	internalTemp := smartfanunit.SensorTemperaturePacket{Sensor: smartfanunit.TemperatureSensorInternal}
+	externalTemp := smartfanunit.SensorTemperaturePacket{Sensor: smartfanunit.TemperatureSensorExternal}
+	fanRpm := smartfanunit.FanSpeedRPMPacket{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		var readErr error
+		internalTemp.Temperature, err = c.FanController.InternalTemperature()
+		if err != nil {
+			println("[!] failed to read internal temperature:", err.Error())
+			readErr = err
+		}
+		temperature, err := c.FanController.ExternalTemperature()
+		switch {
+		case err == emc2101.ErrDiodeFault:
+			// Not a bus error, the last temperature is kept
+			println("[!] failed to read external temperature:", err.Error())
+		case err != nil:
+			println("[!] failed to read external temperature:", err.Error())
+			readErr = err
+		default:
+			externalTemp.Temperature = temperature
+		}
+		fanRpm.RPM, err = c.FanController.FanRPM()
+		if err != nil {
+			println("[!] failed to read fan RPM:", err.Error())
+			readErr = err
+		}
+
+		// Read errors are caused by a locked up I2C bus
+		if readErr != nil {
+			c.recoverFanController(ctx)
+			continue
+		}
+		c.mu.Lock()
+		c.internalTemp, c.externalTemp = internalTemp.Temperature, externalTemp.Temperature
+		c.mu.Unlock()
+
+		// Publish metrics, both sensors are reported to both blades
+		for _, topic := range []string{leftBladeTopicOut, rightBladeTopicOut} {
+			c.eb.Publish(topic, internalTemp.Packet())
+			c.eb.Publish(topic, externalTemp.Packet())
+			c.eb.Publish(topic, fanRpm.Packet())
+		}
+		// Agents not knowing the sensors receive one sensor as air flow temperature
+		airFlowTempLeft := smartfanunit.AirFlowTemperaturePacket{Temperature: internalTemp.Temperature}
+		airFlowTempRight := smartfanunit.AirFlowTemperaturePacket{Temperature: externalTemp.Temperature}
+		c.eb.Publish(leftBladeTopicOut, airFlowTempLeft.Packet())
+		c.eb.Publish(rightBladeTopicOut, airFlowTempRight.Packet())
+	}
+}
+
+// updateFanSpeed sets the fan speed to the max speed requested by the blades.
+// Requests of blades without packets within bladeAliveTimeout expire and revert to the default fan speed, so a
+// crashed blade doesn't keep the fan at its last requested speed.
+// Once a fan curve has been configured, it's applied for blades without a fan speed request within the watchdog
+// timeout, so the fan keeps up with the temperature if the agent of a blade stops.
+// While a lookup table is set, the EMC2101 drives the fan on its own and requests are only recorded. Requests of
+// 100%, e.g. of a blade in critical state, disable the lookup table until withdrawn, as it only sees the external diode.
+// Fan curves and lookup tables are tracked per blade, so a blade only releases its own (see setFanCurve, setFanLUT).
+// Commands are acknowledged once applied.
+func (c *Controller) updateFanSpeed(ctx context.Context) error {
+	var pkt smartfanunit.SetFanSpeedPercentPacket
+
+	subLeft := c.eb.Subscribe(leftBladeTopicIn, 1, smartfanunit.MatchCmd(smartfanunit.CmdSetFanSpeedPercent))
+	defer subLeft.Unsubscribe()
+	subRight := c.eb.Subscribe(rightBladeTopicIn, 1, smartfanunit.MatchCmd(smartfanunit.CmdSetFanSpeedPercent))
+	defer subRight.Unsubscribe()
+	// Hellos share a subscription with the configuration, so a release isn't handled after the configuration pushed
+	// right after the hello
+	matchConfig := func(pktAny any) bool {
+		return smartfanunit.MatchCmd(smartfanunit.CmdHello)(pktAny) ||
+			smartfanunit.MatchCmd(smartfanunit.CmdSetFanCurve)(pktAny) ||
+			smartfanunit.MatchCmd(smartfanunit.CmdSetFanLUT)(pktAny)
+	}
+	subConfigLeft := c.eb.Subscribe(leftBladeTopicIn, 2, matchConfig)
+	defer subConfigLeft.Unsubscribe()
+	subConfigRight := c.eb.Subscribe(rightBladeTopicIn, 2, matchConfig)
+	defer subConfigRight.Unsubscribe()
+	subReinit := c.eb.Subscribe(fanControllerTopic, 1, eventbus.MatchAll)
+	defer subReinit.Unsubscribe()
+
+	ticker := time.NewTicker(fanUpdateInterval)
+	defer ticker.Stop()
+
+	lastSpeed := -1
+	// lutEnabled is true while the lookup table of the EMC2101 drives the fan
+	lutEnabled := false
+	for {
+		// cmd is the command handled in this iteration, acknowledged through replyTopic once applied
+		var cmd proto.Packet
+		var replyTopic string
+		select {
+		case msg := <-subLeft.C():
+			cmd, replyTopic = msg.(proto.Packet), leftBladeTopicOut
+			pkt.FromPacket(cmd)
+			c.leftReqFanSpeed = pkt.Percent
+			c.leftLastReq = time.Now()
+		case msg := <-subRight.C():
+			cmd, replyTopic = msg.(proto.Packet), rightBladeTopicOut
+			pkt.FromPacket(cmd)
+			c.rightReqFanSpeed = pkt.Percent
+			c.rightLastReq = time.Now()
+		case msg := <-subConfigLeft.C():
+			cmd, replyTopic = msg.(proto.Packet), leftBladeTopicOut
+			if c.updateConfig(smartfanunit.BladeSideLeft, cmd) {
+				lutEnabled, lastSpeed = c.fanLUT != nil, -1
+			}
+		case msg := <-subConfigRight.C():
+			cmd, replyTopic = msg.(proto.Packet), rightBladeTopicOut
+			if c.updateConfig(smartfanunit.BladeSideRight, cmd) {
+				lutEnabled, lastSpeed = c.fanLUT != nil, -1
+			}
+		case <-subReinit.C():
+			// The EMC2101 has been reinitialised, the fan setting and lookup table are restored
+			if c.fanLUT != nil {
+				c.applyFanLUT(*c.fanLUT)
+			}
+			lutEnabled, lastSpeed = c.fanLUT != nil, -1
+		case <-ticker.C:
+			if c.leftReqFanSpeed != c.DefaultFanSpeed && c.expired(smartfanunit.BladeSideLeft) {
+				println("[!] left blade timed out, reverting to default fan speed")
+				c.leftReqFanSpeed = c.DefaultFanSpeed
+			}
+			if c.rightReqFanSpeed != c.DefaultFanSpeed && c.expired(smartfanunit.BladeSideRight) {
+				println("[!] right blade timed out, reverting to default fan speed")
+				c.rightReqFanSpeed = c.DefaultFanSpeed
+			}
+		case <-ctx.Done():
+			return nil
+		}
+
+		// Full speed requests override the lookup table
+		override := c.leftReqFanSpeed == 100 || c.rightReqFanSpeed == 100
+		if c.fanLUT != nil && lutEnabled == override {
+			if err := c.FanController.SetLUTEnabled(!override); err != nil {
+				println("[!] failed to switch fan lookup table:", err.Error())
+			} else {
+				println("[ ] full speed requested, fan lookup table enabled:", !override)
+				lutEnabled, lastSpeed = !override, -1
+			}
+		}
+
+		if !lutEnabled {
+			// Update fan speed with the max speed of both blades
+			speed := max(
+				c.bladeFanSpeed(smartfanunit.BladeSideLeft, c.leftReqFanSpeed, c.leftLastReq),
+				c.bladeFanSpeed(smartfanunit.BladeSideRight, c.rightReqFanSpeed, c.rightLastReq),
+			)
+			if int(speed) != lastSpeed {
+				c.FanController.SetFanPercent(speed)
+				lastSpeed = int(speed)
+			}
+		}
+		if replyTopic != "" && cmd.Command != smartfanunit.CmdHello {
+			c.ack(replyTopic, cmd)
+		}
+	}
+}
+
+// updateConfig applies a fan curve, lookup table or hello (releasing both) of a blade.
+// Returns true if the lookup table driving the fan changed.
+func (c *Controller) updateConfig(side smartfanunit.BladeSide, cmd proto.Packet) bool {
+	switch cmd.Command {
+	case smartfanunit.CmdHello:
+		c.setFanCurve(side, nil)
+		return c.setFanLUT(side, nil)
+	case smartfanunit.CmdSetFanCurve:
+		var curvePkt smartfanunit.SetFanCurvePacket
+		curvePkt.FromPacket(cmd)
+		c.setFanCurve(side, &curvePkt.Curve)
+	case smartfanunit.CmdSetFanLUT:
+		var lutPkt smartfanunit.SetFanLUTPacket
+		lutPkt.FromPacket(cmd)
+		if !lutPkt.LUT.Enabled() {
+			// Releases the lookup table of the blade
+			return c.setFanLUT(side, nil)
+		}
+		return c.setFanLUT(side, &lutPkt.LUT)
+	}
+	return false
+}
+
+// setFanCurve sets the fan curve of a blade, nil releases it.
+// The blade becomes the owner of the applied fan curve, once released the one of the other blade applies again.
+func (c *Controller) setFanCurve(side smartfanunit.BladeSide, curve *smartfanunit.FanCurve) {
+	c.fanCurves[side] = curve
+	if curve != nil {
+		c.fanCurveOwner = side
+	} else if c.fanCurveOwner == side {
+		c.fanCurveOwner = otherSide(side)
+	}
+	c.fanCurve = c.fanCurves[c.fanCurveOwner]
+}
+
+// setFanLUT sets the lookup table of a blade, nil releases it.
+// The blade becomes the owner of the programmed lookup table, once released the one of the other blade is programmed
+// again. Returns true if the lookup table driving the fan changed.
+func (c *Controller) setFanLUT(side smartfanunit.BladeSide, lut *smartfanunit.FanLUT) bool {
+	previous := c.fanLUTs[c.fanLUTOwner]
+	c.fanLUTs[side] = lut
+	if lut != nil {
+		c.fanLUTOwner = side
+	} else if c.fanLUTOwner == side {
+		c.fanLUTOwner = otherSide(side)
+	}
+
+	next := c.fanLUTs[c.fanLUTOwner]
+	if next == previous && lut == nil {
+		// Released a lookup table not driving the fan
+		return false
+	}
+	if next == nil {
+		c.applyFanLUT(smartfanunit.FanLUT{})
+	} else {
+		c.applyFanLUT(*next)
+	}
+	return true
+}
+
+// otherSide returns the side of the other blade
+func otherSide(side smartfanunit.BladeSide) smartfanunit.BladeSide {
+	if side == smartfanunit.BladeSideLeft {
+		return smartfanunit.BladeSideRight
+	}
+	return smartfanunit.BladeSideLeft
+}
+
+// applyFanLUT programs and enables the lookup table of the EMC2101, a lookup table without steps disables it.
+// The fan speed requests of the blades apply again if the lookup table can't be programmed.
+func (c *Controller) applyFanLUT(lut smartfanunit.FanLUT) {
+	if !lut.Enabled() {
+		println("[ ] disabling fan lookup table")
+		c.fanLUT = nil
+		if err := c.FanController.SetLUTEnabled(false); err != nil {
+			println("[!] failed to disable fan lookup table:", err.Error())
+		}
+		return
+	}
+
+	steps := make([]emc2101.LUTStep, 0, len(lut.Steps))
+	for _, step := range lut.Steps {
+		steps = append(steps, emc2101.LUTStep{Temperature: step.Temperature, Percent: step.Percent})
+	}
+	err := c.FanController.SetLUT(steps)
+	if err == nil {
+		err = c.FanController.SetLUTHysteresis(lut.Hysteresis)
+	}
+	if err == nil {
+		err = c.FanController.SetLUTEnabled(true)
+	}
+	if err != nil {
+		println("[!] failed to program fan lookup table:", err.Error())
+		c.fanLUT = nil
+		c.FanController.SetLUTEnabled(false)
+		return
+	}
+	println("[ ] fan lookup table enabled")
+	c.fanLUT = &lut
+}
+
+// bladeFanSpeed returns the fan speed for a blade, the fan curve speed if its last request timed out.
+// The curve only applies to blades that have sent a request or are alive, so an empty slot doesn't override the
+// requests of the other blade.
+func (c *Controller) bladeFanSpeed(side smartfanunit.BladeSide, requested uint8, lastReq time.Time) uint8 {
+	if c.fanCurve == nil || (!lastReq.IsZero() && time.Since(lastReq) <= c.fanCurve.WatchdogTimeout) {
+		return requested
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if lastReq.IsZero() && !c.isAlive(*c.lastSeen(side)) {
+		return requested
+	}
+
+	var temperature float32
+	switch c.fanCurve.Sensor {
+	case smartfanunit.FanCurveSensorInternal:
+		temperature = c.internalTemp
+	case smartfanunit.FanCurveSensorExternal:
+		temperature = c.externalTemp
+	default:
+		temperature = max(c.internalTemp, c.externalTemp)
+	}
+	return c.fanCurve.Percent(temperature)
+}
+
+func (c *Controller) updateLEDs(ctx context.Context) error {
+	subLeft := c.eb.Subscribe(leftBladeTopicIn, 1, smartfanunit.MatchCmd(smartfanunit.CmdSetLED))
+	defer subLeft.Unsubscribe()
+	subRight := c.eb.Subscribe(rightBladeTopicIn, 1, smartfanunit.MatchCmd(smartfanunit.CmdSetLED))
+	defer subRight.Unsubscribe()
+
+	var pkt smartfanunit.SetLEDPacket
+	for {
+		// cmd is the command handled in this iteration, acknowledged through replyTopic once written to the LEDs
+		var cmd proto.Packet
+		var replyTopic string
+		// Update LED color depending on blade
+		select {
+		case msg := <-subLeft.C():
+			cmd, replyTopic = msg.(proto.Packet), leftBladeTopicOut
+			pkt.FromPacket(cmd)
+			c.leftLed = pkt.Color
+		case msg := <-subRight.C():
+			cmd, replyTopic = msg.(proto.Packet), rightBladeTopicOut
+			pkt.FromPacket(cmd)
+			c.rightLed = pkt.Color
+		case <-time.After(500 * time.Millisecond):
+		case <-ctx.Done():
+			return nil
+		}
+		// Write to LEDs (they are in a chain -> we always have to update both)
+		_, err := c.LEDs.Write([]byte{
+			c.rightLed.Blue, c.rightLed.Green, c.rightLed.Red,
+			c.leftLed.Blue, c.leftLed.Green, c.leftLed.Red,
+		})
+		if err != nil {
+			println("[!] failed to update LEDs", err.Error())
+			return err
+		}
+		if replyTopic != "" {
+			c.ack(replyTopic, cmd)
+		}
+	}
+}
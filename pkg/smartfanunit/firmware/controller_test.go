@@ -0,0 +1,437 @@
+//go:build !tinygo
+
+package firmware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/emc2101"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+// testBlade is the blade end of a serial link to the simulated fan unit
+type testBlade struct {
+	w       io.Writer
+	packets chan proto.Packet
+}
+
+func newTestBlade(ctx context.Context, rw io.ReadWriter) *testBlade {
+	b := &testBlade{w: rw, packets: make(chan proto.Packet, 64)}
+	go func() {
+		dec := proto.NewDecoder(rw)
+		for {
+			pkt, err := dec.ReadPacket(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+			b.packets <- pkt
+		}
+	}()
+	return b
+}
+
+func (b *testBlade) send(t *testing.T, pktGen smartfanunit.PacketGenerator) {
+	t.Helper()
+	pkt := pktGen.Packet()
+	write := proto.WritePacket
+	if pkt.Payload != nil {
+		write = proto.WritePacketV2
+	}
+	require.NoError(t, write(context.Background(), b.w, pkt))
+}
+
+// expect returns the next packet with the given command, other packets are skipped
+func (b *testBlade) expect(t *testing.T, cmd proto.Command) proto.Packet {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case pkt := <-b.packets:
+			if pkt.Command == cmd {
+				return pkt
+			}
+		case <-timeout:
+			t.Fatalf("no %s packet received", smartfanunit.CommandName(cmd))
+			return proto.Packet{}
+		}
+	}
+}
+
+// keepAlive sends heartbeats until the test ends, so the requests of the blade don't expire
+func (b *testBlade) keepAlive(t *testing.T) {
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				b.send(t, &smartfanunit.HeartbeatPacket{})
+			}
+		}
+	}()
+}
+
+// runSimulator starts the simulated fan unit and completes the version handshake with both blades
+func runSimulator(t *testing.T) (*Simulator, *testBlade, *testBlade) {
+	t.Helper()
+
+	sim, left, right := startSimulator(t)
+	// Wait until the firmware is running, like the agent does with the version handshake
+	for _, blade := range []*testBlade{left, right} {
+		blade.handshake(t)
+	}
+	return sim, left, right
+}
+
+// startSimulator starts the simulated fan unit, the blades don't send any packet yet.
+// setup is called before the firmware starts, e.g. to inject faults.
+func startSimulator(t *testing.T, setup ...func(sim *Simulator)) (*Simulator, *testBlade, *testBlade) {
+	t.Helper()
+
+	sim := NewSimulator(smartfanunit.FirmwareVersion{Major: 1, Minor: 2, Patch: 3})
+	sim.Controller.BladeAliveTimeout = 200 * time.Millisecond
+	for _, f := range setup {
+		f(sim)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sim.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+		sim.Left.Close()
+		sim.Right.Close()
+	})
+	left, right := newTestBlade(ctx, sim.Left), newTestBlade(ctx, sim.Right)
+
+	// Sent on startup once the dispatchers are running
+	left.expect(t, smartfanunit.NotifyHello)
+	right.expect(t, smartfanunit.NotifyHello)
+	return sim, left, right
+}
+
+// handshake sends a hello and waits for the answer
+func (b *testBlade) handshake(t *testing.T) {
+	t.Helper()
+	b.send(t, &smartfanunit.HelloPacket{ProtocolVersion: smartfanunit.ProtocolVersion})
+	b.expect(t, smartfanunit.NotifyHello)
+}
+
+func TestController_Hello(t *testing.T) {
+	t.Parallel()
+
+	_, left, _ := runSimulator(t)
+
+	// Hellos are sent on startup and in response to hellos
+	left.send(t, &smartfanunit.HelloPacket{ProtocolVersion: smartfanunit.ProtocolVersion})
+	var hello smartfanunit.HelloNotifyPacket
+	require.NoError(t, hello.FromPacket(left.expect(t, smartfanunit.NotifyHello)))
+	assert.Equal(t, uint8(smartfanunit.ProtocolVersion), hello.ProtocolVersion)
+	assert.True(t, hello.Capabilities.Has(capabilities))
+
+	var version smartfanunit.FirmwareVersionPacket
+	require.NoError(t, version.FromPacket(left.expect(t, smartfanunit.NotifyFirmwareVersion)))
+	assert.Equal(t, "1.2.3", version.Version.String())
+}
+
+func TestController_FanSpeed(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+	assert.Equal(t, uint8(40), sim.FanController.FanPercent())
+
+	// The fan runs at the max speed requested by both blades
+	left.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 30, Sequence: 1})
+	right.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 70, Sequence: 1})
+	var ack smartfanunit.AckPacket
+	require.NoError(t, ack.FromPacket(right.expect(t, smartfanunit.NotifyAck)))
+	assert.Equal(t, smartfanunit.AckPacket{Command: smartfanunit.CmdSetFanSpeedPercent, Sequence: 1}, ack)
+	// Commands are acknowledged once applied
+	assert.Equal(t, uint8(70), sim.FanController.FanPercent())
+
+	right.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 20, Sequence: 2})
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 30 }, time.Second, time.Millisecond)
+
+	// Out of range requests are rejected
+	left.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 101, Sequence: 2})
+	var nack smartfanunit.NackPacket
+	require.NoError(t, nack.FromPacket(left.expect(t, smartfanunit.NotifyNack)))
+	assert.Equal(t, smartfanunit.NackReasonInvalidValue, nack.Reason)
+	assert.Equal(t, uint8(30), sim.FanController.FanPercent())
+}
+
+func TestController_StaleRequest(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+
+	left.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 90, Sequence: 1})
+	right.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 20, Sequence: 1})
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 90 }, time.Second, time.Millisecond)
+
+	// The right blade keeps sending heartbeats, the request of the left blade expires
+	right.keepAlive(t)
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 40 }, 3*time.Second, 10*time.Millisecond)
+
+	// Heartbeats are answered with the blades alive
+	assert.Eventually(t, func() bool {
+		var status smartfanunit.BladeStatusPacket
+		require.NoError(t, status.FromPacket(right.expect(t, smartfanunit.NotifyBladeStatus)))
+		return status.Status == smartfanunit.BladeStatus{Side: smartfanunit.BladeSideRight, RightAlive: true}
+	}, time.Second, time.Millisecond)
+}
+
+func TestController_FanCurve(t *testing.T) {
+	t.Parallel()
+
+	// Only the left slot is populated
+	sim, left, right := startSimulator(t)
+	left.handshake(t)
+	sim.FanController.SetTemperatures(25, 50)
+	left.keepAlive(t)
+
+	curve := smartfanunit.FanCurve{
+		Sensor:          smartfanunit.FanCurveSensorExternal,
+		WatchdogTimeout: smartfanunit.MinFanCurveWatchdogTimeout,
+		Steps:           []smartfanunit.FanCurveStep{{Temperature: 30, Percent: 20}, {Temperature: 50, Percent: 80}},
+	}
+	left.send(t, &smartfanunit.SetFanCurvePacket{Curve: curve})
+	left.expect(t, smartfanunit.NotifyAck)
+	left.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 20, Sequence: 1})
+	left.expect(t, smartfanunit.NotifyAck)
+
+	// The curve doesn't apply to the empty right slot, the request of the left blade is kept after reading temperatures
+	assert.Never(t, func() bool { return sim.FanController.FanPercent() != 20 }, 2500*time.Millisecond, 10*time.Millisecond)
+
+	// A right blade without fan speed requests gets the curve speed
+	right.keepAlive(t)
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 80 }, 3*time.Second, 10*time.Millisecond)
+
+	// The right blade connects without a curve, the one of the left blade is kept
+	right.handshake(t)
+	assert.Never(t, func() bool { return sim.FanController.FanPercent() != 80 }, 200*time.Millisecond, 10*time.Millisecond)
+
+	// The left blade reconnects without a curve, the stale curve is released
+	left.handshake(t)
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 20 }, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestController_FanLUT(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+	sim.FanController.SetTemperatures(25, 50)
+	left.keepAlive(t)
+	right.keepAlive(t)
+
+	lut := smartfanunit.FanLUT{
+		Hysteresis: 3,
+		Steps:      []smartfanunit.FanCurveStep{{Temperature: 0, Percent: 30}, {Temperature: 45, Percent: 60}, {Temperature: 60, Percent: 100}},
+	}
+	left.send(t, &smartfanunit.SetFanLUTPacket{LUT: lut})
+	var ack smartfanunit.AckPacket
+	require.NoError(t, ack.FromPacket(left.expect(t, smartfanunit.NotifyAck)))
+	assert.Equal(t, smartfanunit.CmdSetFanLUT, ack.Command)
+	assert.Eventually(t, sim.FanController.LUTEnabled, time.Second, time.Millisecond)
+
+	steps, hysteresis := sim.FanController.LUT()
+	assert.Equal(t, []emc2101.LUTStep{{Temperature: 0, Percent: 30}, {Temperature: 45, Percent: 60}, {Temperature: 60, Percent: 100}}, steps)
+	assert.Equal(t, uint8(3), hysteresis)
+
+	// The lookup table drives the fan instead of the requests
+	assert.Equal(t, uint8(60), sim.FanController.FanPercent())
+	right.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 90, Sequence: 1})
+	right.expect(t, smartfanunit.NotifyAck)
+	sim.FanController.SetTemperatures(25, 70)
+	assert.Equal(t, uint8(100), sim.FanController.FanPercent())
+	sim.FanController.SetTemperatures(25, 20)
+	assert.Equal(t, uint8(30), sim.FanController.FanPercent())
+
+	// Full speed requests, e.g. of a blade in critical state, override the lookup table until withdrawn
+	right.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 100, Sequence: 2})
+	right.expect(t, smartfanunit.NotifyAck)
+	assert.False(t, sim.FanController.LUTEnabled())
+	assert.Equal(t, uint8(100), sim.FanController.FanPercent())
+	right.send(t, &smartfanunit.SetFanSpeedPercentPacket{Percent: 90, Sequence: 3})
+	right.expect(t, smartfanunit.NotifyAck)
+	assert.True(t, sim.FanController.LUTEnabled())
+	assert.Equal(t, uint8(30), sim.FanController.FanPercent())
+
+	// A blade without a lookup table can't release the one of the other blade
+	right.send(t, &smartfanunit.SetFanLUTPacket{})
+	right.expect(t, smartfanunit.NotifyAck)
+	right.handshake(t)
+	assert.Never(t, func() bool { return !sim.FanController.LUTEnabled() }, 200*time.Millisecond, 10*time.Millisecond)
+
+	// The lookup table pushed last applies, once released the one of the other blade applies again
+	rightLUT := smartfanunit.FanLUT{Steps: []smartfanunit.FanCurveStep{{Temperature: 0, Percent: 50}}}
+	right.send(t, &smartfanunit.SetFanLUTPacket{LUT: rightLUT})
+	right.expect(t, smartfanunit.NotifyAck)
+	assert.Equal(t, uint8(50), sim.FanController.FanPercent())
+	right.send(t, &smartfanunit.SetFanLUTPacket{})
+	right.expect(t, smartfanunit.NotifyAck)
+	assert.True(t, sim.FanController.LUTEnabled())
+	assert.Equal(t, uint8(30), sim.FanController.FanPercent())
+
+	// The left blade reconnects without a lookup table, the stale one is released and the requests apply again
+	left.handshake(t)
+	assert.Eventually(t, func() bool { return !sim.FanController.LUTEnabled() }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return sim.FanController.FanPercent() == 90 }, time.Second, time.Millisecond)
+
+	// Invalid lookup tables are rejected
+	left.send(t, &smartfanunit.SetFanLUTPacket{LUT: smartfanunit.FanLUT{Hysteresis: smartfanunit.MaxFanLUTHysteresis + 1}})
+	var nack smartfanunit.NackPacket
+	require.NoError(t, nack.FromPacket(left.expect(t, smartfanunit.NotifyNack)))
+	assert.Equal(t, smartfanunit.NackReasonInvalidValue, nack.Reason)
+	assert.False(t, sim.FanController.LUTEnabled())
+}
+
+func TestController_ExternalDiode(t *testing.T) {
+	t.Parallel()
+
+	sim := NewSimulator(smartfanunit.FirmwareVersion{})
+	sim.Controller.ExternalDiode = &emc2101.ExternalDiode{IdealityFactor: 0x10, BetaCompensation: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sim.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return sim.FanController.ExternalDiode() == *sim.Controller.ExternalDiode }, time.Second, time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestController_LEDs(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+
+	left.send(t, &smartfanunit.SetLEDPacket{Color: led.Color{Red: 0xff}})
+	right.send(t, &smartfanunit.SetLEDPacket{Color: led.Color{Blue: 0x10, Green: 0x20}})
+	left.expect(t, smartfanunit.NotifyAck)
+	right.expect(t, smartfanunit.NotifyAck)
+	// Commands are acknowledged once written to the LEDs
+	leftColor, rightColor := sim.LEDs.Colors()
+	assert.Equal(t, led.Color{Red: 0xff}, leftColor)
+	assert.Equal(t, led.Color{Blue: 0x10, Green: 0x20}, rightColor)
+
+	// Repeated colors are acknowledged with their sequence numbers
+	for sequence := uint8(1); sequence <= 2; sequence++ {
+		left.send(t, &smartfanunit.SetLEDPacket{Color: led.Color{Red: 0xff}, Sequence: sequence, HasSequence: true})
+		var ack smartfanunit.AckPacket
+		require.NoError(t, ack.FromPacket(left.expect(t, smartfanunit.NotifyAck)))
+		assert.Equal(t, smartfanunit.AckPacket{Command: smartfanunit.CmdSetLED, Sequence: sequence}, ack)
+	}
+}
+
+func TestController_Button(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+
+	// A single press is forwarded to the left blade
+	sim.Button.Press()
+	left.expect(t, smartfanunit.NotifyButtonPress)
+
+	// A double press is forwarded to the right blade
+	sim.Button.Press()
+	sim.Button.Press()
+	right.expect(t, smartfanunit.NotifyButtonPress)
+	select {
+	case pkt := <-left.packets:
+		assert.NotEqual(t, smartfanunit.NotifyButtonPress, pkt.Command)
+	default:
+	}
+}
+
+func TestController_Telemetry(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+	sim.FanController.SetTemperatures(25, 35)
+
+	// Both sensors are reported to both blades
+	for _, blade := range []*testBlade{left, right} {
+		var temperature smartfanunit.SensorTemperaturePacket
+		require.NoError(t, temperature.FromPacket(blade.expect(t, smartfanunit.NotifyInternalTemperature)))
+		assert.InDelta(t, 25, temperature.Temperature, 0.01)
+		require.NoError(t, temperature.FromPacket(blade.expect(t, smartfanunit.NotifyExternalTemperature)))
+		assert.InDelta(t, 35, temperature.Temperature, 0.01)
+	}
+
+	// The air flow temperature is the internal temperature for the left blade, the external one for the right blade
+	var temperature smartfanunit.AirFlowTemperaturePacket
+	require.NoError(t, temperature.FromPacket(left.expect(t, smartfanunit.NotifyAirFlowTemperature)))
+	assert.InDelta(t, 25, temperature.Temperature, 0.01)
+	require.NoError(t, temperature.FromPacket(right.expect(t, smartfanunit.NotifyAirFlowTemperature)))
+	assert.InDelta(t, 35, temperature.Temperature, 0.01)
+
+	var rpm smartfanunit.FanSpeedRPMPacket
+	require.NoError(t, rpm.FromPacket(left.expect(t, smartfanunit.NotifyFanSpeedRPM)))
+	assert.InDelta(t, 2000, rpm.RPM, 1)
+}
+
+func TestController_BusRecovery(t *testing.T) {
+	t.Parallel()
+
+	sim, left, right := runSimulator(t)
+	lut := smartfanunit.FanLUT{Steps: []smartfanunit.FanCurveStep{{Temperature: 0, Percent: 50}}}
+	left.send(t, &smartfanunit.SetFanLUTPacket{LUT: lut})
+	left.expect(t, smartfanunit.NotifyAck)
+	assert.Eventually(t, sim.FanController.LUTEnabled, time.Second, time.Millisecond)
+
+	// A locked up bus is recovered without resetting the CPU and reported to both blades
+	sim.FanController.LockBus()
+	for _, blade := range []*testBlade{left, right} {
+		var recovery smartfanunit.BusRecoveryPacket
+		require.NoError(t, recovery.FromPacket(blade.expect(t, smartfanunit.NotifyBusRecovery)))
+		assert.Equal(t, smartfanunit.BusRecoveryStats{Recoveries: 1}, recovery.Stats)
+	}
+	assert.Equal(t, 1, sim.BusRecoveries())
+	assert.Zero(t, sim.Resets())
+
+	// The lookup table is restored after reinitialising the EMC2101
+	assert.Eventually(t, sim.FanController.LUTEnabled, time.Second, time.Millisecond)
+	left.expect(t, smartfanunit.NotifyFanSpeedRPM)
+}
+
+func TestController_BusRecoveryOnStartup(t *testing.T) {
+	t.Parallel()
+
+	// The bus is locked up before the firmware starts, e.g. by a reset during a transfer
+	sim, left, _ := startSimulator(t, func(sim *Simulator) { sim.FanController.LockBus() })
+	left.handshake(t)
+	assert.Equal(t, 1, sim.BusRecoveries())
+	assert.Zero(t, sim.Resets())
+	assert.Equal(t, uint8(40), sim.FanController.FanPercent())
+	left.expect(t, smartfanunit.NotifyFanSpeedRPM)
+}
+
+func TestController_BusRecoveryFailed(t *testing.T) {
+	t.Parallel()
+
+	sim, left, _ := runSimulator(t)
+
+	// The CPU is reset once all recovery attempts failed
+	sim.FanController.SetError(errors.New("no acknowledge"))
+	var recovery smartfanunit.BusRecoveryPacket
+	require.NoError(t, recovery.FromPacket(left.expect(t, smartfanunit.NotifyBusRecovery)))
+	assert.Equal(t, smartfanunit.BusRecoveryStats{FailedAttempts: maxBusRecoveryAttempts}, recovery.Stats)
+	assert.Eventually(t, func() bool { return sim.Resets() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, maxBusRecoveryAttempts, sim.BusRecoveries())
+}
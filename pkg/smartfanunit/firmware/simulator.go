@@ -0,0 +1,427 @@
+//go:build !tinygo
+
+package firmware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uptime-induestries/compute-blade-agent/pkg/hal/led"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/emc2101"
+)
+
+// Simulator runs the firmware on the host against simulated hardware.
+// The blades are connected through in-memory serial links, e.g. to run agent-side clients in integration tests.
+type Simulator struct {
+	Controller *Controller
+
+	// Left and Right are the blade ends of the serial links
+	Left  io.ReadWriteCloser
+	Right io.ReadWriteCloser
+
+	FanController *SimulatedEMC2101
+	LEDs          *SimulatedLEDs
+	Button        *SimulatedButton
+
+	// leftUART and rightUART are the fan unit ends of the serial links
+	leftUART      io.ReadWriteCloser
+	rightUART     io.ReadWriteCloser
+	resets        atomic.Int32
+	busRecoveries atomic.Int32
+}
+
+// ErrBusLocked is returned by SimulatedEMC2101 while the simulated I2C bus is locked up
+var ErrBusLocked = errors.New("i2c bus locked up")
+
+// NewSimulator returns a simulator of the given firmware version
+func NewSimulator(version smartfanunit.FirmwareVersion) *Simulator {
+	s := &Simulator{
+		FanController: &SimulatedEMC2101{MaxRPM: 5000},
+		LEDs:          &SimulatedLEDs{},
+		Button:        &SimulatedButton{},
+	}
+	s.Left, s.leftUART = newSerialLink()
+	s.Right, s.rightUART = newSerialLink()
+	s.Controller = &Controller{
+		FirmwareVersion: version,
+		DefaultFanSpeed: 40,
+		LEDs:            s.LEDs,
+		FanController:   s.FanController,
+		Button:          s.Button,
+		Reset:           func() { s.resets.Add(1) },
+		LeftUART:        s.leftUART,
+		RightUART:       s.rightUART,
+		RecoverBus:      s.recoverBus,
+	}
+	return s
+}
+
+// Run runs the firmware until the context is cancelled, the serial links are closed afterward
+func (s *Simulator) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// Unblocks reads of the controller
+		<-ctx.Done()
+		s.leftUART.Close()
+		s.rightUART.Close()
+	}()
+	return s.Controller.Run(ctx)
+}
+
+// Resets returns the number of CPU resets requested by the firmware
+func (s *Simulator) Resets() int {
+	return int(s.resets.Load())
+}
+
+// recoverBus releases the I2C bus of the simulated EMC2101
+func (s *Simulator) recoverBus() error {
+	s.busRecoveries.Add(1)
+	s.FanController.recoverBus()
+	return nil
+}
+
+// BusRecoveries returns the number of I2C bus recoveries requested by the firmware
+func (s *Simulator) BusRecoveries() int {
+	return int(s.busRecoveries.Load())
+}
+
+// SimulatedEMC2101 is an in-memory emc2101.EMC2101, the fan speed follows the duty cycle.
+// While the lookup table is enabled, the duty cycle follows the external temperature, ignoring the hysteresis.
+type SimulatedEMC2101 struct {
+	// MaxRPM is the fan speed at 100%
+	MaxRPM float32
+
+	mu           sync.Mutex
+	internalTemp float32
+	externalTemp float32
+	fanPercent   uint8
+	lutEnabled   bool
+	lut          []emc2101.LUTStep
+	hysteresis   uint8
+	diode        emc2101.ExternalDiode
+	status       emc2101.Status
+	err          error
+	// busLocked fails all accesses with ErrBusLocked until the bus is recovered
+	busLocked bool
+}
+
+// fails if SimulatedEMC2101 does not implement emc2101.EMC2101
+var _ emc2101.EMC2101 = &SimulatedEMC2101{}
+
+func (e *SimulatedEMC2101) Init() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return err
+	}
+	// Like the EMC2101, the fan is driven by the fan setting after Init
+	e.lutEnabled = false
+	return nil
+}
+
+func (e *SimulatedEMC2101) InternalTemperature() (float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.internalTemp, e.fault()
+}
+
+func (e *SimulatedEMC2101) ExternalTemperature() (float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.externalTemp, e.fault()
+}
+
+func (e *SimulatedEMC2101) SetFanPercent(percent uint8) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return err
+	}
+	if !e.lutEnabled {
+		// The fan setting is read-only while the lookup table is enabled
+		e.fanPercent = percent
+	}
+	return nil
+}
+
+func (e *SimulatedEMC2101) FanRPM() (float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.MaxRPM * float32(e.percent()) / 100, e.fault()
+}
+
+func (e *SimulatedEMC2101) SetLUTEnabled(enabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return err
+	}
+	e.lutEnabled = enabled
+	return nil
+}
+
+func (e *SimulatedEMC2101) Status() (emc2101.Status, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return 0, err
+	}
+	// Alert bits are cleared by reading
+	status := e.status
+	e.status = 0
+	return status, nil
+}
+
+func (e *SimulatedEMC2101) SetLUT(steps []emc2101.LUTStep) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return err
+	}
+	e.lut = append([]emc2101.LUTStep(nil), steps...)
+	return nil
+}
+
+func (e *SimulatedEMC2101) SetLUTHysteresis(hysteresis uint8) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return err
+	}
+	e.hysteresis = hysteresis
+	return nil
+}
+
+func (e *SimulatedEMC2101) ConfigureExternalDiode(diode emc2101.ExternalDiode) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.fault(); err != nil {
+		return err
+	}
+	e.diode = diode
+	return nil
+}
+
+// percent returns the current duty cycle, e.mu must be held
+func (e *SimulatedEMC2101) percent() uint8 {
+	if !e.lutEnabled || len(e.lut) == 0 {
+		return e.fanPercent
+	}
+	// The speed of the highest step not above the temperature applies, the first step below it
+	percent := e.lut[0].Percent
+	for _, step := range e.lut[1:] {
+		if e.externalTemp >= float32(step.Temperature) {
+			percent = step.Percent
+		}
+	}
+	return percent
+}
+
+// LUT returns the lookup table and hysteresis programmed by the firmware
+func (e *SimulatedEMC2101) LUT() ([]emc2101.LUTStep, uint8) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]emc2101.LUTStep(nil), e.lut...), e.hysteresis
+}
+
+// ExternalDiode returns the configuration of the external diode
+func (e *SimulatedEMC2101) ExternalDiode() emc2101.ExternalDiode {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.diode
+}
+
+// SetStatus sets the status bits returned by the next Status call
+func (e *SimulatedEMC2101) SetStatus(status emc2101.Status) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status |= status
+}
+
+// LUTEnabled returns true if the firmware enabled the lookup table
+func (e *SimulatedEMC2101) LUTEnabled() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lutEnabled
+}
+
+// SetTemperatures sets the temperatures measured by the internal sensor and the external diode
+func (e *SimulatedEMC2101) SetTemperatures(internal, external float32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.internalTemp, e.externalTemp = internal, external
+}
+
+// fault returns the error of an access, e.mu must be held
+func (e *SimulatedEMC2101) fault() error {
+	if e.busLocked {
+		return ErrBusLocked
+	}
+	return e.err
+}
+
+// LockBus locks up the I2C bus until the firmware recovers it
+func (e *SimulatedEMC2101) LockBus() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.busLocked = true
+}
+
+// recoverBus releases a locked up I2C bus, errors set by SetError persist
+func (e *SimulatedEMC2101) recoverBus() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.busLocked = false
+}
+
+// SetError makes all further accesses fail with err, even after bus recoveries, nil recovers
+func (e *SimulatedEMC2101) SetError(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+// FanPercent returns the fan speed set by the firmware, or by the lookup table while it's enabled
+func (e *SimulatedEMC2101) FanPercent() uint8 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.percent()
+}
+
+// SimulatedLEDs records the colors written to the LED chain
+type SimulatedLEDs struct {
+	mu     sync.Mutex
+	colors []byte
+}
+
+func (l *SimulatedLEDs) Write(buf []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.colors = append(l.colors[:0], buf...)
+	return len(buf), nil
+}
+
+// Colors returns the colors of the left and right blade's LED, the chain starts with the right LED in BGR order
+func (l *SimulatedLEDs) Colors() (left led.Color, right led.Color) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.colors) < 6 {
+		return led.Color{}, led.Color{}
+	}
+	right = led.Color{Blue: l.colors[0], Green: l.colors[1], Red: l.colors[2]}
+	left = led.Color{Blue: l.colors[3], Green: l.colors[4], Red: l.colors[5]}
+	return left, right
+}
+
+// SimulatedButton is a button pressed through Press
+type SimulatedButton struct {
+	mu       sync.Mutex
+	callback func()
+}
+
+func (b *SimulatedButton) SetInterrupt(callback func()) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.callback = callback
+	return nil
+}
+
+// Press presses the button once, presses within 600ms are a double press
+func (b *SimulatedButton) Press() {
+	b.mu.Lock()
+	callback := b.callback
+	b.mu.Unlock()
+	if callback != nil {
+		callback()
+	}
+}
+
+// newSerialLink returns both ends of an in-memory serial link.
+// Unlike net.Pipe, writes don't wait for the other end to read, like a UART.
+func newSerialLink() (io.ReadWriteCloser, io.ReadWriteCloser) {
+	a, b := newSerialBuffer(), newSerialBuffer()
+	return &serialPort{rx: a, tx: b}, &serialPort{rx: b, tx: a}
+}
+
+// serialPort is one end of a serial link
+type serialPort struct {
+	rx *serialBuffer
+	tx *serialBuffer
+}
+
+func (p *serialPort) Read(buf []byte) (int, error) {
+	return p.rx.read(buf)
+}
+
+func (p *serialPort) Write(buf []byte) (int, error) {
+	return p.tx.write(buf)
+}
+
+// Close closes both directions of the link
+func (p *serialPort) Close() error {
+	p.rx.close()
+	p.tx.close()
+	return nil
+}
+
+// serialBuffer buffers the bytes sent in one direction of a serial link
+type serialBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+	// ready is signalled on writes and close
+	ready chan struct{}
+}
+
+func newSerialBuffer() *serialBuffer {
+	return &serialBuffer{ready: make(chan struct{}, 1)}
+}
+
+func (b *serialBuffer) read(buf []byte) (int, error) {
+	for {
+		b.mu.Lock()
+		if b.buf.Len() > 0 {
+			n, err := b.buf.Read(buf)
+			b.mu.Unlock()
+			return n, err
+		}
+		closed := b.closed
+		b.mu.Unlock()
+		if closed {
+			return 0, io.EOF
+		}
+		<-b.ready
+	}
+}
+
+func (b *serialBuffer) write(buf []byte) (int, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	n, err := b.buf.Write(buf)
+	b.mu.Unlock()
+	b.signal()
+	return n, err
+}
+
+func (b *serialBuffer) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.signal()
+}
+
+func (b *serialBuffer) signal() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
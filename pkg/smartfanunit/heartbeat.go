@@ -0,0 +1,99 @@
+package smartfanunit
+
+import (
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+// BladeSide is the side of the fan unit a blade is connected to
+type BladeSide uint8
+
+const (
+	// BladeSideLeft is the blade receiving single button presses
+	BladeSideLeft BladeSide = iota
+	// BladeSideRight is the blade receiving double button presses
+	BladeSideRight
+)
+
+func (s BladeSide) String() string {
+	switch s {
+	case BladeSideLeft:
+		return "left"
+	case BladeSideRight:
+		return "right"
+	default:
+		return "unknown"
+	}
+}
+
+// BladeStatus is the status of the blades connected to the fan unit.
+// A blade is alive while it sends heartbeats or commands, the fan speed request of a dead blade has expired.
+type BladeStatus struct {
+	// Side of the blade receiving the status
+	Side       BladeSide
+	LeftAlive  bool
+	RightAlive bool
+}
+
+// Alive returns true if the blade on the given side is alive
+func (s BladeStatus) Alive(side BladeSide) bool {
+	switch side {
+	case BladeSideLeft:
+		return s.LeftAlive
+	case BladeSideRight:
+		return s.RightAlive
+	default:
+		return false
+	}
+}
+
+// HeartbeatPacket is sent periodically from the blade to the fan unit, so its fan speed request doesn't expire.
+type HeartbeatPacket struct{}
+
+func (p *HeartbeatPacket) Packet() proto.Packet {
+	return proto.Packet{
+		Command: CmdHeartbeat,
+	}
+}
+
+func (p *HeartbeatPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != CmdHeartbeat {
+		return ErrInvalidCommand
+	}
+	return nil
+}
+
+// BladeStatusPacket is sent from the fan unit to the blade in response to a heartbeat.
+type BladeStatusPacket struct {
+	Status BladeStatus
+}
+
+const (
+	bladeStatusLeftAlive  = 1 << 0
+	bladeStatusRightAlive = 1 << 1
+)
+
+func (p *BladeStatusPacket) Packet() proto.Packet {
+	var alive uint8
+	if p.Status.LeftAlive {
+		alive |= bladeStatusLeftAlive
+	}
+	if p.Status.RightAlive {
+		alive |= bladeStatusRightAlive
+	}
+	return proto.Packet{
+		Command: NotifyBladeStatus,
+		Data:    proto.Data{uint8(p.Status.Side), alive, 0},
+	}
+}
+
+func (p *BladeStatusPacket) FromPacket(packet proto.Packet) error {
+	if packet.Command != NotifyBladeStatus {
+		return ErrInvalidCommand
+	}
+	p.Status = BladeStatus{
+		Side:       BladeSide(packet.Data[0]),
+		LeftAlive:  packet.Data[1]&bladeStatusLeftAlive != 0,
+		RightAlive: packet.Data[1]&bladeStatusRightAlive != 0,
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build !tinygo
+
+package smartfanunit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+func TestBladeStatusPacket(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []BladeStatus{
+		{Side: BladeSideLeft, LeftAlive: true},
+		{Side: BladeSideRight, LeftAlive: true, RightAlive: true},
+		{Side: BladeSideRight},
+	} {
+		pkt := BladeStatusPacket{Status: status}
+		var parsed BladeStatusPacket
+		assert.NoError(t, parsed.FromPacket(pkt.Packet()))
+		assert.Equal(t, status, parsed.Status)
+		assert.Equal(t, status.LeftAlive, parsed.Status.Alive(BladeSideLeft))
+		assert.Equal(t, status.RightAlive, parsed.Status.Alive(BladeSideRight))
+	}
+
+	var parsed BladeStatusPacket
+	assert.ErrorIs(t, parsed.FromPacket(proto.Packet{Command: CmdHeartbeat}), ErrInvalidCommand)
+}
@@ -0,0 +1,172 @@
+package proto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+// decoderBufferSize is the number of bytes read from the underlying reader at once
+const decoderBufferSize = 64
+
+// DecoderStats are the counters of a Decoder
+type DecoderStats struct {
+	// Packets is the number of packets decoded
+	Packets uint64
+	// DroppedBytes is the number of bytes dropped outside of a frame
+	DroppedBytes uint64
+	// FramingErrors is the number of frames with an invalid length or interrupted by the start of another frame
+	FramingErrors uint64
+	// ChecksumErrors is the number of frames with a checksum mismatch
+	ChecksumErrors uint64
+	// Overflows is the number of frames exceeding the maximum frame size
+	Overflows uint64
+}
+
+// Decoder reads packets from a stream of v1 and v2 frames.
+// Unlike ReadPacket, reads are buffered and a partial frame is kept across calls. A start of frame within a frame
+// ends the current frame with ErrInvalidFramingByte and starts the next one, so no frame is lost on resync.
+// A Decoder is not safe for concurrent use.
+type Decoder struct {
+	r    io.Reader
+	uart drivers.UART
+
+	buf      [decoderBufferSize]uint8
+	pos, end int
+
+	frame   []uint8
+	started bool
+	escaped bool
+
+	stats DecoderStats
+}
+
+// NewDecoder returns a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{
+		r:     r,
+		frame: make([]uint8, 0, maxFrameSize),
+	}
+	d.uart, _ = r.(drivers.UART)
+	return d
+}
+
+// Stats returns the counters of the decoder
+func (d *Decoder) Stats() DecoderStats {
+	return d.stats
+}
+
+// ReadPacket blocks until the next packet has been decoded.
+// Invalid frames are reported with ErrInvalidFramingByte, ErrChecksumMismatch or ErrFrameOverflow,
+// the decoder can be used to read further packets afterward.
+func (d *Decoder) ReadPacket(ctx context.Context) (Packet, error) {
+	for {
+		for d.pos < d.end {
+			b := d.buf[d.pos]
+			d.pos++
+
+			pkt, ok, err := d.decodeByte(b)
+			if err != nil {
+				d.countError(err)
+				return Packet{}, err
+			}
+			if ok {
+				d.stats.Packets++
+				return pkt, nil
+			}
+		}
+
+		// Check if context is done before reading
+		select {
+		case <-ctx.Done():
+			return Packet{}, ctx.Err()
+		default:
+		}
+
+		if d.uart != nil && d.uart.Buffered() == 0 {
+			// Allows TinyGo to switch to other goroutines
+			time.Sleep(time.Millisecond)
+			continue
+		}
+
+		n, err := d.r.Read(d.buf[:])
+		d.pos, d.end = 0, n
+		if n == 0 && err != nil {
+			return Packet{}, err
+		}
+	}
+}
+
+// decodeByte processes a single byte, returns true once a packet has been decoded
+func (d *Decoder) decodeByte(b uint8) (Packet, bool, error) {
+	if !d.started {
+		// Drop bytes until the start of a frame
+		if b == SOF || b == SOFV2 {
+			d.startFrame(b)
+		} else {
+			d.stats.DroppedBytes++
+		}
+		return Packet{}, false, nil
+	}
+
+	switch {
+	case b == SOF || (b == SOFV2 && d.frame[0] == SOFV2):
+		// Start of the next frame, even after an escape character as SOF is never sent escaped.
+		// v1 frames might contain unescaped SOFV2 bytes though.
+		d.startFrame(b)
+		return Packet{}, false, ErrInvalidFramingByte
+	case d.escaped:
+		d.escaped = false
+		return Packet{}, false, d.appendByte(b ^ XOR)
+	case b == ESC:
+		d.escaped = true
+		return Packet{}, false, nil
+	case b == EOF:
+		d.started = false
+		if err := d.appendByte(b); err != nil {
+			return Packet{}, false, err
+		}
+		var (
+			pkt Packet
+			err error
+		)
+		if d.frame[0] == SOFV2 {
+			pkt, err = decodeV2(d.frame)
+		} else {
+			pkt, err = decodeV1(d.frame)
+		}
+		return pkt, err == nil, err
+	default:
+		return Packet{}, false, d.appendByte(b)
+	}
+}
+
+func (d *Decoder) startFrame(b uint8) {
+	d.frame = append(d.frame[:0], b)
+	d.started = true
+	d.escaped = false
+}
+
+func (d *Decoder) appendByte(b uint8) error {
+	if len(d.frame) == maxFrameSize {
+		// Drop the frame and wait for the next one
+		d.started = false
+		return ErrFrameOverflow
+	}
+	d.frame = append(d.frame, b)
+	return nil
+}
+
+func (d *Decoder) countError(err error) {
+	switch {
+	case errors.Is(err, ErrInvalidFramingByte):
+		d.stats.FramingErrors++
+	case errors.Is(err, ErrChecksumMismatch):
+		d.stats.ChecksumErrors++
+	case errors.Is(err, ErrFrameOverflow):
+		d.stats.Overflows++
+	}
+}
@@ -0,0 +1,199 @@
+package proto_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/smartfanunit/proto"
+)
+
+// oneByteReader returns a single byte per read, like a slow serial port
+type oneByteReader struct {
+	r io.Reader
+}
+
+func (r oneByteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return r.r.Read(p[:1])
+}
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+
+	v1 := proto.Packet{Command: 0x01, Data: proto.Data{proto.SOF, proto.SOFV2, proto.EOF}}
+	v2 := proto.Packet{Command: 0x02, Data: proto.Data{0x21, 0x22, 0x23}, Payload: []uint8{0x21, 0x22, 0x23, proto.ESC}}
+
+	var buffer bytes.Buffer
+	buffer.Write([]uint8{0x00, 0x01})
+	assert.NoError(t, proto.WritePacket(context.TODO(), &buffer, v1))
+	assert.NoError(t, proto.WritePacketV2(context.TODO(), &buffer, v2))
+	assert.NoError(t, proto.WritePacket(context.TODO(), &buffer, v1))
+
+	dec := proto.NewDecoder(oneByteReader{&buffer})
+	for _, expected := range []proto.Packet{v1, v2, v1} {
+		packet, err := dec.ReadPacket(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, expected, packet)
+	}
+
+	_, err := dec.ReadPacket(context.TODO())
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, proto.DecoderStats{Packets: 3, DroppedBytes: 2}, dec.Stats())
+}
+
+func TestDecoder_PartialFrame(t *testing.T) {
+	t.Parallel()
+
+	var frame bytes.Buffer
+	pkt := proto.Packet{Command: 0x01, Data: proto.Data{0x11, 0x12, 0x13}}
+	assert.NoError(t, proto.WritePacket(context.TODO(), &frame, pkt))
+
+	// The frame arrives in two reads with a read timeout in between
+	r := &scriptedReader{
+		chunks: [][]uint8{frame.Bytes()[:3], nil, frame.Bytes()[3:]},
+		errs:   []error{nil, errTimeout, nil},
+	}
+	dec := proto.NewDecoder(r)
+	_, err := dec.ReadPacket(context.TODO())
+	assert.ErrorIs(t, err, errTimeout)
+
+	// The partial frame is continued with the next read
+	packet, err := dec.ReadPacket(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, pkt, packet)
+}
+
+var errTimeout = errors.New("timeout")
+
+// scriptedReader returns the given chunks and errors, one per read
+type scriptedReader struct {
+	chunks [][]uint8
+	errs   []error
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	err := r.errs[0]
+	r.chunks, r.errs = r.chunks[1:], r.errs[1:]
+	return n, err
+}
+
+func TestDecoder_Resync(t *testing.T) {
+	t.Parallel()
+
+	pkt := proto.Packet{Command: 0x01, Data: proto.Data{0x11, 0x12, 0x13}}
+	var buffer bytes.Buffer
+	// Truncated frames followed by a start of frame, ending with an escape character
+	buffer.Write([]uint8{proto.SOF, 0x01, 0x11})
+	buffer.Write([]uint8{proto.SOF, 0x01, proto.ESC})
+	assert.NoError(t, proto.WritePacket(context.TODO(), &buffer, pkt))
+
+	dec := proto.NewDecoder(&buffer)
+	_, err := dec.ReadPacket(context.TODO())
+	assert.ErrorIs(t, err, proto.ErrInvalidFramingByte)
+	_, err = dec.ReadPacket(context.TODO())
+	assert.ErrorIs(t, err, proto.ErrInvalidFramingByte)
+
+	// The frame interrupting the truncated one is not lost
+	packet, err := dec.ReadPacket(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, pkt, packet)
+	assert.Equal(t, proto.DecoderStats{Packets: 1, FramingErrors: 2}, dec.Stats())
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	// Checksum mismatch
+	buffer.Write([]uint8{proto.SOF, 0x01, 0x11, 0x12, 0x13, 0x00, proto.EOF})
+	// Overflow
+	buffer.WriteByte(proto.SOFV2)
+	buffer.Write(bytes.Repeat([]uint8{0x01}, 300))
+	buffer.WriteByte(proto.EOF)
+	// Valid packet
+	pkt := proto.Packet{Command: 0x01, Data: proto.Data{0x11, 0x12, 0x13}}
+	assert.NoError(t, proto.WritePacket(context.TODO(), &buffer, pkt))
+
+	dec := proto.NewDecoder(&buffer)
+	_, err := dec.ReadPacket(context.TODO())
+	assert.ErrorIs(t, err, proto.ErrChecksumMismatch)
+	_, err = dec.ReadPacket(context.TODO())
+	assert.ErrorIs(t, err, proto.ErrFrameOverflow)
+
+	packet, err := dec.ReadPacket(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, pkt, packet)
+
+	stats := dec.Stats()
+	assert.Equal(t, uint64(1), stats.Packets)
+	assert.Equal(t, uint64(1), stats.ChecksumErrors)
+	assert.Equal(t, uint64(1), stats.Overflows)
+}
+
+func TestDecoder_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dec := proto.NewDecoder(bytes.NewReader(nil))
+	_, err := dec.ReadPacket(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// benchmarkStream returns a stream of v1 packets as sent by the smart fan unit
+func benchmarkStream(b *testing.B, packets int) []uint8 {
+	b.Helper()
+	var buffer bytes.Buffer
+	for i := 0; i < packets; i++ {
+		pkt := proto.Packet{Command: 0xa3, Data: proto.Data{0x00, uint8(i >> 8), uint8(i)}}
+		if err := proto.WritePacket(context.TODO(), &buffer, pkt); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buffer.Bytes()
+}
+
+const benchmarkPackets = 1000
+
+func BenchmarkReadPacket(b *testing.B) {
+	stream := benchmarkStream(b, benchmarkPackets)
+	b.SetBytes(int64(len(stream)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r := bytes.NewReader(stream)
+		for j := 0; j < benchmarkPackets; j++ {
+			if _, err := proto.ReadPacket(context.TODO(), r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	stream := benchmarkStream(b, benchmarkPackets)
+	b.SetBytes(int64(len(stream)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dec := proto.NewDecoder(bytes.NewReader(stream))
+		for j := 0; j < benchmarkPackets; j++ {
+			if _, err := dec.ReadPacket(context.TODO()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
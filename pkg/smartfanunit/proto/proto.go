@@ -10,20 +10,37 @@ import (
 )
 
 // Simple P2P protocol for communicating over a serial port.
-// All commands are 4 bytes long, the first byte is the command, the remaining bytes are data
+// v1 frames are 4 bytes long, the first byte is the command, the remaining bytes are data
 // This allows encoding of 256 commands, with a payload of 3 bytes each.
 // Includes SOF/EOF framing and a checksum. Colliding bytes in the payload are escaped.
+//
+// v2 frames start with SOFV2 and carry a variable-length payload:
+// SOFV2 | command | length | payload (length bytes) | CRC-16 (big endian) | EOF
+// The CRC-16 covers command, length and payload. Colliding bytes are escaped like in v1 frames.
 
 var (
 	ErrChecksumMismatch   = errors.New("checksum mismatch")
 	ErrInvalidFramingByte = errors.New("invalid framing byte")
+	ErrPayloadTooLarge    = errors.New("payload too large")
+	ErrFrameOverflow      = errors.New("frame overflow")
 )
 
 const (
-	SOF = 0x7E // Start of Frame
-	ESC = 0x7D // Escape character
-	XOR = 0x20 // XOR value for escaping
-	EOF = 0x7F // End of Frame
+	SOF   = 0x7E // Start of Frame
+	SOFV2 = 0x7C // Start of Frame (v2)
+	ESC   = 0x7D // Escape character
+	XOR   = 0x20 // XOR value for escaping
+	EOF   = 0x7F // End of Frame
+
+	// MaxPayloadSize is the maximum payload size of v2 frames
+	MaxPayloadSize = 0xff
+
+	// v1FrameSize is the size of v1 frames without escaping: SOF, command, data, checksum, EOF
+	v1FrameSize = 7
+	// v2FrameOverhead is the size of v2 frames without payload and escaping: SOFV2, command, length, CRC-16, EOF
+	v2FrameOverhead = 6
+	// maxFrameSize is the maximum size of frames without escaping
+	maxFrameSize = MaxPayloadSize + v2FrameOverhead
 )
 
 // Command represents the command byte.
@@ -36,6 +53,17 @@ type Data [3]uint8
 type Packet struct {
 	Command Command
 	Data    Data
+	// Payload is the variable-length payload of v2 frames, nil for packets read from v1 frames.
+	// Data holds the first bytes of the payload, so packets can be parsed regardless of the framing.
+	Payload []uint8
+}
+
+// payload returns the variable-length payload, Data if no Payload is set
+func (packet *Packet) payload() []uint8 {
+	if packet.Payload != nil {
+		return packet.Payload
+	}
+	return packet.Data[:]
 }
 
 // Checksum calculates the Checksum for a packet.
@@ -48,8 +76,16 @@ func (packet *Packet) Checksum() uint8 {
 	return crc
 }
 
-// WritePacket writes a packet to an io.Writer with escaping.
+// WritePacket writes a packet as v1 frame to an io.Writer with escaping.
+// A Payload is written as Data, it must not exceed the size of Data.
 func WritePacket(_ context.Context, w io.Writer, packet Packet) error {
+	if packet.Payload != nil {
+		if len(packet.Payload) > len(packet.Data) {
+			return ErrPayloadTooLarge
+		}
+		packet.Data = Data{}
+		copy(packet.Data[:], packet.Payload)
+	}
 	checksum := packet.Checksum()
 
 	buf := []uint8{uint8(packet.Command), packet.Data[0], packet.Data[1], packet.Data[2], checksum}
@@ -75,8 +111,39 @@ func WritePacket(_ context.Context, w io.Writer, packet Packet) error {
 	return err
 }
 
+// WritePacketV2 writes a packet as v2 frame to an io.Writer with escaping.
+// The Payload is written, Data if no Payload is set.
+func WritePacketV2(_ context.Context, w io.Writer, packet Packet) error {
+	payload := packet.payload()
+	if len(payload) > MaxPayloadSize {
+		return ErrPayloadTooLarge
+	}
+
+	content := make([]uint8, 0, len(payload)+4)
+	content = append(content, uint8(packet.Command), uint8(len(payload)))
+	content = append(content, payload...)
+	crc := CRC16(content)
+	content = append(content, uint8(crc>>8), uint8(crc))
+
+	frame := make([]uint8, 0, 2*len(content)+2)
+	frame = append(frame, SOFV2)
+	for _, b := range content {
+		if b == SOF || b == SOFV2 || b == EOF || b == ESC {
+			frame = append(frame, ESC, b^XOR)
+		} else {
+			frame = append(frame, b)
+		}
+	}
+	frame = append(frame, EOF)
+
+	_, err := w.Write(frame)
+	return err
+}
+
 // ReadPacket reads a packet from an io.Reader with escaping.
-// This is blocking and drops invalid bytes until a valid packet is received.
+// This is blocking and drops bytes outside of a frame until a packet is received.
+// Both v1 and v2 frames are read. Frames with an invalid length are reported with ErrInvalidFramingByte.
+// Bytes are read one at a time and a partial frame is dropped on error, use a Decoder to read a stream of packets.
 func ReadPacket(ctx context.Context, r io.Reader) (Packet, error) {
 	buffer := []uint8{}
 
@@ -106,38 +173,49 @@ func ReadPacket(ctx context.Context, r io.Reader) (Packet, error) {
 			return Packet{}, err
 		}
 
-		if b[0] == SOF && !started {
-			started = true
-		} else if !started {
+		if !started {
+			// Drop bytes until the start of a frame
+			if b[0] == SOF || b[0] == SOFV2 {
+				started = true
+				buffer = append(buffer, b[0])
+			}
 			continue
 		}
 
 		if escaped {
 			buffer = append(buffer, b[0]^XOR)
 			escaped = false
+			continue
 		} else if b[0] == ESC {
 			escaped = true
-		} else {
-			buffer = append(buffer, b[0])
+			continue
 		}
 
-		if b[0] == EOF && !escaped {
-			if len(buffer) == 7 { // Packet size
-				break
-			} else {
-				buffer = []uint8{}
-			}
+		buffer = append(buffer, b[0])
+		if b[0] == EOF {
+			break
 		}
+		if len(buffer) > maxFrameSize {
+			return Packet{}, ErrFrameOverflow
+		}
+	}
+
+	if buffer[0] == SOFV2 {
+		return decodeV2(buffer)
 	}
+	return decodeV1(buffer)
+}
 
-	if buffer[0] != SOF || buffer[len(buffer)-1] != EOF {
+// decodeV1 decodes an unescaped v1 frame
+func decodeV1(buffer []uint8) (Packet, error) {
+	if len(buffer) != v1FrameSize {
 		return Packet{}, ErrInvalidFramingByte
 	}
 
 	command := Command(buffer[1])
 	data := Data{buffer[2], buffer[3], buffer[4]}
 	checksum := buffer[5]
-	pkt := Packet{command, data}
+	pkt := Packet{Command: command, Data: data}
 	expectedChecksum := pkt.Checksum()
 
 	if checksum != expectedChecksum {
@@ -146,3 +224,23 @@ func ReadPacket(ctx context.Context, r io.Reader) (Packet, error) {
 
 	return pkt, nil
 }
+
+// decodeV2 decodes an unescaped v2 frame
+func decodeV2(buffer []uint8) (Packet, error) {
+	if len(buffer) < v2FrameOverhead || len(buffer) != v2FrameOverhead+int(buffer[2]) {
+		return Packet{}, ErrInvalidFramingByte
+	}
+
+	content := buffer[1 : len(buffer)-3]
+	checksum := uint16(buffer[len(buffer)-3])<<8 | uint16(buffer[len(buffer)-2])
+	if checksum != CRC16(content) {
+		return Packet{}, ErrChecksumMismatch
+	}
+
+	pkt := Packet{
+		Command: Command(content[0]),
+		Payload: append([]uint8{}, content[2:]...),
+	}
+	copy(pkt.Data[:], pkt.Payload)
+	return pkt, nil
+}
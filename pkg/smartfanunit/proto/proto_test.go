@@ -90,10 +90,11 @@ func TestWritePacket(t *testing.T) {
 }
 
 func FuzzPacketReadWrite(f *testing.F) {
-	f.Add(uint8(0x01), uint8(0x02), uint8(0x03), uint8(0x04))
+	f.Add(uint8(0x01), uint8(0x02), uint8(0x03), uint8(0x04), []byte{})
+	f.Add(uint8(0xff), uint8(proto.SOF), uint8(proto.EOF), uint8(proto.ESC), []byte{proto.SOFV2, proto.ESC, 0x00})
 
 	// Fuzz function
-	f.Fuzz(func(t *testing.T, cmd, d0, d1, d2 uint8) {
+	f.Fuzz(func(t *testing.T, cmd, d0, d1, d2 uint8, payload []byte) {
 		pkt := proto.Packet{
 			Command: proto.Command(cmd),
 			Data:    proto.Data([]uint8{d0, d1, d2}),
@@ -106,6 +107,20 @@ func FuzzPacketReadWrite(f *testing.F) {
 		readPkt, err := proto.ReadPacket(context.TODO(), &buffer)
 		assert.NoError(t, err)
 		assert.Equal(t, pkt, readPkt)
+
+		// v2 frames with variable-length payload
+		if len(payload) > proto.MaxPayloadSize {
+			payload = payload[:proto.MaxPayloadSize]
+		}
+		pktV2 := proto.Packet{Command: proto.Command(cmd), Payload: payload}
+		buffer.Reset()
+		err = proto.WritePacketV2(context.TODO(), &buffer, pktV2)
+		assert.NoError(t, err)
+
+		readPkt, err = proto.ReadPacket(context.TODO(), &buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, pktV2.Command, readPkt.Command)
+		assert.True(t, bytes.Equal(payload, readPkt.Payload))
 	})
 }
 
@@ -204,3 +219,129 @@ func TestReadPacketDirtyReader(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, proto.Packet{Command: proto.Command(0x01), Data: proto.Data{0x11, 0x12, 0x13}}, pkt)
 }
+
+func TestReadPacketInvalidFrameLength(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	buffer.Write([]uint8{
+		// Truncated packet
+		proto.SOF,
+		0x01,
+		0x11,
+		0x12,
+		proto.EOF,
+		// Actual packet
+		proto.SOF,
+		0x01,
+		0x11,
+		0x12,
+		0x13,
+		0x11,
+		proto.EOF,
+	})
+
+	_, err := proto.ReadPacket(context.TODO(), &buffer)
+	assert.ErrorIs(t, err, proto.ErrInvalidFramingByte)
+
+	// The next packet is read just fine
+	pkt, err := proto.ReadPacket(context.TODO(), &buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Packet{Command: proto.Command(0x01), Data: proto.Data{0x11, 0x12, 0x13}}, pkt)
+}
+
+func TestCRC16(t *testing.T) {
+	t.Parallel()
+
+	// Check value of CRC-16/CCITT-FALSE
+	assert.Equal(t, uint16(0x29B1), proto.CRC16([]uint8("123456789")))
+}
+
+func TestPacketReadWriteV2(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		packet   proto.Packet
+		expected proto.Packet
+	}{
+		{
+			name:     "Variable-length payload",
+			packet:   proto.Packet{Command: 0x10, Payload: []uint8("compute-blade")},
+			expected: proto.Packet{Command: 0x10, Data: proto.Data{'c', 'o', 'm'}, Payload: []uint8("compute-blade")},
+		},
+		{
+			name:     "Framing bytes in payload",
+			packet:   proto.Packet{Command: proto.SOFV2, Payload: []uint8{proto.SOF, proto.SOFV2, proto.ESC, proto.EOF}},
+			expected: proto.Packet{Command: proto.SOFV2, Data: proto.Data{proto.SOF, proto.SOFV2, proto.ESC}, Payload: []uint8{proto.SOF, proto.SOFV2, proto.ESC, proto.EOF}},
+		},
+		{
+			name:     "Empty payload",
+			packet:   proto.Packet{Command: 0x01, Payload: []uint8{}},
+			expected: proto.Packet{Command: 0x01, Payload: []uint8{}},
+		},
+		{
+			name:     "Data without payload",
+			packet:   proto.Packet{Command: 0x01, Data: proto.Data{0x11, 0x12, 0x13}},
+			expected: proto.Packet{Command: 0x01, Data: proto.Data{0x11, 0x12, 0x13}, Payload: []uint8{0x11, 0x12, 0x13}},
+		},
+	}
+
+	for _, tcl := range testcases {
+		tc := tcl
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buffer bytes.Buffer
+			assert.NoError(t, proto.WritePacketV2(context.TODO(), &buffer, tc.packet))
+
+			packet, err := proto.ReadPacket(context.TODO(), &buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, packet)
+		})
+	}
+}
+
+func TestReadPacketMixedFrames(t *testing.T) {
+	t.Parallel()
+
+	v1 := proto.Packet{Command: 0x01, Data: proto.Data{0x11, 0x12, 0x13}}
+	v2 := proto.Packet{Command: 0x02, Data: proto.Data{0x21, 0x22, 0x23}, Payload: []uint8{0x21, 0x22, 0x23, 0x24}}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, proto.WritePacket(context.TODO(), &buffer, v1))
+	assert.NoError(t, proto.WritePacketV2(context.TODO(), &buffer, v2))
+	assert.NoError(t, proto.WritePacket(context.TODO(), &buffer, v1))
+
+	for _, expected := range []proto.Packet{v1, v2, v1} {
+		packet, err := proto.ReadPacket(context.TODO(), &buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, packet)
+	}
+}
+
+func TestReadPacketV2Errors(t *testing.T) {
+	t.Parallel()
+
+	var frame bytes.Buffer
+	assert.NoError(t, proto.WritePacketV2(context.TODO(), &frame, proto.Packet{Command: 0x01, Payload: []uint8{0x11, 0x12}}))
+	valid := frame.Bytes()
+
+	// Corrupted payload byte
+	corrupted := append([]uint8{}, valid...)
+	corrupted[4] ^= 0x01
+	_, err := proto.ReadPacket(context.TODO(), bytes.NewReader(corrupted))
+	assert.ErrorIs(t, err, proto.ErrChecksumMismatch)
+
+	// Length byte not matching the payload
+	truncated := append(append([]uint8{}, valid[:4]...), valid[5:]...)
+	_, err = proto.ReadPacket(context.TODO(), bytes.NewReader(truncated))
+	assert.ErrorIs(t, err, proto.ErrInvalidFramingByte)
+}
+
+func TestWritePacketPayloadTooLarge(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	assert.ErrorIs(t, proto.WritePacket(context.TODO(), &buffer, proto.Packet{Payload: make([]uint8, 4)}), proto.ErrPayloadTooLarge)
+	assert.ErrorIs(t, proto.WritePacketV2(context.TODO(), &buffer, proto.Packet{Payload: make([]uint8, proto.MaxPayloadSize+1)}), proto.ErrPayloadTooLarge)
+}
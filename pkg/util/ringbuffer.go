@@ -0,0 +1,59 @@
+package util
+
+import "sync"
+
+// RingBuffer is a bounded, thread-safe FIFO that overwrites the oldest element once full.
+type RingBuffer[T any] struct {
+	mu    sync.RWMutex
+	items []T
+	start int
+	len   int
+}
+
+// NewRingBuffer creates a ring buffer holding at most size elements
+func NewRingBuffer[T any](size int) *RingBuffer[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &RingBuffer[T]{
+		items: make([]T, size),
+	}
+}
+
+// Push appends an element, dropping the oldest one if the buffer is full
+func (r *RingBuffer[T]) Push(item T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.len < len(r.items) {
+		r.items[(r.start+r.len)%len(r.items)] = item
+		r.len++
+		return
+	}
+	r.items[r.start] = item
+	r.start = (r.start + 1) % len(r.items)
+}
+
+// Len returns the number of elements currently stored
+func (r *RingBuffer[T]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.len
+}
+
+// Cap returns the maximum number of elements the buffer can hold
+func (r *RingBuffer[T]) Cap() int {
+	return len(r.items)
+}
+
+// Items returns a copy of all stored elements, oldest first
+func (r *RingBuffer[T]) Items() []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]T, r.len)
+	for i := 0; i < r.len; i++ {
+		result[i] = r.items[(r.start+i)%len(r.items)]
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptime-induestries/compute-blade-agent/pkg/util"
+)
+
+func TestRingBuffer_PushBelowCapacity(t *testing.T) {
+	t.Parallel()
+
+	rb := util.NewRingBuffer[int](4)
+	rb.Push(1)
+	rb.Push(2)
+
+	assert.Equal(t, 2, rb.Len())
+	assert.Equal(t, 4, rb.Cap())
+	assert.Equal(t, []int{1, 2}, rb.Items())
+}
+
+func TestRingBuffer_Overwrite(t *testing.T) {
+	t.Parallel()
+
+	rb := util.NewRingBuffer[int](3)
+	for i := 1; i <= 7; i++ {
+		rb.Push(i)
+	}
+
+	assert.Equal(t, 3, rb.Len())
+	assert.Equal(t, []int{5, 6, 7}, rb.Items())
+}
+
+func TestRingBuffer_Empty(t *testing.T) {
+	t.Parallel()
+
+	rb := util.NewRingBuffer[string](0)
+	assert.Equal(t, 1, rb.Cap())
+	assert.Empty(t, rb.Items())
+}